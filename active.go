@@ -0,0 +1,55 @@
+package logrotate
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// archiveActive renames the active file set by WithActiveFile to the
+// filename generated by pattern for the period its content belongs to,
+// which is the rotation time of prev if any, otherwise evaluated from the
+// modification time of the file. A sequence suffix is appended if the
+// filename already exists, e.g.: on MaxSize rotations. It returns the new
+// filename, or "" if the active file doesn't exist. l.mu must be held by
+// the caller.
+func (l *Logger) archiveActive(prev *fileHandle) (string, error) {
+	active := l.opts.activeFile
+	info, err := l.osStat(active)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", &RotationError{Op: "stat", Path: active, Err: err}
+	}
+	var rotationTime int64
+	if prev != nil {
+		rotationTime = prev.rotationTime
+	} else {
+		rotationTime = evalRotationTime(info.ModTime().UnixNano(), l.tzOffset, l.maxInterval)
+	}
+	filename := l.freeFilename(l.genBaseFilename(rotationTime))
+	if err := os.Rename(active, filename); err != nil {
+		return "", &RotationError{Op: "rename", Path: active, Err: err}
+	}
+	return filename, nil
+}
+
+// staleActive reports whether the active file set by WithActiveFile,
+// with info, was last written in a previous period, e.g.: before a
+// restart, so it should be archived before being resumed.
+func (l *Logger) staleActive(info fs.FileInfo) bool {
+	return l.opts.activeFile != "" && l.maxInterval > 0 &&
+		evalRotationTime(info.ModTime().UnixNano(), l.tzOffset, l.maxInterval) != l.currRotationTime
+}
+
+// freeFilename returns base if it doesn't exist, otherwise base with the
+// first sequence suffix which doesn't exist, e.g.: "app.log.1".
+func (l *Logger) freeFilename(base string) string {
+	filename := base
+	for seq := 1; ; seq++ {
+		if _, err := l.osStat(filename); errors.Is(err, fs.ErrNotExist) {
+			return filename
+		}
+		filename = sequenceFilename(base, l.opts.seqSuffix, uint(seq))
+	}
+}
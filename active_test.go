@@ -0,0 +1,62 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ActiveFile(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_ActiveFile")
+	defer os.RemoveAll(dir)
+
+	active := filepath.Join(dir, "app.log")
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(day1)
+	newLogger := func() *Logger {
+		l, err := New(
+			filepath.Join(dir, "app.%Y%m%d.log"),
+			WithClock(clock),
+			WithMaxInterval(24*time.Hour),
+			WithMaxSize(4),
+			WithActiveFile(active),
+		)
+		require.NoError(t, err, "New should succeed")
+		require.Equal(t, active, l.Options().ActiveFile, "ActiveFile should match")
+		return l
+	}
+	readFile := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+
+	l := newLogger()
+	for _, line := range []string{"aaa", "bbb", "ccc"} {
+		_, err := l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	clock.Advance(24 * time.Hour)
+	_, err := l.Write([]byte("ddd"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, "aaa", readFile(filepath.Join(dir, "app.20240101.log")), "file of day 1 should match")
+	require.Equal(t, "bbb", readFile(filepath.Join(dir, "app.20240101.log.1")), "MaxSize rotation should append a sequence")
+	require.Equal(t, "ccc", readFile(filepath.Join(dir, "app.20240101.log.2")), "MaxSize rotation should append a sequence")
+	require.Equal(t, "ddd", readFile(active), "current file should be the active file")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	// a file left from the previous period should be renamed on restart.
+	day2 := day1.Add(24 * time.Hour)
+	require.NoError(t, os.Chtimes(active, day2, day2), "Chtimes should succeed")
+	clock.Advance(24 * time.Hour)
+	l = newLogger()
+	defer l.Close()
+	_, err = l.Write([]byte("eee"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, "ddd", readFile(filepath.Join(dir, "app.20240102.log")), "file of day 2 should match")
+	require.Equal(t, "eee", readFile(active), "current file should be the active file")
+}
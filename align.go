@@ -0,0 +1,137 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// alignedWriter coalesces the writes to the underlying file, and writes
+// them out in whole blocks, so that each physical write ends at a block
+// boundary of the file, which reduces read-modify-write cycles on some
+// storage. The remainder is written out maxDelay after it's buffered, on
+// flush and on Close.
+type alignedWriter struct {
+	io.WriteCloser
+	name     string // filename
+	block    int64
+	maxDelay time.Duration
+	onError  func(err error) // called on errors of delayed writes
+
+	mu     sync.Mutex // guards following
+	buf    []byte
+	offset int64       // file offset where buf starts
+	timer  *time.Timer // armed while buf is not empty
+	closed bool
+}
+
+// align wraps the file f named name, which is at offset size, with an
+// alignedWriter if WithAlignedWrites is set.
+func (l *Logger) align(f io.WriteCloser, name string, size int64) io.WriteCloser {
+	if l.opts.alignBlock <= 0 {
+		return f
+	}
+	return &alignedWriter{
+		WriteCloser: f,
+		name:        name,
+		block:       int64(l.opts.alignBlock),
+		maxDelay:    l.opts.alignDelay,
+		onError:     l.handleError,
+		offset:      size,
+	}
+}
+
+// Write buffers b, and writes out the buffered bytes up to the last block
+// boundary, if any. If that fails, the buffered bytes are dropped, and the
+// count of bytes of b written is returned with the error.
+func (w *alignedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	start := len(w.buf)
+	w.buf = append(w.buf, b...)
+	end := w.offset + int64(len(w.buf))
+	if n := int(end - end%w.block - w.offset); n > 0 {
+		if written, err := w.writeOut(n); err != nil {
+			if written < start {
+				return 0, err
+			}
+			return written - start, err
+		}
+	}
+	if len(w.buf) > 0 && w.timer == nil {
+		w.timer = time.AfterFunc(w.maxDelay, w.flushDelayed)
+	}
+	return len(b), nil
+}
+
+// writeOut writes the first n buffered bytes to the file. w.mu must be
+// held by the caller.
+func (w *alignedWriter) writeOut(n int) (int, error) {
+	written, err := w.WriteCloser.Write(w.buf[:n])
+	w.offset += int64(written)
+	if err != nil {
+		w.buf = w.buf[:0]
+		return written, err
+	}
+	if rest := w.buf[n:]; cap(w.buf) > maxPooledBufferSize {
+		// don't pin the capacity grown by a large write.
+		w.buf = append([]byte(nil), rest...)
+	} else {
+		w.buf = w.buf[:copy(w.buf, rest)]
+	}
+	return written, nil
+}
+
+// flush writes out all the buffered bytes.
+func (w *alignedWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushLocked()
+}
+
+// flushLocked is like flush, but w.mu must be held by the caller.
+func (w *alignedWriter) flushLocked() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.writeOut(len(w.buf))
+	return err
+}
+
+// flushDelayed writes out the remainder once maxDelay has elapsed since
+// it's buffered, and reports the error as there is no caller to return it
+// to.
+func (w *alignedWriter) flushDelayed() {
+	w.mu.Lock()
+	var err error
+	if !w.closed {
+		err = w.flushLocked()
+	}
+	w.mu.Unlock()
+	if err != nil && w.onError != nil {
+		w.onError(&RotationError{Op: "flush", Path: w.name, Err: err})
+	}
+}
+
+// Close writes out all the buffered bytes, and closes the file.
+func (w *alignedWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if !w.closed {
+		err = w.flushLocked()
+		w.closed = true
+	}
+	w.mu.Unlock()
+	if cerr := w.WriteCloser.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
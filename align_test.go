@@ -0,0 +1,114 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordFile records the lengths of writes.
+type recordFile struct {
+	mu     sync.Mutex
+	writes []int
+	closed bool
+}
+
+func (f *recordFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, len(b))
+	return len(b), nil
+}
+
+func (f *recordFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *recordFile) lens() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.writes...)
+}
+
+func Test_alignedWriter(t *testing.T) {
+	f := &recordFile{}
+	w := &alignedWriter{WriteCloser: f, block: 8, maxDelay: time.Hour, offset: 5}
+
+	for _, size := range []int{2, 4, 10, 3} {
+		n, err := w.Write(make([]byte, size))
+		require.NoError(t, err, "Write should succeed")
+		require.Equal(t, size, n, "Write length should match")
+	}
+	// 5+2+4 ends at 11, then 21 and 24.
+	require.Equal(t, []int{3, 8, 8}, f.lens(), "writes should end at block boundaries")
+	require.Zero(t, len(w.buf), "nothing should be buffered at a block boundary")
+
+	_, err := w.Write(make([]byte, 3))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, w.flush(), "flush should succeed")
+	require.Equal(t, []int{3, 8, 8, 3}, f.lens(), "flush should write out the remainder")
+
+	_, err = w.Write(make([]byte, 1))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, w.Close(), "Close should succeed")
+	require.Equal(t, []int{3, 8, 8, 3, 1}, f.lens(), "Close should write out the remainder")
+	require.True(t, f.closed, "file should be closed")
+	_, err = w.Write([]byte("a"))
+	require.ErrorIs(t, err, os.ErrClosed, "Write should fail after Close")
+}
+
+func Test_alignedWriter_Delay(t *testing.T) {
+	f := &recordFile{}
+	w := &alignedWriter{WriteCloser: f, block: 4096, maxDelay: 10 * time.Millisecond}
+	defer w.Close()
+
+	_, err := w.Write([]byte("abc"))
+	require.NoError(t, err, "Write should succeed")
+	require.Eventually(t, func() bool {
+		return len(f.lens()) == 1
+	}, time.Second, time.Millisecond, "remainder should be written out after maxDelay")
+}
+
+func Test_AlignedWrites(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_AlignedWrites")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithAlignedWrites(0, time.Second))
+	require.Error(t, err, "non-positive block size should be rejected")
+	_, err = New(filepath.Join(dir, "app.log"), WithAlignedWrites(4096, 0))
+	require.Error(t, err, "non-positive delay should be rejected")
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithMaxSize(40), WithAlignedWrites(16, time.Hour))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, 16, l.Options().AlignBlock, "AlignBlock should match")
+	require.Equal(t, time.Hour, l.Options().AlignDelay, "AlignDelay should match")
+
+	fileSize := func(name string) int64 {
+		fi, err := os.Stat(name)
+		require.NoError(t, err, "Stat should succeed")
+		return fi.Size()
+	}
+	_, err = l.Write([]byte("0123456789"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, int64(0), fileSize(filename), "write should be buffered")
+	_, err = l.Write([]byte("0123456789"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, int64(16), fileSize(filename), "first block should be written out")
+	require.NoError(t, l.Sync(), "Sync should succeed")
+	require.Equal(t, int64(20), fileSize(filename), "Sync should write out the remainder")
+
+	// rotation writes out the remainder of the rotated file.
+	_, err = l.Write([]byte("abc"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = l.Write(make([]byte, 20))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, int64(23), fileSize(filename), "rotated file should be written out")
+	require.Equal(t, int64(16), fileSize(filename+".1"), "first block should be written out")
+}
@@ -0,0 +1,81 @@
+package logrotate
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	autoTuneInterval = time.Second // interval between tunes of the queue bound
+	autoTuneRange    = 16          // ratio of the max bound to the min bound
+
+	// autoTuneLatency is the drain latency, i.e.: the age of the oldest
+	// queued entry, below which the queue is deemed drained promptly.
+	autoTuneLatency = 10 * time.Millisecond
+)
+
+// queueTuner tunes the effective bound of the default queue between min
+// and max by the observed pressure, see WithAutoTune.
+type queueTuner struct {
+	min, max int
+	bound    atomic.Int64  // effective bound of the queue
+	full     atomic.Uint64 // count of enqueues rejected at the bound
+
+	lastFull uint64 // full on the last tune, only accessed by tuneLoop
+}
+
+// newQueueTuner returns a queueTuner of max bound size, starting at max.
+func newQueueTuner(size int) *queueTuner {
+	t := &queueTuner{min: size / autoTuneRange, max: size}
+	if t.min < 1 {
+		t.min = 1
+	}
+	t.bound.Store(int64(size))
+	return t
+}
+
+// queueBound returns the effective bound of the default queue.
+func (l *Logger) queueBound() int {
+	if l.tuner != nil {
+		return int(l.tuner.bound.Load())
+	}
+	return l.opts.writeChSize
+}
+
+// tuneLoop runs in a goroutine to tune the queue bound every
+// autoTuneInterval until Close is called.
+func (l *Logger) tuneLoop() {
+	for {
+		select {
+		case <-l.life.quit:
+			return
+		case <-l.after(autoTuneInterval):
+			l.tuneQueue()
+		}
+	}
+}
+
+// tuneQueue doubles the queue bound if enqueues were rejected at the bound
+// since the last tune, or halves it if the queue is below a quarter of the
+// bound and drained promptly, within [min, max].
+func (l *Logger) tuneQueue() {
+	t := l.tuner
+	full := t.full.Load()
+	rejected := full != t.lastFull
+	t.lastFull = full
+
+	bound := int(t.bound.Load())
+	switch {
+	case rejected:
+		bound *= 2
+		if bound > t.max {
+			bound = t.max
+		}
+	case 4*l.queue.Len() < bound && l.metrics.oldestQueuedAge(l.opts.clock.Now()) < autoTuneLatency:
+		bound /= 2
+		if bound < t.min {
+			bound = t.min
+		}
+	}
+	t.bound.Store(int64(bound))
+}
@@ -0,0 +1,48 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AutoTune(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_AutoTune")
+	defer os.RemoveAll(dir)
+
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithClock(clockwork.NewFakeClock()),
+		WithWriteChan(64),
+		WithAutoTune(true),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.True(t, l.Options().AutoTune, "AutoTune should be enabled")
+	require.Equal(t, 64, l.Metrics().QueueBound, "bound should start at the max")
+
+	// shrinks to the min while quiet.
+	for _, bound := range []int{32, 16, 8, 4, 4} {
+		l.tuneQueue()
+		require.Equal(t, bound, l.Metrics().QueueBound, "bound should shrink while quiet")
+	}
+
+	// hold l.mu, so writeLoop is blocked and the queue fills up.
+	l.mu.Lock()
+	discarded := false
+	for i := 0; i < 10 && !discarded; i++ {
+		_, err = l.Write([]byte("1"))
+		discarded = errors.Is(err, ErrDiscarded)
+	}
+	queued := l.queue.Len()
+	l.tuneQueue()
+	bound := l.Metrics().QueueBound
+	l.mu.Unlock()
+	require.True(t, discarded, "Write should be discarded at the bound")
+	require.LessOrEqual(t, queued, 4, "queue should be bounded")
+	require.Equal(t, 8, bound, "bound should grow on discards")
+}
@@ -0,0 +1,22 @@
+//go:build darwin || freebsd || netbsd
+
+package logrotate
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// birthTime returns the birth time of the file from its stat info.
+func birthTime(path string, fi fs.FileInfo) (time.Time, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	sec, nsec := st.Birthtimespec.Unix()
+	if sec == 0 && nsec == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, nsec), true
+}
@@ -0,0 +1,76 @@
+//go:build linux && (amd64 || arm64 || riscv64 || loong64)
+
+package logrotate
+
+import (
+	"io/fs"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// statx syscall number, which is the same on all architectures using the
+// generic syscall table, except amd64.
+var sysStatx uintptr = func() uintptr {
+	if runtime.GOARCH == "amd64" {
+		return 332
+	}
+	return 291
+}()
+
+const (
+	atFdcwd           = -0x64
+	atSymlinkNofollow = 0x100
+	statxBtime        = 0x800
+)
+
+type statxTimestamp struct {
+	Sec  int64
+	Nsec uint32
+	_    int32
+}
+
+// statxT mirrors struct statx of linux/stat.h.
+type statxT struct {
+	Mask           uint32
+	Blksize        uint32
+	Attributes     uint64
+	Nlink          uint32
+	Uid            uint32
+	Gid            uint32
+	Mode           uint16
+	_              uint16
+	Ino            uint64
+	Size           uint64
+	Blocks         uint64
+	AttributesMask uint64
+	Atime          statxTimestamp
+	Btime          statxTimestamp
+	Ctime          statxTimestamp
+	Mtime          statxTimestamp
+	_              [16]uint64
+}
+
+// birthTime returns the birth time of the file by statx(2), which requires
+// Linux 4.11+ and a filesystem recording it (e.g.: ext4, xfs, btrfs).
+func birthTime(path string, fi fs.FileInfo) (time.Time, bool) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var stx statxT
+	dirfd := atFdcwd
+	_, _, errno := syscall.Syscall6(sysStatx,
+		uintptr(dirfd),
+		uintptr(unsafe.Pointer(p)),
+		atSymlinkNofollow,
+		statxBtime,
+		uintptr(unsafe.Pointer(&stx)),
+		0,
+	)
+	if errno != 0 || stx.Mask&statxBtime == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), true
+}
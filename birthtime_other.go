@@ -0,0 +1,13 @@
+//go:build !(darwin || freebsd || netbsd) && !(linux && (amd64 || arm64 || riscv64 || loong64))
+
+package logrotate
+
+import (
+	"io/fs"
+	"time"
+)
+
+// birthTime is not supported on this platform.
+func birthTime(path string, fi fs.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
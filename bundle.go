@@ -0,0 +1,243 @@
+package logrotate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// BundleFormat is the archive format of day bundles, see WithDayBundle.
+type BundleFormat int
+
+const (
+	// BundleNone disables day bundles.
+	BundleNone BundleFormat = iota
+	// BundleZip bundles the files of a day into a .zip archive.
+	BundleZip
+	// BundleTarGz bundles the files of a day into a .tar.gz archive.
+	BundleTarGz
+)
+
+// ext returns the filename extension of bundles in f.
+func (f BundleFormat) ext() string {
+	switch f {
+	case BundleZip:
+		return ".zip"
+	case BundleTarGz:
+		return ".tar.gz"
+	default:
+		return ""
+	}
+}
+
+// String returns the name of f.
+func (f BundleFormat) String() string {
+	switch f {
+	case BundleNone:
+		return "none"
+	case BundleZip:
+		return "zip"
+	case BundleTarGz:
+		return "tar.gz"
+	default:
+		return fmt.Sprintf("BundleFormat(%d)", int(f))
+	}
+}
+
+// dayLayout is the layout of days which files are bundled by.
+const dayLayout = "2006-01-02"
+
+// bundleDays bundles the log files of each day before today into one
+// archive if WithDayBundle, and removes the originals. It's done once a
+// day, on the first mill pass after the day rolls over, and retried on the
+// next pass if failed. The current file and the rotated files still
+// waiting for post-rotation processing are never bundled.
+func (l *Logger) bundleDays() error {
+	if l.opts.dayBundle == BundleNone {
+		return nil
+	}
+	l.bundleMu.Lock()
+	defer l.bundleMu.Unlock()
+	now := l.opts.clock.Now()
+	today := now.Format(dayLayout)
+	if today == l.bundledDay {
+		return nil
+	}
+	files, err := l.getLogFiles()
+	if err != nil {
+		return err
+	}
+	skipped := map[string]bool{l.currentFilename(): true}
+	l.rotatedMu.Lock()
+	for _, path := range l.rotated {
+		skipped[path] = true
+	}
+	l.rotatedMu.Unlock()
+
+	days := make(map[string][]FileInfo)
+	for _, f := range files {
+		if skipped[f.Path] || isBundle(f.Path) {
+			continue
+		}
+		if day := f.Time().In(now.Location()).Format(dayLayout); day < today {
+			days[day] = append(days[day], f)
+		}
+	}
+	var errs []error
+	for day, group := range days {
+		// files are sorted newest first, see byModTime.
+		for i, j := 0, len(group)-1; i < j; i, j = i+1, j-1 {
+			group[i], group[j] = group[j], group[i]
+		}
+		if err := l.bundle(day, group); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		l.bundledDay = today
+	}
+	return errors.Join(errs...)
+}
+
+// isBundle reports whether path is a day bundle of any format.
+func isBundle(path string) bool {
+	return strings.HasSuffix(path, BundleZip.ext()) || strings.HasSuffix(path, BundleTarGz.ext())
+}
+
+// bundle bundles files of the day, sorted from the oldest, into an
+// archive named after the first file without the sequence suffix, e.g.:
+// "app.log.20240101.zip", and removes the originals. The day is appended
+// to the name if the pattern has no time verbs, e.g.:
+// "app.log.2024-01-01.zip". The day is left to a later pass if the
+// deletion of any file is deferred, see deferDeletion.
+func (l *Logger) bundle(day string, files []FileInfo) error {
+	for _, f := range files {
+		if err := l.deferDeletion(f); err != nil {
+			return &PurgeError{Path: f.Path, Err: err}
+		}
+	}
+	// the first file is the base, unless the others aren't its sequence,
+	// e.g.: removed by retention.
+	base := strings.TrimSuffix(files[0].Path, ".gz")
+	for _, f := range files[1:] {
+		if _, ok := sequenceOf(strings.TrimSuffix(f.Path, ".gz"), base, l.opts.seqSuffix); !ok {
+			base, _ = trimSequence(base, l.opts.seqSuffix)
+			break
+		}
+	}
+	if strings.TrimSuffix(l.globPattern, suffixGlob) == l.patternString() {
+		base += "." + day
+	}
+	ext := l.opts.dayBundle.ext()
+	name := base + ext
+	for seq := uint(1); ; seq++ {
+		if _, err := os.Lstat(name); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		name = sequenceFilename(base, l.opts.seqSuffix, seq) + ext
+	}
+
+	tmp := name + ".tmp"
+	if err := l.writeBundle(tmp, files); err != nil {
+		os.Remove(tmp)
+		return &RotationError{Op: "bundle", Path: name, Err: err}
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return &RotationError{Op: "bundle", Path: name, Err: err}
+	}
+	// keep the modification time of the latest file, so retention still
+	// works as expected.
+	latest := files[len(files)-1].ModTime()
+	_ = os.Chtimes(name, latest, latest)
+	l.index.touch(name)
+
+	var errs []error
+	for _, f := range files {
+		if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+		}
+		l.index.touch(f.Path)
+		l.untrackMoved(f.Path)
+	}
+	return errors.Join(errs...)
+}
+
+// writeBundle writes files into the archive at path in the DayBundle
+// format.
+func (l *Logger) writeBundle(path string, files []FileInfo) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	if l.opts.dayBundle == BundleZip {
+		err = l.writeZip(out, files)
+	} else {
+		err = l.writeTarGz(out, files)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeZip writes files into a zip archive to w.
+func (l *Logger) writeZip(w io.Writer, files []FileInfo) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		hdr, err := zip.FileInfoHeader(f)
+		if err != nil {
+			return err
+		}
+		hdr.Method = zip.Deflate
+		dst, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if err := l.copyFile(dst, f.Path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz writes files into a gzipped tar archive to w.
+func (l *Logger) writeTarGz(w io.Writer, files []FileInfo) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr, err := tar.FileInfoHeader(f, "")
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := l.copyFile(tw, f.Path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// copyFile copies the file at path to w, throttled by MillRateLimit.
+func (l *Logger) copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, ThrottleReader(l.ctx, f))
+	return err
+}
@@ -0,0 +1,102 @@
+package logrotate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DayBundle(t *testing.T) {
+	defer os.RemoveAll(filepath.Join(baseLogDir, "Test_DayBundle"))
+	for _, format := range []BundleFormat{BundleZip, BundleTarGz} {
+		t.Run(format.String(), func(t *testing.T) {
+			dir := filepath.Join(baseLogDir, "Test_DayBundle", format.String())
+			defer os.RemoveAll(dir)
+
+			day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+			clock := clockwork.NewFakeClockAt(day1)
+			l, err := New(
+				filepath.Join(dir, "app.log.%Y%m%d"),
+				WithClock(clock),
+				WithMaxInterval(24*time.Hour),
+				WithMaxSize(4),
+				WithDayBundle(format),
+			)
+			require.NoError(t, err, "New should succeed")
+			defer l.Close()
+			require.Equal(t, format, l.Options().DayBundle, "DayBundle should match")
+
+			for i, line := range []string{"aaa\n", "bbb\n", "ccc\n"} {
+				_, err = l.Write([]byte(line))
+				require.NoError(t, err, "Write should succeed")
+				// keep the files in day1 by modification time.
+				mtime := day1.Add(time.Duration(i) * time.Minute)
+				require.NoError(t, os.Chtimes(l.currentFilename(), mtime, mtime), "Chtimes should succeed")
+			}
+			require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+			_, err = os.Stat(filepath.Join(dir, "app.log.20240101.1"))
+			require.NoError(t, err, "files of today should not be bundled")
+
+			clock.Advance(24 * time.Hour)
+			_, err = l.Write([]byte("ddd\n"))
+			require.NoError(t, err, "Write should succeed")
+			require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+
+			names, err := filepath.Glob(filepath.Join(dir, "app.log.*"))
+			require.NoError(t, err, "Glob should succeed")
+			bundle := filepath.Join(dir, "app.log.20240101"+format.ext())
+			require.ElementsMatch(t, []string{bundle, filepath.Join(dir, "app.log.20240102")}, names, "files of day1 should be bundled")
+
+			entries := readBundle(t, bundle, format)
+			require.Equal(t, []string{
+				"app.log.20240101", "aaa\n",
+				"app.log.20240101.1", "bbb\n",
+				"app.log.20240101.2", "ccc\n",
+			}, entries, "bundle entries should match")
+		})
+	}
+}
+
+// readBundle returns the names and contents of the entries of the bundle
+// at path in order.
+func readBundle(t *testing.T, path string, format BundleFormat) []string {
+	var entries []string
+	if format == BundleZip {
+		zr, err := zip.OpenReader(path)
+		require.NoError(t, err, "zip.OpenReader should succeed")
+		defer zr.Close()
+		for _, f := range zr.File {
+			r, err := f.Open()
+			require.NoError(t, err, "Open should succeed")
+			b, err := io.ReadAll(r)
+			r.Close()
+			require.NoError(t, err, "ReadAll should succeed")
+			entries = append(entries, f.Name, string(b))
+		}
+		return entries
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err, "Open should succeed")
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err, "gzip.NewReader should succeed")
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		require.NoError(t, err, "Next should succeed")
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err, "ReadAll should succeed")
+		entries = append(entries, hdr.Name, string(b))
+	}
+}
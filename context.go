@@ -0,0 +1,125 @@
+package logrotate
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+)
+
+// contextPollInterval is the interval at which WriteContext retries the
+// full queue or the contended internal lock until ctx is done.
+const contextPollInterval = time.Millisecond
+
+// WriteContext is like Write, but it waits for the Logger until ctx is done,
+// so callers on request paths can bound how long they wait, e.g.: by the
+// deadline of the request. In buffered mode, it waits for room if queue is
+// full, instead of discarding the log line, unless WithSpillDir. In
+// unbuffered mode, it waits for the internal lock if contended, e.g.: by a
+// rotation in progress.
+//
+// If ctx is done before the log line is queued or written, nothing is
+// written, the log line is counted in Metrics.DiscardedCanceled, and
+// ctx.Err() is returned.
+//
+// NOTE: once the internal lock is taken, it may still block on the file
+// write itself, see WithWriteTimeout.
+func (l *Logger) WriteContext(ctx context.Context, b []byte) (n int, err error) {
+	if len(l.opts.redactors) > 0 {
+		size := len(b)
+		b = l.redact(b)
+		defer func() { n = redactedLen(n, err, size) }()
+	}
+	if l.closed.Load() {
+		l.discard(&l.metrics.DiscardsClosed, len(b), b)
+		return 0, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		l.discard(&l.metrics.DiscardsCanceled, len(b), b)
+		return 0, err
+	}
+	if l.opts.mirror != nil {
+		defer l.mirror(&err, b)
+	}
+	if l.opts.writeChSize > 0 {
+		if err := l.enqueueContext(ctx, b); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if l.Degraded() {
+		return 0, ErrWriteTimeout
+	}
+	if n, ok, err := l.writeFast(b); ok {
+		if err == nil {
+			err = l.commit.wait(l.commit.position())
+		}
+		return n, err
+	}
+	if err := l.lockContext(ctx); err != nil {
+		l.discard(&l.metrics.DiscardsCanceled, len(b), b)
+		return 0, err
+	}
+	n, err = l.writeLocked(b)
+	if cerr := l.unlock(); cerr != nil {
+		tracef(os.Stderr, "failed to close rotated file: %v", cerr)
+	}
+	if err != nil {
+		l.recordError(err)
+		return n, err
+	}
+	return n, l.commit.wait(l.commit.position())
+}
+
+// enqueueContext is like enqueue, but waits for room in queue until ctx is
+// done, unless b is spilled. The discarded b is counted in metrics.
+func (l *Logger) enqueueContext(ctx context.Context, b []byte) error {
+	// keep the order with the spilled writes not yet replayed.
+	if l.spillWrite(len(b), false, b) {
+		return nil
+	}
+	for {
+		if l.tryEnqueue(len(b), b) || l.spillWrite(len(b), true, b) {
+			return nil
+		}
+		if err := waitContext(ctx); err != nil {
+			l.discard(&l.metrics.DiscardsCanceled, len(b), b)
+			return err
+		}
+		if l.closed.Load() {
+			l.discard(&l.metrics.DiscardsClosed, len(b), b)
+			return ErrClosed
+		}
+	}
+}
+
+// lockContext locks l.mu, or returns ctx.Err() if ctx is done before the
+// lock is acquired.
+func (l *Logger) lockContext(ctx context.Context) error {
+	for i := 0; ; i++ {
+		if l.mu.TryLock() {
+			return nil
+		}
+		if i < tryWriteSpins {
+			runtime.Gosched()
+			continue
+		}
+		if err := waitContext(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// waitContext waits for contextPollInterval, or returns ctx.Err() if ctx is
+// done in the meantime.
+func waitContext(ctx context.Context) error {
+	timer := time.NewTimer(contextPollInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package logrotate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteContext(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WriteContext")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.WriteContext(canceled, []byte("1"))
+	require.ErrorIs(t, err, context.Canceled, "WriteContext should fail with canceled ctx")
+
+	// hold l.mu, so the first write can't open the file.
+	l.mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.WriteContext(ctx, []byte("2"))
+	require.ErrorIs(t, err, context.DeadlineExceeded, "WriteContext should give up on contended lock")
+	l.mu.Unlock()
+
+	n, err := l.WriteContext(context.Background(), []byte("3"))
+	require.NoError(t, err, "WriteContext should succeed")
+	require.Equal(t, 1, n, "written bytes should match")
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "3", string(b), "only the accepted write should be written")
+
+	metrics := l.Metrics()
+	require.Equal(t, uint64(2), metrics.DiscardedCanceled, "canceled writes should be counted")
+	require.Equal(t, uint64(2), metrics.DiscardedBytes, "discarded bytes should be counted")
+}
+
+func Test_WriteContext_Buffered(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WriteContext_Buffered")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"), WithWriteChan(1))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	// hold l.mu, so writeLoop is blocked on writing the first line, and
+	// the queue is full with the next ones.
+	l.mu.Lock()
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	for l.queued() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.WriteContext(ctx, []byte("2"))
+	require.ErrorIs(t, err, context.DeadlineExceeded, "WriteContext should give up on full queue")
+	require.Equal(t, uint64(1), l.Metrics().DiscardedCanceled, "canceled write should be counted")
+
+	// waits for room instead of discarding.
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.WriteContext(context.Background(), []byte("3"))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.mu.Unlock()
+	require.NoError(t, <-done, "WriteContext should succeed once queue has room")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, byte('3'), b[len(b)-1], "waiting write should be written last")
+}
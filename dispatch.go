@@ -0,0 +1,66 @@
+package logrotate
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Route routes the writes matched by Match to Logger, see Dispatcher.
+type Route struct {
+	// Match reports whether the payload of a write goes to Logger. It must
+	// not retain or modify the payload.
+	Match  func(b []byte) bool
+	Logger *Logger
+}
+
+// MatchContains returns a Route matcher which matches the payloads
+// containing substr, e.g.: "AUDIT".
+func MatchContains(substr string) func(b []byte) bool {
+	sep := []byte(substr)
+	return func(b []byte) bool {
+		return bytes.Contains(b, sep)
+	}
+}
+
+// Dispatcher is an io.Writer which routes each write to one of several
+// Loggers by its payload, so that a single io.Writer handed to legacy code
+// can feed differently-retained files, e.g.: the lines containing "AUDIT"
+// to the audit pattern with 1-year retention, and the others to the
+// application pattern with 7-day retention.
+//
+// NOTE: the Loggers are owned by the caller, so they are not closed by the
+// Dispatcher.
+type Dispatcher struct {
+	routes   []Route
+	fallback *Logger
+}
+
+// NewDispatcher returns a Dispatcher which routes each write to the Logger
+// of the first route whose Match returns true, or to fallback if none does.
+func NewDispatcher(fallback *Logger, routes ...Route) (*Dispatcher, error) {
+	if fallback == nil {
+		return nil, errors.New("logrotate: nil fallback logger")
+	}
+	for _, r := range routes {
+		if r.Match == nil || r.Logger == nil {
+			return nil, errors.New("logrotate: route without matcher or logger")
+		}
+	}
+	return &Dispatcher{routes: append([]Route(nil), routes...), fallback: fallback}, nil
+}
+
+// Write writes b as a single log entry to the Logger routed to, see
+// NewDispatcher. It returns the result of the Logger's Write.
+func (d *Dispatcher) Write(b []byte) (n int, err error) {
+	return d.route(b).Write(b)
+}
+
+// route returns the Logger which b is routed to.
+func (d *Dispatcher) route(b []byte) *Logger {
+	for _, r := range d.routes {
+		if r.Match(b) {
+			return r.Logger
+		}
+	}
+	return d.fallback
+}
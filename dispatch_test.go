@@ -0,0 +1,52 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Dispatcher(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Dispatcher")
+	defer os.RemoveAll(dir)
+
+	audit, err := New(filepath.Join(dir, "audit.log"), WithMaxAge(365*24*time.Hour))
+	require.NoError(t, err, "New should succeed")
+	defer audit.Close()
+	errs, err := New(filepath.Join(dir, "error.log"))
+	require.NoError(t, err, "New should succeed")
+	defer errs.Close()
+	app, err := New(filepath.Join(dir, "app.log"), WithMaxAge(7*24*time.Hour))
+	require.NoError(t, err, "New should succeed")
+	defer app.Close()
+
+	_, err = NewDispatcher(nil)
+	require.Error(t, err, "nil fallback should be rejected")
+	_, err = NewDispatcher(app, Route{Match: MatchContains("AUDIT")})
+	require.Error(t, err, "route without logger should be rejected")
+
+	d, err := NewDispatcher(app,
+		Route{Match: MatchContains("AUDIT"), Logger: audit},
+		Route{Match: MatchContains("ERROR"), Logger: errs},
+	)
+	require.NoError(t, err, "NewDispatcher should succeed")
+	for _, line := range []string{"INFO started\n", "AUDIT login\n", "ERROR failed\n", "AUDIT ERROR denied\n"} {
+		_, err := fmt.Fprint(d, line)
+		require.NoError(t, err, "Write should succeed")
+	}
+
+	want := map[string]string{
+		"audit.log": "AUDIT login\nAUDIT ERROR denied\n",
+		"error.log": "ERROR failed\n",
+		"app.log":   "INFO started\n",
+	}
+	for name, content := range want {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Equal(t, content, string(b), "writes should be routed by the first matched route")
+	}
+}
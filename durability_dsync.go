@@ -0,0 +1,10 @@
+//go:build darwin || linux || netbsd || openbsd || solaris
+
+package logrotate
+
+import (
+	"syscall"
+)
+
+// oDSync is the open flag for DurabilityDSync.
+const oDSync = syscall.O_DSYNC
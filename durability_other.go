@@ -0,0 +1,11 @@
+//go:build !(darwin || linux || netbsd || openbsd || solaris)
+
+package logrotate
+
+import (
+	"os"
+)
+
+// oDSync falls back to O_SYNC, as O_DSYNC is not supported on this
+// platform.
+const oDSync = os.O_SYNC
@@ -0,0 +1,107 @@
+package logrotate
+
+import (
+	"errors"
+)
+
+var (
+	// ErrClosed is returned by Write, Rotate and Close after the Logger
+	// was closed.
+	ErrClosed = errors.New("logrotate: logger closed")
+
+	// ErrDiscarded is returned by Write in buffered mode when the write
+	// channel is full and the log line is discarded.
+	ErrDiscarded = errors.New("logrotate: write channel full, discarded")
+
+	// ErrWouldBlock is returned by TryWrite if the write would block.
+	ErrWouldBlock = errors.New("logrotate: write would block")
+
+	// ErrFileLocked is returned if Exclusive is enabled and the log file is
+	// locked by another Logger instance or process.
+	ErrFileLocked = errors.New("logrotate: log file is locked by another instance")
+
+	// ErrPatternInUse is returned by New if another open Logger in the
+	// process uses the same pattern, and they are not both shared.
+	ErrPatternInUse = errors.New("logrotate: pattern used by another logger")
+
+	// ErrWriteTimeout is returned by Write if WriteTimeout is set and the
+	// file write didn't complete in time, or the logger is degraded by a
+	// previous hung write. The result of a timed out write is indeterminate:
+	// it may still complete later, so a retry may duplicate the entry.
+	ErrWriteTimeout = errors.New("logrotate: write timeout")
+
+	// ErrReadOnly is reported to the ErrorHandler when the filesystem turns
+	// read-only, and returned by Write in the read-only mode if
+	// FallbackWriter is not set.
+	ErrReadOnly = errors.New("logrotate: filesystem is read-only")
+
+	// ErrOpenBackoff is reported to the ErrorHandler when opening the log
+	// file keeps failing, e.g.: EACCES, and returned by Write while backing
+	// off from reopening if FallbackWriter is not set.
+	ErrOpenBackoff = errors.New("logrotate: failed to open log file, backing off")
+
+	// ErrQueueSaturated is returned by Healthy if the queue is full in
+	// buffered mode, so new log lines are being discarded.
+	ErrQueueSaturated = errors.New("logrotate: write queue saturated")
+
+	// ErrClockRegression is reported to the ErrorHandler when the clock
+	// jumps backwards before the rotation time of the current file, e.g.:
+	// on NTP step. The rotation time is kept until the clock catches up.
+	ErrClockRegression = errors.New("logrotate: clock jumped backwards")
+
+	// ErrPartialWrite is reported to the ErrorHandler when a write failed
+	// part way, and the remainder was written to the reopened log file
+	// with PartialWriteRecovery.
+	ErrPartialWrite = errors.New("logrotate: partial write recovered")
+
+	// ErrNotFinalized is returned by ReadFinalMarker if the file has no
+	// valid finalization record.
+	ErrNotFinalized = errors.New("logrotate: log file not finalized")
+
+	// ErrRotationVetoed is returned by Rotate if the PreRotateHook vetoed
+	// the rotation. Rotations on writes are deferred to the next write
+	// instead.
+	ErrRotationVetoed = errors.New("logrotate: rotation vetoed")
+
+	// ErrNotArchived is reported to the ErrorHandler in a PurgeError when
+	// the deletion of a log file is deferred by VerifyBeforeDelete, as its
+	// remote object is missing or doesn't match.
+	ErrNotArchived = errors.New("logrotate: log file not archived remotely")
+
+	// ErrFileInUse is reported to the ErrorHandler in a PurgeError when the
+	// deletion of a log file is deferred by MinAgeBeforeDelete or
+	// OpenFileCheck, as it may still be read, e.g.: by a log shipper.
+	ErrFileInUse = errors.New("logrotate: log file in use")
+)
+
+// RotationError records an error and the operation and file path that
+// caused it while opening, rotating or closing log files.
+type RotationError struct {
+	Op   string // operation, e.g.: "open", "close", "mkdir", "lock"
+	Path string // path of the log file or directory
+	Err  error  // underlying error
+}
+
+func (e *RotationError) Error() string {
+	return "logrotate: " + e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *RotationError) Unwrap() error {
+	return e.Err
+}
+
+// PurgeError records an error and the file path that caused it while
+// removing old log files by the mill goroutine. It's reported to the
+// ErrorHandler.
+type PurgeError struct {
+	Path string // path of the log file to be removed
+	Err  error  // underlying error
+}
+
+func (e *PurgeError) Error() string {
+	return "logrotate: purge " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *PurgeError) Unwrap() error {
+	return e.Err
+}
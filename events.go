@@ -0,0 +1,61 @@
+package logrotate
+
+import (
+	"time"
+)
+
+// rotationEventsSize is the buffer size of the RotationEvents channel.
+const rotationEventsSize = 64
+
+// RotationEvent is sent on the RotationEvents channel once a rotated file
+// is closed.
+type RotationEvent struct {
+	Time    time.Time    // when the rotated file was closed
+	Path    string       // path of the rotated file, moved by the RotateHook if any
+	Current string       // path of the file rotated to
+	Reason  RotateReason // reason of the rotation, 0 if finished by a Rotator
+	Stats   FileStats    // stats of the rotated file, with Path as above
+}
+
+// RotationEvents returns the channel which receives a RotationEvent once a
+// rotated file is closed, so that shippers can react to rotations without
+// watching the filesystem. The same channel is returned on each call, and
+// it's closed on Close. Events are only sent after the first call.
+//
+// NOTE: events are dropped if the channel is full, as rotations never wait
+// for the receiver.
+func (l *Logger) RotationEvents() <-chan RotationEvent {
+	l.eventsMu.Lock()
+	defer l.eventsMu.Unlock()
+	if l.events == nil {
+		l.events = make(chan RotationEvent, rotationEventsSize)
+		if l.eventsClosed {
+			close(l.events)
+		}
+	}
+	return l.events
+}
+
+// sendRotationEvent sends e to the RotationEvents channel if it was
+// requested, or drops e if the channel is full.
+func (l *Logger) sendRotationEvent(e RotationEvent) {
+	l.eventsMu.Lock()
+	defer l.eventsMu.Unlock()
+	if l.events == nil || l.eventsClosed {
+		return
+	}
+	select {
+	case l.events <- e:
+	default:
+	}
+}
+
+// closeRotationEvents closes the RotationEvents channel.
+func (l *Logger) closeRotationEvents() {
+	l.eventsMu.Lock()
+	defer l.eventsMu.Unlock()
+	if l.events != nil && !l.eventsClosed {
+		close(l.events)
+	}
+	l.eventsClosed = true
+}
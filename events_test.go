@@ -0,0 +1,76 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RotationEvents(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotationEvents")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "New should succeed")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+
+	events := l.RotationEvents()
+	require.Equal(t, events, l.RotationEvents(), "the same channel should be returned")
+	require.Empty(t, events, "events before the first call should not be sent")
+
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = l.Write([]byte("34"))
+	require.NoError(t, err, "Write should succeed")
+	stats := l.CurrentStats()
+	require.Equal(t, filepath.Join(dir, "app.log.1"), stats.Path, "Path should be the current file")
+	require.Equal(t, int64(2), stats.Entries, "Entries should match")
+	require.Equal(t, int64(3), stats.Size, "Size should match")
+	require.False(t, stats.FirstWrite.After(stats.LastWrite), "FirstWrite should not be after LastWrite")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+
+	e := <-events
+	require.Equal(t, filepath.Join(dir, "app.log.1"), e.Path, "Path should be the rotated file")
+	require.Equal(t, filepath.Join(dir, "app.log.2"), e.Current, "Current should be the file rotated to")
+	require.False(t, e.Time.IsZero(), "Time should be set")
+	require.Equal(t, e.Path, e.Stats.Path, "Stats.Path should be the rotated file")
+	require.Equal(t, int64(2), e.Stats.Entries, "Stats.Entries should match")
+	require.Equal(t, int64(3), e.Stats.Size, "Stats.Size should match")
+	require.Equal(t, stats.FirstWrite, e.Stats.FirstWrite, "Stats.FirstWrite should match")
+	require.Equal(t, RotateByRequest, e.Reason, "Reason should match")
+	require.Zero(t, l.CurrentStats().Entries, "stats should be reset for the new file")
+
+	require.NoError(t, l.Close(), "Close should succeed")
+	_, ok := <-events
+	require.False(t, ok, "channel should be closed on Close")
+}
+
+func Test_RotationReason(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotationReason")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(4))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	events := l.RotationEvents()
+
+	for _, line := range []string{"aaa", "bbb"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	require.Equal(t, RotateBySize, (<-events).Reason, "Reason should be size")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	require.Equal(t, RotateByRequest, (<-events).Reason, "Reason should be request")
+
+	m := l.Metrics()
+	require.Equal(t, uint64(1), m.RotationsBySize, "RotationsBySize should match")
+	require.Equal(t, uint64(1), m.RotationsByRequest, "RotationsByRequest should match")
+	require.Zero(t, m.RotationsByInterval, "RotationsByInterval should match")
+	require.Equal(t, "request", RotateByRequest.String(), "String should match")
+	require.Equal(t, RotateByRecovery, parseRotateReason("recovery"), "parseRotateReason should match")
+}
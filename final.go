@@ -0,0 +1,149 @@
+package logrotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// finalPrefix is the prefix of the finalization record line.
+const finalPrefix = "#logrotate-final "
+
+// maxFinalRecord is the max length of a finalization record line.
+const maxFinalRecord = 128
+
+// FinalMarker is the finalization record appended as the last line of a file
+// closed by rotation or Close if WithFinalMarker, e.g.:
+//
+//	#logrotate-final entries=42 size=4096 crc32=8d2f4e1a
+//
+// so consumers can distinguish cleanly finished files from ones truncated by
+// a crash.
+type FinalMarker struct {
+	Entries int64  // count of writes by the Logger which finalized the file
+	Size    int64  // size of the content before the record
+	CRC32   uint32 // IEEE CRC-32 checksum of the content before the record
+}
+
+// record returns the record line of m.
+func (m FinalMarker) record() []byte {
+	return []byte(fmt.Sprintf("%sentries=%d size=%d crc32=%08x\n", finalPrefix, m.Entries, m.Size, m.CRC32))
+}
+
+// ReadFinalMarker reads the finalization record of the file at path, and
+// verifies the content before it. It returns ErrNotFinalized if the file has
+// no record as the last line, or the content doesn't match the record, e.g.:
+// the file was truncated by a crash, or appended after finalized.
+func ReadFinalMarker(path string) (FinalMarker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FinalMarker{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return FinalMarker{}, err
+	}
+	size := fi.Size()
+	if size > maxFinalRecord {
+		size = maxFinalRecord
+	}
+	tail := make([]byte, size)
+	if _, err := f.ReadAt(tail, fi.Size()-size); err != nil {
+		return FinalMarker{}, err
+	}
+	line, ok := bytes.CutSuffix(tail, []byte("\n"))
+	if !ok {
+		return FinalMarker{}, ErrNotFinalized
+	}
+	line = line[bytes.LastIndexByte(line, '\n')+1:]
+	var m FinalMarker
+	if _, err := fmt.Sscanf(string(line), finalPrefix+"entries=%d size=%d crc32=%08x", &m.Entries, &m.Size, &m.CRC32); err != nil {
+		return FinalMarker{}, ErrNotFinalized
+	}
+	if m.Size+int64(len(line))+1 != fi.Size() {
+		return m, fmt.Errorf("%w: size mismatch", ErrNotFinalized)
+	}
+	sum, err := checksumFile(f, m.Size)
+	if err != nil {
+		return m, err
+	}
+	if sum != m.CRC32 {
+		return m, fmt.Errorf("%w: checksum mismatch", ErrNotFinalized)
+	}
+	return m, nil
+}
+
+// finalize appends the finalization record to the file of h if
+// WithFinalMarker, after in-flight writes are done.
+func (l *Logger) finalize(h *fileHandle) error {
+	if !l.opts.finalMarker {
+		return nil
+	}
+	h.retire()
+	f, ok := h.osFile()
+	if !ok {
+		return nil
+	}
+	if err := h.flush(); err != nil {
+		return &RotationError{Op: "finalize", Path: h.name, Err: err}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return &RotationError{Op: "finalize", Path: h.name, Err: err}
+	}
+	if l.opts.savelogCycle > 0 {
+		// so the file won't be shifted in the meantime.
+		l.savelogMu.Lock()
+		defer l.savelogMu.Unlock()
+	}
+	// the file is opened write-only, so read it by its path, which may
+	// have been moved by the rotation.
+	path := h.name
+	if h.archivedName != "" {
+		path = h.archivedName
+	}
+	candidates := []string{path}
+	if l.opts.savelogCycle > 0 {
+		candidates = append(candidates, savelogFilename(h.name, 0, false))
+	}
+	var r *os.File
+	for _, name := range candidates {
+		if r, err = os.Open(name); err != nil {
+			continue
+		}
+		if rfi, err := r.Stat(); err == nil && os.SameFile(fi, rfi) {
+			break
+		}
+		r.Close()
+		r = nil
+	}
+	if r == nil {
+		return &RotationError{Op: "finalize", Path: path, Err: errors.New("file moved")}
+	}
+	defer r.Close()
+	sum, err := checksumFile(r, fi.Size())
+	if err != nil {
+		return &RotationError{Op: "finalize", Path: path, Err: err}
+	}
+	m := FinalMarker{Entries: h.entries.Load(), Size: fi.Size(), CRC32: sum}
+	n, err := f.Write(m.record())
+	h.size.Add(int64(n))
+	if err != nil {
+		return &RotationError{Op: "finalize", Path: path, Err: err}
+	}
+	return nil
+}
+
+// checksumFile returns the IEEE CRC-32 checksum of the first size bytes of
+// f.
+func checksumFile(f *os.File, size int64) (uint32, error) {
+	hash := crc32.NewIEEE()
+	if _, err := io.Copy(hash, io.NewSectionReader(f, 0, size)); err != nil {
+		return 0, err
+	}
+	return hash.Sum32(), nil
+}
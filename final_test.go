@@ -0,0 +1,68 @@
+package logrotate
+
+import (
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FinalMarker(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_FinalMarker")
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name    string
+		options []Option
+		rotated string
+	}{
+		{"sequence", nil, "app.log"},
+		{"savelog", []Option{WithSavelog(3, false)}, "app.log.0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, os.RemoveAll(dir), "RemoveAll should succeed")
+			options := append([]Option{WithFinalMarker(true), WithMaxSize(6)}, tc.options...)
+			l, err := New(filepath.Join(dir, "app.log"), options...)
+			require.NoError(t, err, "New should succeed")
+			require.True(t, l.Options().FinalMarker, "FinalMarker should match")
+
+			for _, line := range []string{"aaa\n", "bb\n", "c\n"} {
+				_, err := l.Write([]byte(line))
+				require.NoError(t, err, "Write should succeed")
+			}
+			require.NoError(t, l.Close(), "Close should succeed")
+
+			m, err := ReadFinalMarker(filepath.Join(dir, tc.rotated))
+			require.NoError(t, err, "rotated file should be finalized")
+			require.Equal(t, FinalMarker{Entries: 1, Size: 4, CRC32: crc32.ChecksumIEEE([]byte("aaa\n"))}, m, "FinalMarker should match")
+
+			current := filepath.Join(dir, "app.log")
+			if tc.rotated == "app.log" {
+				current = filepath.Join(dir, "app.log.1")
+			}
+			m, err = ReadFinalMarker(current)
+			require.NoError(t, err, "current file should be finalized on Close")
+			require.Equal(t, int64(2), m.Entries, "Entries should match")
+			require.Equal(t, int64(5), m.Size, "Size should match")
+
+			f, err := os.OpenFile(current, os.O_APPEND|os.O_WRONLY, 0644)
+			require.NoError(t, err, "OpenFile should succeed")
+			_, err = f.WriteString("dd\n")
+			require.NoError(t, err, "WriteString should succeed")
+			require.NoError(t, f.Close(), "Close should succeed")
+			_, err = ReadFinalMarker(current)
+			require.ErrorIs(t, err, ErrNotFinalized, "file appended after finalized should not be finalized")
+		})
+	}
+
+	path := filepath.Join(dir, "truncated.log")
+	require.NoError(t, os.WriteFile(path, []byte("aaa\nbb"), 0644), "WriteFile should succeed")
+	_, err := ReadFinalMarker(path)
+	require.ErrorIs(t, err, ErrNotFinalized, "truncated file should not be finalized")
+
+	require.NoError(t, os.WriteFile(path, []byte("aab\n#logrotate-final entries=1 size=4 crc32=00000000\n"), 0644), "WriteFile should succeed")
+	_, err = ReadFinalMarker(path)
+	require.ErrorIs(t, err, ErrNotFinalized, "corrupted file should not be finalized")
+}
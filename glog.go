@@ -0,0 +1,117 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Severity is the severity of glog-style log files.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	numSeverity
+)
+
+var severityNames = [numSeverity]string{"INFO", "WARNING", "ERROR"}
+
+// String returns the glog name of s, e.g.: "INFO".
+func (s Severity) String() string {
+	if s < 0 || s >= numSeverity {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// SeverityLoggers is a set of Loggers for INFO, WARNING and ERROR
+// severities, with glog compatible file names:
+//
+//	program.host.user.log.SEVERITY.%Y%m%d-%H%M%S.pid
+//
+// and symlinks "program.SEVERITY" to the current files. As glog, a log line
+// of some severity is also written to the files of all lower severities.
+//
+// NOTE: the time in file names is the rotation time evaluated based on
+// MaxInterval, rather than the file creation time as glog.
+type SeverityLoggers struct {
+	loggers [numSeverity]*Logger
+}
+
+// NewSeverityLoggers creates SeverityLoggers in dir with the provided
+// options applied to the Logger of each severity. Symlink option is
+// ignored, as symlinks are named by severities.
+func NewSeverityLoggers(dir string, options ...Option) (*SeverityLoggers, error) {
+	program := filepath.Base(os.Args[0])
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknownhost"
+	}
+	username := "unknownuser"
+	if u, err := user.Current(); err == nil {
+		// on Windows, username is like "domain\user".
+		username = strings.ReplaceAll(u.Username, `\`, "_")
+	}
+	prefix := fmt.Sprintf("%s.%s.%s.log", program, host, username)
+	// escape "%" in names, as the pattern is in strftime format.
+	prefix = strings.ReplaceAll(prefix, "%", "%%")
+
+	s := &SeverityLoggers{}
+	for sev := SeverityInfo; sev < numSeverity; sev++ {
+		pattern := filepath.Join(dir, fmt.Sprintf("%s.%s.%%Y%%m%%d-%%H%%M%%S.%d", prefix, sev, os.Getpid()))
+		symlink := filepath.Join(dir, program+"."+sev.String())
+		l, err := New(pattern, append(options[:len(options):len(options)], WithSymlink(symlink))...)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.loggers[sev] = l
+	}
+	return s, nil
+}
+
+// Logger returns the Logger of severity sev, which only writes to the files
+// of sev.
+func (s *SeverityLoggers) Logger(sev Severity) *Logger {
+	return s.loggers[sev]
+}
+
+// Writer returns a writer which writes to the files of severity sev and all
+// lower severities.
+func (s *SeverityLoggers) Writer(sev Severity) io.Writer {
+	return severityWriter{s: s, sev: sev}
+}
+
+// Close closes the Loggers of all severities.
+func (s *SeverityLoggers) Close() error {
+	var errs []error
+	for _, l := range s.loggers {
+		if l != nil {
+			errs = append(errs, l.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type severityWriter struct {
+	s   *SeverityLoggers
+	sev Severity
+}
+
+// Write writes b to the files of w.sev and all lower severities, and returns
+// the first error, if any.
+func (w severityWriter) Write(b []byte) (n int, err error) {
+	for sev := w.sev; sev >= SeverityInfo; sev-- {
+		n1, err1 := w.s.loggers[sev].Write(b)
+		if err == nil {
+			n, err = n1, err1
+		}
+	}
+	return n, err
+}
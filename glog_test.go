@@ -0,0 +1,48 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SeverityLoggers(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SeverityLoggers")
+	defer os.RemoveAll(dir)
+
+	s, err := NewSeverityLoggers(dir)
+	require.NoError(t, err, "NewSeverityLoggers should succeed")
+
+	_, err = s.Writer(SeverityInfo).Write([]byte("info\n"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = s.Writer(SeverityWarning).Write([]byte("warning\n"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = s.Writer(SeverityError).Write([]byte("error\n"))
+	require.NoError(t, err, "Write should succeed")
+	for sev := SeverityInfo; sev < numSeverity; sev++ {
+		// symlinks are created by the mill goroutine, so run it in place.
+		require.NoError(t, s.Logger(sev).millRunOnce(), "millRunOnce should succeed")
+	}
+	require.NoError(t, s.Close(), "Close should succeed")
+
+	program := filepath.Base(os.Args[0])
+	want := map[Severity]string{
+		SeverityInfo:    "info\nwarning\nerror\n",
+		SeverityWarning: "warning\nerror\n",
+		SeverityError:   "error\n",
+	}
+	for sev, content := range want {
+		files, err := filepath.Glob(filepath.Join(dir, program+".*.log."+sev.String()+".*"))
+		require.NoError(t, err, "Glob should succeed")
+		require.Equal(t, 1, len(files), "one %s file should be created", sev)
+		require.True(t, strings.HasSuffix(files[0], "."+strconv.Itoa(os.Getpid())), "file name should end with pid")
+
+		data, err := os.ReadFile(filepath.Join(dir, program+"."+sev.String()))
+		require.NoError(t, err, "ReadFile via symlink should succeed")
+		require.Equal(t, content, string(data), "%s file content should match", sev)
+	}
+}
@@ -0,0 +1,80 @@
+package logrotate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OpenFileCheck is the scope of the processes checked for holding a log
+// file open before it's deleted, see WithOpenFileCheck.
+type OpenFileCheck int
+
+const (
+	// OpenCheckNone doesn't check whether log files are held open.
+	OpenCheckNone OpenFileCheck = iota
+	// OpenCheckSelf checks the file descriptors of the current process.
+	OpenCheckSelf
+	// OpenCheckAll checks the file descriptors of all the processes which
+	// are visible and permitted, e.g.: a log shipper of the same user.
+	OpenCheckAll
+)
+
+// String returns the name of c.
+func (c OpenFileCheck) String() string {
+	switch c {
+	case OpenCheckNone:
+		return "none"
+	case OpenCheckSelf:
+		return "self"
+	case OpenCheckAll:
+		return "all"
+	default:
+		return fmt.Sprintf("OpenFileCheck(%d)", int(c))
+	}
+}
+
+// deferDeletion returns nil if the log file f selected by retention
+// policies or the QuotaGroup can be deleted now. Otherwise, it returns an
+// error, and the deletion of f should be deferred to a later pass: a file
+// modified within MinAgeBeforeDelete, or held open by a reader as checked
+// by OpenFileCheck, is reported with ErrFileInUse, and a file not archived
+// with ErrNotArchived, see verifyArchived.
+func (l *Logger) deferDeletion(f FileInfo) error {
+	if err := deferDeletion(l.ctx, l.opts, f); err != nil {
+		l.metrics.DeferredDeletes.Add(1)
+		return err
+	}
+	return nil
+}
+
+// deferDeletion is like Logger.deferDeletion, but with the options only,
+// for PurgeDir.
+func deferDeletion(ctx context.Context, opts *Options, f FileInfo) error {
+	if err := checkInUse(opts, f); err != nil {
+		return err
+	}
+	return verifyArchived(ctx, opts.remoteStore, f)
+}
+
+// checkInUse returns an error wrapping ErrFileInUse if the log file f is
+// too recent or held open, see WithMinAgeBeforeDelete and
+// WithOpenFileCheck.
+func checkInUse(opts *Options, f FileInfo) error {
+	if d := opts.minDeleteAge; d > 0 {
+		if age := opts.clock.Now().Sub(f.ModTime()); age < d {
+			return fmt.Errorf("%w: modified %v ago", ErrFileInUse, age.Truncate(time.Second))
+		}
+	}
+	if opts.openFileCheck == OpenCheckNone {
+		return nil
+	}
+	pid, err := openedBy(f.Path, opts.openFileCheck == OpenCheckAll)
+	if err != nil {
+		return fmt.Errorf("%w: check holders: %w", ErrFileInUse, err)
+	}
+	if pid != 0 {
+		return fmt.Errorf("%w: opened by pid %d", ErrFileInUse, pid)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MinAgeBeforeDelete(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MinAgeBeforeDelete")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	_, err := New(filepath.Join(dir, "app.log"), WithOpenFileCheck(OpenCheckAll+1))
+	require.Error(t, err, "invalid open file check should be rejected")
+
+	now := time.Now()
+	clock := clockwork.NewFakeClockAt(now)
+	var names []string
+	for i := 0; i < 3; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+		// the oldest one modified an hour ago, the others just now.
+		mtime := now.Add(time.Duration(i-3) * time.Second)
+		if i == 0 {
+			mtime = now.Add(-time.Hour)
+		}
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d.log"),
+		WithClock(clock),
+		WithMaxBackups(1),
+		WithMinAgeBeforeDelete(time.Minute),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, time.Minute, l.Options().MinAgeBeforeDelete, "MinAgeBeforeDelete should match")
+	_, err = l.Write([]byte("current"))
+	require.NoError(t, err, "Write should succeed")
+
+	err = l.millRunOnce()
+	require.ErrorIs(t, err, ErrFileInUse, "deferred deletion should be reported")
+	var perr *PurgeError
+	require.True(t, errors.As(err, &perr), "error should be a PurgeError")
+	require.NoFileExists(t, names[0], "old file should be removed")
+	require.FileExists(t, names[1], "recently modified file should be kept")
+	require.FileExists(t, names[2], "recently modified file should be kept")
+	require.NotZero(t, l.Metrics().DeferredDeletes, "DeferredDeletes should be counted")
+
+	// removed on a later pass, once old enough.
+	clock.Advance(time.Minute)
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, names[1], "file should be removed once old enough")
+	require.NoFileExists(t, names[2], "file should be removed once old enough")
+}
+
+func Test_MinAgeBeforeDelete_PurgeDir(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MinAgeBeforeDelete_PurgeDir")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Now()
+	var names []string
+	for i := 0; i < 3; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+		mtime := now.Add(time.Duration(i-3) * time.Hour)
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+
+	clock := clockwork.NewFakeClockAt(now)
+	removed, err := PurgeDir(
+		filepath.Join(dir, "app.%Y%m%d.log"),
+		NewMaxBackupsPolicy(0),
+		WithClock(clock),
+		WithMinAgeBeforeDelete(150*time.Minute),
+	)
+	require.ErrorIs(t, err, ErrFileInUse, "deferred deletion should be reported")
+	var perr *PurgeError
+	require.True(t, errors.As(err, &perr), "error should be a PurgeError")
+	require.Equal(t, names[1], perr.Path, "recently modified file should be deferred")
+	require.Equal(t, names[:1], removed, "old file should be removed")
+	require.FileExists(t, names[1], "recently modified file should be kept")
+	require.FileExists(t, names[2], "newest file should be kept")
+}
+
+func Test_MinAgeBeforeDelete_DayBundle(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MinAgeBeforeDelete_DayBundle")
+	defer os.RemoveAll(dir)
+
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := clockwork.NewFakeClockAt(day1)
+	l, err := New(
+		filepath.Join(dir, "app.log.%Y%m%d"),
+		WithClock(clock),
+		WithMaxInterval(24*time.Hour),
+		WithDayBundle(BundleZip),
+		WithMinAgeBeforeDelete(36*time.Hour),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	_, err = l.Write([]byte("aaa\n"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, os.Chtimes(l.currentFilename(), day1, day1), "Chtimes should succeed")
+
+	clock.Advance(24 * time.Hour)
+	_, err = l.Write([]byte("bbb\n"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NotZero(t, l.Metrics().DeferredDeletes, "DeferredDeletes should be counted")
+	require.FileExists(t, filepath.Join(dir, "app.log.20240101"), "recently modified file should not be bundled")
+	require.NoFileExists(t, filepath.Join(dir, "app.log.20240101.zip"), "bundle should not be created")
+
+	// bundled on a later pass, once old enough.
+	clock.Advance(12 * time.Hour)
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, filepath.Join(dir, "app.log.20240101"), "file should be bundled once old enough")
+	require.FileExists(t, filepath.Join(dir, "app.log.20240101.zip"), "bundle should be created")
+}
@@ -0,0 +1,275 @@
+package logrotate
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// groupCommit fsyncs the written files in batches, so that writes can be
+// acknowledged once durable without paying an fsync each. Bytes written
+// are numbered by their position in the stream of all writes, and synced
+// is the durability watermark: all bytes before it are on stable storage.
+type groupCommit struct {
+	maxDelay time.Duration
+	maxBytes int64
+	kick     chan struct{} // wakes commitLoop up before maxDelay
+
+	// syncMu serializes commits and the syncs of files being closed, so
+	// that a file in dirty is never synced after it's closed.
+	syncMu sync.Mutex
+
+	mu      sync.Mutex // guards following
+	cond    *sync.Cond // broadcast when synced, err or stopped changes
+	dirty   map[*fileHandle]struct{}
+	written uint64 // position of the latest write
+	pending int64  // bytes written since the last commit
+	synced  uint64 // durability watermark
+	stopped bool   // set when commitLoop has stopped
+
+	// the bytes in (lostFrom, lostTo] failed to be committed with err.
+	lostFrom uint64
+	lostTo   uint64
+	err      error
+
+	commits atomic.Uint64 // count of group commits
+}
+
+// groupCommit returns the groupCommit of the options, or nil if disabled.
+func (opts *Options) groupCommit() *groupCommit {
+	if opts.groupCommitDelay <= 0 {
+		return nil
+	}
+	c := &groupCommit{
+		maxDelay: opts.groupCommitDelay,
+		maxBytes: opts.groupCommitBytes,
+		kick:     make(chan struct{}, 1),
+		dirty:    make(map[*fileHandle]struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// wrote records n bytes written to h, and wakes commitLoop up once
+// maxBytes are pending. It's a no-op on a nil groupCommit.
+func (c *groupCommit) wrote(h *fileHandle, n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.dirty[h] = struct{}{}
+	c.written += uint64(n)
+	c.pending += int64(n)
+	full := c.maxBytes > 0 && c.pending >= c.maxBytes
+	c.mu.Unlock()
+	if full {
+		c.wake()
+	}
+}
+
+// wake wakes commitLoop up. It's ok to skip if kick is full.
+func (c *groupCommit) wake() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+// position returns the position of the latest write, or 0 on a nil
+// groupCommit.
+func (c *groupCommit) position() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written
+}
+
+// wait blocks until the bytes before pos are synced, and returns the error
+// if their commit failed. It returns nil once commitLoop has stopped, as
+// the files are synced on close then. It's a no-op on a nil groupCommit.
+func (c *groupCommit) wait(pos uint64) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		switch {
+		case c.err != nil && c.lostFrom < pos && pos <= c.lostTo:
+			return c.err
+		case c.synced >= pos || c.stopped:
+			return nil
+		}
+		c.cond.Wait()
+	}
+}
+
+// commit syncs all the files written since the last commit, and advances
+// the watermark on success.
+func (c *groupCommit) commit() error {
+	c.syncMu.Lock()
+	c.mu.Lock()
+	pos, dirty := c.written, c.dirty
+	if len(dirty) == 0 && c.synced >= pos {
+		c.mu.Unlock()
+		c.syncMu.Unlock()
+		return nil
+	}
+	c.dirty = make(map[*fileHandle]struct{})
+	c.pending = 0
+	c.mu.Unlock()
+
+	var errs []error
+	for h := range dirty {
+		if err := syncHandle(h); err != nil {
+			errs = append(errs, &RotationError{Op: "sync", Path: h.name, Err: err})
+		}
+	}
+	c.syncMu.Unlock()
+	err := errors.Join(errs...)
+
+	c.mu.Lock()
+	if err != nil {
+		c.fail(pos, err)
+	} else if pos > c.synced {
+		c.synced = pos
+	}
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	c.commits.Add(1)
+	return err
+}
+
+// release syncs h if written since the last commit, before h is closed. h
+// must be retired, so it's not written anymore. It's a no-op on a nil
+// groupCommit.
+func (c *groupCommit) release(h *fileHandle) error {
+	if c == nil {
+		return nil
+	}
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	c.mu.Lock()
+	_, ok := c.dirty[h]
+	delete(c.dirty, h)
+	pos := c.written
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := syncHandle(h); err != nil {
+		c.mu.Lock()
+		c.fail(pos, &RotationError{Op: "sync", Path: h.name, Err: err})
+		c.cond.Broadcast()
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// fail records that the bytes not yet synced before pos failed to be
+// committed with err. c.mu must be held by the caller.
+func (c *groupCommit) fail(pos uint64, err error) {
+	c.lostFrom, c.lostTo, c.err = c.synced, pos, err
+}
+
+// stop releases all the waiters, as nothing is committed after.
+func (c *groupCommit) stop() {
+	c.mu.Lock()
+	c.stopped = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+}
+
+// watermark returns the durability watermark.
+func (c *groupCommit) watermark() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.synced
+}
+
+// syncHandle fsyncs the file of h, if it's an *os.File, after writing out
+// the writes buffered by WithAlignedWrites.
+func syncHandle(h *fileHandle) error {
+	if err := h.flush(); err != nil {
+		return err
+	}
+	if f, ok := h.osFile(); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// commitLoop runs in a goroutine to commit the written files every
+// maxDelay, or once maxBytes are pending, until Close is called.
+func (l *Logger) commitLoop() {
+	c := l.commit
+	ticker := time.NewTicker(c.maxDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.kick:
+		case <-l.life.quit:
+			if err := c.commit(); err != nil {
+				l.handleError(err)
+			}
+			c.stop()
+			return
+		}
+		if err := c.commit(); err != nil {
+			l.handleError(err)
+		}
+	}
+}
+
+// closeHandle closes h, which is synced first if WithGroupCommit, so the
+// bytes written to it are durable before the watermark passes them.
+func (l *Logger) closeHandle(h *fileHandle) error {
+	h.retire()
+	err := l.commit.release(h)
+	return errors.Join(err, h.Close())
+}
+
+// Sync commits the data written so far to stable storage. In buffered
+// mode, the entries already queued are written first. With
+// WithGroupCommit, it triggers a group commit and waits until the
+// durability watermark passes the data, see Metrics.CommittedBytes.
+// Otherwise, it fsyncs the current file.
+//
+// It returns ErrClosed after Close called.
+func (l *Logger) Sync() error {
+	var err error
+	done := make(chan struct{})
+	ok := l.submit(func() {
+		err = l.sync()
+		close(done)
+	})
+	if !ok {
+		return ErrClosed
+	}
+	<-done
+	return err
+}
+
+// sync commits the data written so far to stable storage.
+func (l *Logger) sync() error {
+	if c := l.commit; c != nil {
+		pos := c.position()
+		c.wake()
+		return c.wait(pos)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.file.Load()
+	if h == nil {
+		return nil
+	}
+	if err := syncHandle(h); err != nil {
+		return &RotationError{Op: "sync", Path: h.name, Err: err}
+	}
+	return nil
+}
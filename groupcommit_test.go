@@ -0,0 +1,90 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GroupCommit(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_GroupCommit")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithGroupCommit(0, 0))
+	require.Error(t, err, "non-positive delay should be rejected")
+	_, err = New(filepath.Join(dir, "app.log"), WithGroupCommit(time.Millisecond, 0), WithDurability(DurabilityDSync))
+	require.Error(t, err, "group commit with durability should be rejected")
+
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithMaxSize(40),
+		WithGroupCommit(time.Hour, 10),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, time.Hour, l.Options().GroupCommitDelay, "GroupCommitDelay should match")
+	require.Equal(t, int64(10), l.Options().GroupCommitBytes, "GroupCommitBytes should match")
+
+	// concurrent writes are acknowledged by commits triggered by maxBytes,
+	// long before maxDelay.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := l.Write([]byte("0123456789"))
+			require.NoError(t, err, "Write should succeed")
+		}()
+	}
+	wg.Wait()
+	m := l.Metrics()
+	require.Equal(t, uint64(40), m.CommittedBytes, "all writes should be committed")
+	require.NotZero(t, m.GroupCommits, "GroupCommits should be counted")
+	require.LessOrEqual(t, m.GroupCommits, uint64(4), "writes should be committed in groups")
+
+	// the rotated file is synced on close, and Sync commits the pending
+	// bytes below maxBytes, which TryWrite doesn't wait for.
+	_, err = l.TryWrite([]byte("01234"))
+	require.NoError(t, err, "TryWrite should succeed")
+	require.Equal(t, uint64(40), l.Metrics().CommittedBytes, "TryWrite should not be committed yet")
+	require.NoError(t, l.Sync(), "Sync should succeed")
+	require.Equal(t, uint64(45), l.Metrics().CommittedBytes, "Sync should commit the write")
+}
+
+func Test_GroupCommit_Buffered(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_GroupCommit_Buffered")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(16), WithGroupCommit(time.Hour, 0))
+	require.NoError(t, err, "New should succeed")
+
+	for i := 0; i < 3; i++ {
+		_, err = l.Write([]byte("abc\n"))
+		require.NoError(t, err, "Write should succeed")
+	}
+	require.NoError(t, l.Sync(), "Sync should succeed")
+	require.Equal(t, uint64(12), l.Metrics().CommittedBytes, "Sync should commit the queued writes")
+
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.ErrorIs(t, l.Sync(), ErrClosed, "Sync should fail after Close")
+}
+
+func Test_Sync(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Sync")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	require.NoError(t, l.Sync(), "Sync should succeed without a file")
+	_, err = l.Write([]byte("abc\n"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Sync(), "Sync should succeed")
+	require.Zero(t, l.Metrics().CommittedBytes, "CommittedBytes should be 0 without group commit")
+}
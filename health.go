@@ -0,0 +1,70 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// healthErrorWindow is how long an error makes the Logger unhealthy.
+const healthErrorWindow = 30 * time.Second
+
+// errorRecord is an error and when it occurred.
+type errorRecord struct {
+	err error
+	at  time.Time
+}
+
+// recordError records err as the last error, for Healthy.
+func (l *Logger) recordError(err error) {
+	l.lastErr.Store(&errorRecord{err: err, at: l.opts.clock.Now()})
+}
+
+// Healthy performs a cheap end-to-end check of the Logger, suitable for
+// readiness probes, e.g.: /healthz, so services can restart or alert when
+// logging is broken. It returns nil if healthy, otherwise the reason:
+//
+//   - ErrClosed, if the Logger was closed.
+//   - ErrWriteTimeout, if degraded by a hung write.
+//   - ErrReadOnly or ErrOpenBackoff, if the log file can't be written.
+//   - a *RotationError, if the current file can't be stat'ed.
+//   - ErrQueueSaturated, if the default queue is full in buffered mode.
+//   - the last error, if any occurred in the last 30 seconds, e.g.: a
+//     failed write or a background error reported to the ErrorHandler.
+func (l *Logger) Healthy() error {
+	if l.closed.Load() {
+		return ErrClosed
+	}
+	if l.Degraded() {
+		return ErrWriteTimeout
+	}
+
+	var err error
+	l.mu.RLock()
+	if l.readOnly {
+		err = fmt.Errorf("%w: %w", ErrReadOnly, l.readOnlyErr)
+	} else if l.openFailing {
+		err = fmt.Errorf("%w: %w", ErrOpenBackoff, l.openErr)
+	}
+	h := l.file.Load()
+	l.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if h != nil {
+		// a removed file is reopened on the next write.
+		if _, err := l.osStat(h.name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return &RotationError{Op: "stat", Path: h.name, Err: err}
+		}
+	}
+
+	// a custom queue may be unbounded, so only the default one is checked.
+	if l.queue != nil && l.opts.queue == nil && l.queue.Len() >= l.queueBound() {
+		return ErrQueueSaturated
+	}
+	if r := l.lastErr.Load(); r != nil && l.opts.clock.Now().Sub(r.at) < healthErrorWindow {
+		return fmt.Errorf("recent error at %s: %w", r.at.Format(time.RFC3339), r.err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Healthy(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Healthy")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClock()
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithClock(clock),
+		WithWriteChan(1),
+		WithRotateHook(func(path string) (string, error) {
+			return "", errors.New("hook failed")
+		}),
+		WithErrorHandler(func(err error) {}),
+	)
+	require.NoError(t, err, "New should succeed")
+	require.NoError(t, l.Healthy(), "new Logger should be healthy")
+
+	// block writeLoop, so the queue is saturated.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should be queued")
+	require.ErrorIs(t, l.Healthy(), ErrQueueSaturated, "saturated queue should be unhealthy")
+	close(release)
+	require.Eventually(t, func() bool {
+		return l.Healthy() == nil
+	}, time.Second, time.Millisecond, "drained queue should be healthy")
+
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	err = l.Healthy()
+	require.Error(t, err, "recent error should be unhealthy")
+	require.ErrorContains(t, err, "hook failed", "recent error should be returned")
+
+	clock.Advance(healthErrorWindow)
+	require.NoError(t, l.Healthy(), "old error should not be unhealthy")
+
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.ErrorIs(t, l.Healthy(), ErrClosed, "closed Logger should be unhealthy")
+}
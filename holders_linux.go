@@ -0,0 +1,49 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// procDir is the mount point of procfs.
+var procDir = "/proc"
+
+// openedBy returns the pid of a process holding the file at path open, or
+// 0 if none does, by reading the links of the file descriptors in procfs,
+// as fuser does. Only the current process is checked unless all is true.
+// The processes not permitted to inspect are skipped.
+func openedBy(path string, all bool) (int, error) {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	pids := []int{os.Getpid()}
+	if all {
+		entries, err := os.ReadDir(procDir)
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range entries {
+			if pid, err := strconv.Atoi(e.Name()); err == nil && pid != pids[0] {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	for _, pid := range pids {
+		fdDir := filepath.Join(procDir, strconv.Itoa(pid), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // exited, or not permitted
+		}
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && link == target {
+				return pid, nil
+			}
+		}
+	}
+	return 0, nil
+}
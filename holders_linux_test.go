@@ -0,0 +1,52 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenFileCheck(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_OpenFileCheck")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Now()
+	var names []string
+	for i := 0; i < 2; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+		mtime := now.Add(time.Duration(i-2) * time.Hour)
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+	// a reader still holding the oldest file.
+	reader, err := os.Open(names[0])
+	require.NoError(t, err, "Open should succeed")
+	defer reader.Close()
+
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d.log"),
+		WithMaxBackups(1),
+		WithOpenFileCheck(OpenCheckSelf),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, OpenCheckSelf, l.Options().OpenFileCheck, "OpenFileCheck should match")
+	_, err = l.Write([]byte("current"))
+	require.NoError(t, err, "Write should succeed")
+
+	err = l.millRunOnce()
+	require.ErrorIs(t, err, ErrFileInUse, "deferred deletion should be reported")
+	require.FileExists(t, names[0], "file held open should be kept")
+	require.NoFileExists(t, names[1], "file not held open should be removed")
+
+	// removed on a later pass, once closed by the reader.
+	require.NoError(t, reader.Close(), "Close should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, names[0], "file should be removed once closed")
+}
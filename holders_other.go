@@ -0,0 +1,9 @@
+//go:build !linux
+
+package logrotate
+
+// openedBy always returns 0, as the file descriptors of processes are not
+// inspected on this platform.
+func openedBy(path string, all bool) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,132 @@
+package logrotate
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileIndex is the in-memory index of the log files, so that a retention
+// pass only lstats the files touched since the previous pass, instead of
+// globbing and lstating all of them. The index is fully rescanned at most
+// every rescan interval, to pick up external changes.
+type fileIndex struct {
+	rescan    time.Duration
+	seqSuffix string // format of the sequence suffix to sort files
+
+	mu        sync.Mutex // guards following
+	files     map[string]FileInfo
+	touched   map[string]struct{}
+	scannedAt time.Time // zero if a full scan is required
+}
+
+// newFileIndex returns a fileIndex fully rescanned every rescan interval,
+// which sorts files by the sequence suffix in seqSuffix format.
+func newFileIndex(rescan time.Duration, seqSuffix string) *fileIndex {
+	return &fileIndex{rescan: rescan, seqSuffix: seqSuffix, touched: make(map[string]struct{})}
+}
+
+// fileIndex returns the fileIndex of the options, or nil if disabled.
+func (opts *Options) fileIndex() *fileIndex {
+	if opts.indexRescan <= 0 {
+		return nil
+	}
+	return newFileIndex(opts.indexRescan, opts.seqSuffix)
+}
+
+// touch marks the paths which may have been created, modified, renamed or
+// removed, so they are lstated again on the next pass. It's a no-op on a
+// nil index.
+func (x *fileIndex) touch(paths ...string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, path := range paths {
+		if path != "" {
+			x.touched[path] = struct{}{}
+		}
+	}
+}
+
+// invalidate requires a full rescan on the next pass, e.g.: after a whole
+// directory is removed. It's a no-op on a nil index.
+func (x *fileIndex) invalidate() {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	x.scannedAt = time.Time{}
+	x.mu.Unlock()
+}
+
+// list returns the indexed log files sorted newest first. The files are
+// fully rescanned with scan if due at now, otherwise only the touched paths
+// are updated with stat, which reports false if the path is no log file.
+func (x *fileIndex) list(now time.Time, scan func() ([]FileInfo, error), stat func(path string) (FileInfo, bool)) ([]FileInfo, error) {
+	x.mu.Lock()
+	full := x.scannedAt.IsZero() || now.Before(x.scannedAt) || now.Sub(x.scannedAt) >= x.rescan
+	touched := x.touched
+	x.touched = make(map[string]struct{})
+	x.mu.Unlock()
+
+	if full {
+		files, err := scan()
+		if err != nil {
+			x.invalidate()
+			return nil, err
+		}
+		indexed := make(map[string]FileInfo, len(files))
+		for _, f := range files {
+			indexed[f.Path] = f
+		}
+		x.mu.Lock()
+		x.files, x.scannedAt = indexed, now
+		x.mu.Unlock()
+		return files, nil
+	}
+
+	updates := make(map[string]FileInfo, len(touched))
+	for path := range touched {
+		if f, ok := stat(path); ok {
+			updates[path] = f
+		} else {
+			updates[path] = FileInfo{}
+		}
+	}
+	x.mu.Lock()
+	for path, f := range updates {
+		if f.FileInfo == nil {
+			delete(x.files, path)
+		} else {
+			x.files[path] = f
+		}
+	}
+	files := make([]FileInfo, 0, len(x.files))
+	for _, f := range x.files {
+		files = append(files, f)
+	}
+	x.mu.Unlock()
+
+	sort.Sort(byModTime{files, x.seqSuffix})
+	return files, nil
+}
+
+// statLogFile returns the FileInfo of the log file at path for the index,
+// and false if it no longer exists, or is not matched by the globPattern
+// nor moved by the RotateHook.
+func (l *Logger) statLogFile(path string) (FileInfo, bool) {
+	matched, _ := filepath.Match(l.globPattern, path)
+	if !matched && !l.isMoved(path) {
+		return FileInfo{}, false
+	}
+	f, ok, err := lstatLogFile(path, l.opts.birthTime, l.names)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.untrackMoved(path)
+	}
+	return f, ok && err == nil
+}
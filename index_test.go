@@ -0,0 +1,58 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileIndex(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_FileIndex")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	external := func(name string, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644), "WriteFile should succeed")
+		mtime := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(path, mtime, mtime), "Chtimes should succeed")
+		return path
+	}
+	older := external("app.20231231.log", 24*time.Hour)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d.log"),
+		WithClock(clock),
+		WithMaxInterval(24*time.Hour),
+		WithMaxBackups(2),
+		WithFileIndex(48*time.Hour),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, 48*time.Hour, l.Options().FileIndex, "snapshot should show the rescan interval")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.FileExists(t, older, "file within MaxBackups should be kept")
+
+	// created by others after the full scan, so not indexed until rescan.
+	oldest := external("app.20231230.log", 48*time.Hour)
+	clock.Advance(24 * time.Hour)
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, older, "indexed file over MaxBackups should be removed")
+	require.FileExists(t, oldest, "file not indexed should be kept until rescan")
+	require.FileExists(t, filepath.Join(dir, "app.20240101.log"), "rotated file should be indexed")
+
+	clock.Advance(24 * time.Hour)
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, oldest, "file should be removed after rescan")
+	require.FileExists(t, filepath.Join(dir, "app.20240101.log"), "file within MaxBackups should be kept")
+}
@@ -0,0 +1,177 @@
+package logrotate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Journal states of a pending rotation.
+const (
+	// journalIntent is recorded when the new file is opened, and the
+	// rotated file is going to be closed.
+	journalIntent = "intent"
+	// journalClosed is recorded when the rotated file is closed and moved
+	// by the RotateHook, and is going to be post-processed.
+	journalClosed = "closed"
+)
+
+// journalEntry is a pending rotation recorded in the journal.
+type journalEntry struct {
+	state  string
+	path   string       // path of the rotated file in the state
+	next   string       // path of the file rotated to
+	reason RotateReason // reason of the rotation, 0 if journaled by an older version
+}
+
+// journal records pending rotations as a small state machine, from intent
+// to closed and then removed after post-processed, to a sidecar file, so
+// that a Logger can complete or roll back the rotations pending at a crash
+// on restart. The file is replaced atomically on each transition, and is
+// removed when no rotation is pending.
+type journal struct {
+	path string
+
+	mu      sync.Mutex // guards following
+	entries []journalEntry
+}
+
+// openJournal opens the journal at path, and returns the rotations pending
+// at a crash of a predecessor.
+func openJournal(path string) (*journal, []journalEntry, error) {
+	j := &journal{path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var pending []journalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		var reason string
+		if n, err := fmt.Sscanf(scanner.Text(), "%s %q %q %s", &e.state, &e.path, &e.next, &reason); err != nil && n < 3 {
+			// a torn line can't be written, as the file is replaced
+			// atomically, so it's corrupted by others.
+			return nil, nil, fmt.Errorf("invalid journal line %q: %v", scanner.Text(), err)
+		}
+		e.reason = parseRotateReason(reason)
+		pending = append(pending, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	j.entries = append(j.entries, pending...)
+	return j, pending, nil
+}
+
+// begin records the intent to rotate the file at path to next for reason.
+// It's a no-op if j is nil, as are the other methods.
+func (j *journal) begin(path, next string, reason RotateReason) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, journalEntry{state: journalIntent, path: path, next: next, reason: reason})
+	return j.persist()
+}
+
+// close records the rotated file at path is closed and moved to newPath.
+func (j *journal) close(path, newPath string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.entries {
+		if j.entries[i].path == path {
+			j.entries[i].state = journalClosed
+			j.entries[i].path = newPath
+			return j.persist()
+		}
+	}
+	return nil
+}
+
+// done removes the rotation of the file at path, which is done.
+func (j *journal) done(path string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.entries {
+		if j.entries[i].path == path {
+			j.entries = append(j.entries[:i], j.entries[i+1:]...)
+			return j.persist()
+		}
+	}
+	return nil
+}
+
+// persist replaces the journal file with the entries atomically. j.mu must
+// be held by the caller.
+func (j *journal) persist() error {
+	if len(j.entries) == 0 {
+		if err := os.Remove(j.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, e := range j.entries {
+		fmt.Fprintf(&buf, "%s %q %q", e.state, e.path, e.next)
+		if e.reason != 0 {
+			fmt.Fprintf(&buf, " %s", e.reason)
+		}
+		buf.WriteByte('\n')
+	}
+	return replaceFile(j.path, buf.Bytes())
+}
+
+// recoverJournal opens the journal, and completes or rolls back the
+// rotations pending at a crash of a predecessor: a rotated file which still
+// exists is completed, by calling the RotateHook if not yet, and queueing it
+// for post-rotation processing, and the rotation of a file which no longer
+// exists is rolled back, i.e.: discarded.
+func (l *Logger) recoverJournal() error {
+	j, pending, err := openJournal(l.opts.journal)
+	if err != nil {
+		return &RotationError{Op: "journal", Path: l.opts.journal, Err: err}
+	}
+	l.journal = j
+	var errs []error
+	for _, e := range pending {
+		if _, err := os.Stat(e.path); err != nil {
+			errs = append(errs, j.done(e.path))
+			continue
+		}
+		path := e.path
+		if e.state == journalIntent {
+			path = l.finishRotated(e.path)
+			if path == "" {
+				errs = append(errs, j.done(e.path))
+				continue
+			}
+			errs = append(errs, j.close(e.path, path))
+		}
+		if len(l.processors) == 0 {
+			errs = append(errs, j.done(path))
+			continue
+		}
+		l.queueRotated(path)
+		l.mill()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return &RotationError{Op: "journal", Path: l.opts.journal, Err: err}
+	}
+	return nil
+}
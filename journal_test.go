@@ -0,0 +1,78 @@
+package logrotate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Journal(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Journal")
+	defer os.RemoveAll(dir)
+
+	journal := filepath.Join(dir, "rotation.journal")
+	readJournal := func() string {
+		data, err := os.ReadFile(journal)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+	var mu sync.Mutex
+	var processed, states []string
+	record := ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, path)
+		states = append(states, readJournal())
+		return path, nil
+	})
+	waitProcessed := func() {
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(journal)
+			return os.IsNotExist(err)
+		}, time.Second, time.Millisecond, "journal should be removed after processed")
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	newLogger := func() *Logger {
+		l, err := New(
+			filepath.Join(dir, "app.log"),
+			WithMaxSize(4),
+			WithJournal(journal),
+			WithPostRotateProcessors(record),
+		)
+		require.NoError(t, err, "New should succeed")
+		require.Equal(t, journal, l.Options().Journal, "Journal should match")
+		return l
+	}
+
+	l := newLogger()
+	for _, line := range []string{"aaa", "bbb"} {
+		_, err := l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	waitProcessed()
+	rotated := filepath.Join(dir, "app.log")
+	require.Equal(t, []string{rotated}, processed, "rotated file should be processed")
+	require.Equal(t, []string{fmt.Sprintf("closed %q %q size\n", rotated, rotated+".1")}, states, "rotation should be closed while processed")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	// simulate a crash mid-rotation of the predecessor.
+	processed, states = nil, nil
+	gone := filepath.Join(dir, "app.log.0")
+	require.NoError(t, os.WriteFile(journal, []byte(fmt.Sprintf("intent %q %q\nclosed %q %q\n", rotated+".1", rotated+".2", gone, rotated)), 0644), "WriteFile should succeed")
+	l = newLogger()
+	defer l.Close()
+	waitProcessed()
+	require.Equal(t, []string{rotated + ".1"}, processed, "pending rotated file should be processed")
+	require.Equal(t, []string{fmt.Sprintf("closed %q %q\n", rotated+".1", rotated+".2")}, states, "intent should be completed, and rotation of a missing file should be rolled back")
+
+	require.NoError(t, os.WriteFile(journal, []byte("intent\n"), 0644), "WriteFile should succeed")
+	_, err := New(filepath.Join(dir, "other.log"), WithJournal(journal))
+	require.Error(t, err, "New should fail with a corrupted journal")
+}
@@ -0,0 +1,51 @@
+package logrotate
+
+import "sync"
+
+// lifecycle manages the background goroutines of a Logger, e.g.: writeLoop
+// and millLoop, so that Close blocks until all of them have fully stopped.
+type lifecycle struct {
+	quit    chan struct{} // closed when the goroutines should quit
+	stopped chan struct{} // closed when all the goroutines have returned
+
+	wg       sync.WaitGroup // counts running goroutines
+	quitOnce sync.Once
+	stopOnce sync.Once
+}
+
+// newLifecycle returns a lifecycle with no goroutines.
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// start runs fn in a goroutine, which should return once quit is closed.
+// It returns after the goroutine has started, and it's counted before, so
+// wait never misses it.
+func (lc *lifecycle) start(fn func()) {
+	started := make(chan struct{})
+	lc.wg.Add(1)
+	go func() {
+		defer lc.wg.Done()
+		close(started)
+		fn()
+	}()
+	<-started
+}
+
+// signal tells the goroutines to quit. It's safe to call multiple times.
+func (lc *lifecycle) signal() {
+	lc.quitOnce.Do(func() { close(lc.quit) })
+}
+
+// wait blocks until all the goroutines have returned, after signal is
+// called. It's safe to call multiple times and concurrently.
+func (lc *lifecycle) wait() {
+	lc.stopOnce.Do(func() {
+		lc.wg.Wait()
+		close(lc.stopped)
+	})
+	<-lc.stopped
+}
@@ -0,0 +1,84 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loggerGoroutines returns the stacks of goroutines created by the
+// package, e.g.: the background loops of a Logger, timed out writes and
+// processors, excluding the ones created by tests.
+func loggerGoroutines() []string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	var leaked []string
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		i := strings.Index(g, "\ncreated by github.com/gounknown/logrotate.")
+		if i < 0 {
+			continue
+		}
+		creator, _, _ := strings.Cut(g[i+len("\ncreated by "):], "\n")
+		if !strings.Contains(creator, ".Test") {
+			leaked = append(leaked, g)
+		}
+	}
+	return leaked
+}
+
+func Test_lifecycle(t *testing.T) {
+	lc := newLifecycle()
+	var running atomic.Int32
+	for i := 0; i < 3; i++ {
+		lc.start(func() {
+			running.Add(1)
+			defer running.Add(-1)
+			<-lc.quit
+			time.Sleep(10 * time.Millisecond)
+		})
+	}
+	require.Equal(t, int32(3), running.Load(), "goroutines should have started")
+
+	lc.signal()
+	lc.signal()
+	done := make(chan struct{})
+	go func() {
+		lc.wait()
+		close(done)
+	}()
+	lc.wait()
+	<-done
+	require.Equal(t, int32(0), running.Load(), "wait should block until goroutines return")
+}
+
+func Test_Close_NoGoroutineLeak(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Close_NoGoroutineLeak")
+	defer os.RemoveAll(dir)
+
+	// loggers left open by other tests may still be running
+	running := len(loggerGoroutines())
+	filename := filepath.Join(dir, "app.log")
+	for i := 0; i < 10; i++ {
+		l, err := New(
+			filename,
+			WithWriteChan(100),
+			WithMaxInterval(time.Hour),
+			WithScheduledRotation(true),
+		)
+		require.NoError(t, err, "New should succeed")
+		_, err = l.Write([]byte("1"))
+		require.NoError(t, err, "Write should succeed")
+		require.NoError(t, l.Close(), "Close should succeed")
+		require.LessOrEqual(t, len(loggerGoroutines()), running, "Close should stop all goroutines")
+	}
+
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "1111111111", string(b), "Close should flush writes")
+}
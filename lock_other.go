@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package logrotate
+
+import (
+	"os"
+)
+
+// lockFile is a no-op, as advisory locks are not supported on this
+// platform.
+func lockFile(f *os.File) error {
+	return nil
+}
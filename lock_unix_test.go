@@ -0,0 +1,40 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Exclusive(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Exclusive")
+	defer os.RemoveAll(dir)
+
+	pattern := filepath.Join(dir, "app.log")
+	l1, err := New(pattern, WithExclusive(true))
+	require.NoError(t, err, "New should succeed")
+	_, err = l1.Write([]byte("Hello, World"))
+	require.NoError(t, err, "Write should succeed")
+
+	_, err = New(pattern, WithExclusive(true))
+	require.ErrorIs(t, err, ErrPatternInUse, "New should fail in the same process")
+
+	// simulate another process, which doesn't share the registry.
+	require.True(t, unregister(l1), "l1 should be unregistered")
+	_, err = New(pattern, WithExclusive(true))
+	require.True(t, errors.Is(err, ErrFileLocked), "New should fail with ErrFileLocked, got: %v", err)
+
+	content, err := os.ReadFile(pattern)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "Hello, World", string(content), "locked file should not be clobbered")
+
+	require.NoError(t, l1.Close(), "Close should succeed")
+	l2, err := New(pattern, WithExclusive(true))
+	require.NoError(t, err, "New should succeed after the holder closed")
+	require.NoError(t, l2.Close(), "Close should succeed")
+}
@@ -3,14 +3,18 @@
 package logrotate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lestrrat-go/strftime"
@@ -19,30 +23,120 @@ import (
 // ensure we always implement io.WriteCloser
 var _ io.WriteCloser = (*Logger)(nil)
 
+const (
+	minProbeBackoff = time.Second // initial backoff to probe for recovery
+	maxProbeBackoff = time.Minute // max backoff to probe for recovery
+)
+
 // Logger is an io.WriteCloser that writes to the appropriate filename. It
 // can get automatically rotated as you write to it.
 type Logger struct {
 	// Read-only fields after *New* method inited.
-	opts               *Options
-	pattern            *strftime.Strftime
-	globPattern        string
-	maxIntervalSeconds int64 // max interval in seconds
-	tzOffsetSeconds    int64 // time zone offset in seconds
-
-	mu               sync.RWMutex   // guards following
-	file             io.WriteCloser // current file handle being written to
-	size             int64          // write size of current file
-	currRotationTime int64          // Unix timestamp with location
-	currFilename     string         // current filename being written to
-	currBaseFilename string         // base filename without suffix sequence
-	currSequence     uint           // filename suffix sequence
-
-	wg      sync.WaitGroup // counts active background goroutines
-	writeCh chan []byte    // buffered chan for write goroutine
-	millCh  chan struct{}  // 1-size notification chan for mill goroutine
-	quit    chan struct{}  // closed when writeLoop and millLoop should quit
+	opts        *Options
+	pattern     *strftime.Strftime
+	funcPattern *funcPattern // pattern with template functions if any
+	names       *timeParser  // parses the times of log files from filenames
+	dirNames    *timeParser  // parses the times of per-interval directories
+	globPattern string
+	dirGlob     string // glob of per-interval directories with RetentionDirectory
+	maxInterval int64  // max interval in nanoseconds
+	tzOffset    int64  // time zone offset in nanoseconds
+	policies    []RetentionPolicy
+	processors  []Processor
+	index       *fileIndex // nil if FileIndex disabled
+	key         string     // key in the process-wide registry
+
+	refs int // reference count of shared Logger, guarded by registry.mu
+
+	// current file handle being written to, which is loaded without l.mu
+	// by the write fast path, but only stored with l.mu held.
+	file atomic.Pointer[fileHandle]
+
+	mu               sync.RWMutex  // guards following
+	currRotationTime int64         // Unix nanoseconds with location
+	currFilename     string        // current filename being written to
+	currBaseFilename string        // base filename without suffix sequence
+	currSequence     uint          // filename suffix sequence
+	recovered        bool          // whether orphaned files were adopted
+	detached         []*fileHandle // rotated files waiting to be closed
+	reports          []error       // errors waiting to be reported
+
+	warnMu   sync.Mutex // guards warnings, which may be added without l.mu
+	warnings []error    // non-fatal errors waiting to be reported
+
+	readOnly     bool          // whether the filesystem is read-only
+	readOnlyErr  error         // the last error caused by read-only filesystem
+	probeBackoff time.Duration // backoff between probes for recovery
+	nextProbe    time.Time     // when to probe for recovery
+
+	openFailing bool          // whether opening the log file keeps failing
+	openErr     error         // the last error opening the log file
+	openBackoff time.Duration // backoff between attempts to reopen
+	nextOpen    time.Time     // when to attempt to reopen
+
+	pins          int          // count of open Writers deferring rotation
+	pendingReason RotateReason // reason of the rotation deferred by Writers, if any
+	vetoedSince   time.Time    // when rotations began to be vetoed by the PreRotateHook
+
+	standby *standbyFile // pre-opened file of the next interval if WithWarmStandby
+
+	life   *lifecycle    // manages writeLoop, millLoop and scheduleLoop
+	queue  Queue         // queue of buffered writes for write goroutine
+	highCh chan writeOp  // buffered chan for PriorityHigh writes
+	notify chan struct{} // 1-size notification chan for write goroutine
+	spill  *spill        // spills queue overflow if WithSpillDir
+	opMu   sync.RWMutex  // held by submit, so Close can wait for submitted ops
+	millCh chan struct{} // 1-size notification chan for mill goroutine
+	millMu sync.Mutex    // serializes mill passes, e.g.: run in place by tests
+	closed atomic.Bool   // set when Close is called
+
+	closeOnce sync.Once // runs shutdown once
+	closeErr  error     // returned by the first Close
+
+	ctx    context.Context    // passed to post-rotation processors
+	cancel context.CancelFunc // cancels ctx on Close
+
+	// count of entries enqueued to and dequeued from queue, so that fn ops
+	// are run in order with them.
+	enqueued atomic.Uint64
+	dequeued uint64 // only accessed by writeLoop
+
+	opsMu sync.Mutex // guards following
+	ops   []writeOp  // fn ops waiting to be run by writeLoop
+
+	rotatedMu sync.Mutex // guards following
+	rotated   []string   // rotated files waiting for post-rotation processing
+
+	movedMu sync.Mutex          // guards following
+	moved   map[string]struct{} // rotated files moved by the RotateHook
+
+	eventsMu     sync.Mutex         // guards following
+	events       chan RotationEvent // created on the first RotationEvents call
+	eventsClosed bool               // set when events is closed on Close
+
+	savelogMu    sync.Mutex // guards following, and renaming savelog-style files
+	savelogQueue []string   // savelog-style files waiting for compression
+
+	bundleMu   sync.Mutex // guards following, and bundling files
+	bundledDay string     // the day when files were last bundled, if WithDayBundle
+
+	sweepMu  sync.Mutex  // serializes sweeping partial artifacts
+	sweepDue atomic.Bool // set when a sweep is requested by sweepLoop
 
 	metrics atomicMetrics
+	lastErr atomic.Pointer[errorRecord] // the last error, for Healthy
+	dropped droppedSamples              // samples of discarded writes if WithDroppedSamples
+	hung    atomic.Int64                // count of timed out writes still pending
+	commit  *groupCommit                // group commit of writes if WithGroupCommit
+	tuner   *queueTuner                 // tuner of the queue bound if WithAutoTune
+	forward *forwarder                  // forwarder of written lines if WithSyslog
+
+	clockRegressed atomic.Bool // set while the clock is behind the current rotation time
+
+	journal *journal   // journal of pending rotations if WithJournal
+	state   *stateFile // state of the rotation schedule if WithStateFile
+
+	segments *segmentIndex // index of chunks if WithSegments
 
 	// mocked out for testing.
 	osStat func(name string) (fs.FileInfo, error) // os.Stat
@@ -51,81 +145,380 @@ type Logger struct {
 // New creates a new concurrent safe Logger object with the provided
 // filename pattern and options.
 func New(pattern string, options ...Option) (*Logger, error) {
+	opts, err := parseOptions(options...)
+	if err != nil {
+		return nil, err
+	}
 	globPattern := parseGlobPattern(pattern)
-	filenamePattern, err := strftime.New(pattern)
+	funcPattern, err := newFuncPattern(pattern, opts.patternFuncs)
 	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+	var filenamePattern *strftime.Strftime
+	if funcPattern != nil {
+		filenamePattern, _ = funcPattern.compile()
+	} else if filenamePattern, err = newStrftime(pattern); err != nil {
 		return nil, fmt.Errorf("invalid strftime pattern: %v", err)
 	}
-	opts := parseOptions(options...)
+	var dirGlobPattern string
+	var dirNames *timeParser
+	if opts.retentionGranularity == RetentionDirectory {
+		if dirGlobPattern, err = parseDirGlobPattern(pattern); err != nil {
+			return nil, err
+		}
+		dirNames = newTimeParser(filepath.Dir(pattern), opts.seqSuffix)
+	}
 	_, offset := opts.clock.Now().Zone()
+	ctx := context.Background()
+	if opts.millRateLimit > 0 {
+		ctx = context.WithValue(ctx, rateLimiterKey{}, newRateLimiter(opts.millRateLimit))
+	}
+	ctx, cancel := context.WithCancel(ctx)
 	l := &Logger{
-		opts:               opts,
-		pattern:            filenamePattern,
-		globPattern:        globPattern,
-		maxIntervalSeconds: int64(opts.maxInterval.Seconds()),
-		tzOffsetSeconds:    int64(offset),
-		millCh:             make(chan struct{}, 1),
-		quit:               make(chan struct{}),
+		opts:        opts,
+		pattern:     filenamePattern,
+		funcPattern: funcPattern,
+		globPattern: globPattern,
+		dirGlob:     dirGlobPattern,
+		names:       newTimeParser(pattern, opts.seqSuffix),
+		dirNames:    dirNames,
+		maxInterval: int64(opts.maxInterval),
+		tzOffset:    int64(offset) * int64(time.Second),
+		policies:    opts.retentionPolicies(),
+		processors:  opts.postRotateProcessors(),
+		index:       opts.fileIndex(),
+		commit:      opts.groupCommit(),
+		millCh:      make(chan struct{}, 1),
+		life:        newLifecycle(),
+		ctx:         ctx,
+		cancel:      cancel,
 
 		osStat: os.Stat,
 	}
 
+	registered, err := register(registryKey(pattern), l)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if registered != l {
+		cancel()
+		return registered, nil
+	}
+
+	if opts.journal != "" {
+		if err := l.recoverJournal(); err != nil {
+			unregister(l)
+			cancel()
+			return nil, err
+		}
+	}
+
+	if opts.stateFile != "" {
+		if l.state, err = loadStateFile(opts.stateFile); err != nil {
+			unregister(l)
+			cancel()
+			return nil, &RotationError{Op: "state", Path: opts.stateFile, Err: err}
+		}
+	}
+
+	if opts.segmentIndex != "" {
+		if l.segments, err = loadSegmentIndex(opts.segmentIndex); err != nil {
+			unregister(l)
+			cancel()
+			return nil, &RotationError{Op: "segment", Path: opts.segmentIndex, Err: err}
+		}
+	}
+
+	if opts.exclusive || opts.createOnNew {
+		// open the current file eagerly, so that the lock is taken and
+		// another holder is detected on New, and the file exists before
+		// the first Write.
+		l.mu.Lock()
+		err := l.openExistingOrNew(0)
+		if h := l.file.Load(); err == nil && h != nil && opts.symlink != "" && opts.symlinkTarget == SymlinkActiveFile {
+			if lerr := link(h.name, l.evalSymlink(opts.symlink, h.rotationTime)); lerr != nil {
+				err = &RotationError{Op: "symlink", Path: opts.symlink, Err: lerr}
+			}
+		}
+		l.mu.Unlock()
+		if err != nil {
+			unregister(l)
+			cancel()
+			return nil, err
+		}
+	}
+
+	if opts.quotaGroup != nil {
+		opts.quotaGroup.join(l, opts.quotaWeight, opts.quotaPriority)
+	}
+
+	// starting the forward goroutine, if any
+	l.forward = newForwarder(l)
+
 	if opts.writeChSize > 0 {
-		l.writeCh = make(chan []byte, opts.writeChSize)
+		l.queue = opts.queue
+		if l.queue == nil {
+			l.queue = newChanQueue(opts.writeChSize)
+		}
+		l.highCh = make(chan writeOp, opts.writeChSize)
+		l.notify = make(chan struct{}, 1)
+		if opts.spillDir != "" {
+			l.spill = &spill{dir: opts.spillDir}
+		}
+		if opts.autoTune && opts.queue == nil {
+			l.tuner = newQueueTuner(opts.writeChSize)
+		}
 		// starting the write goroutine
-		l.wg.Add(1)
-		go func() {
-			l.wg.Done()
-			l.writeLoop()
-		}()
+		l.life.start(l.writeLoop)
 	}
 
 	// starting the mill goroutine
-	l.wg.Add(1)
-	go func() {
-		l.wg.Done()
-		l.millLoop()
-	}()
+	l.life.start(l.millLoop)
+
+	if l.commit != nil {
+		// starting the group commit goroutine
+		l.life.start(l.commitLoop)
+	}
+
+	if l.tuner != nil {
+		// starting the queue tuning goroutine
+		l.life.start(l.tuneLoop)
+	}
+
+	if opts.partialSweep > 0 {
+		// starting the sweep goroutine
+		l.life.start(l.sweepLoop)
+	}
+
+	if opts.warmStandby > 0 && l.maxInterval > 0 {
+		// starting the standby goroutine
+		l.life.start(l.standbyLoop)
+	}
+
+	if opts.scheduledRotation && l.maxInterval > 0 {
+		// starting the schedule goroutine
+		l.life.start(l.scheduleLoop)
+	}
 
 	return l, nil
 }
 
 // Write implements io.Writer. If writeChSize <= 0, then it writes to the
-// current file directly. Otherwise, it just writes to writeCh, so there is no
-// blocking disk I/O operations and would not block unless writeCh is full.
-// In the meantime, the writeLoop goroutine will sink the writeCh to files
+// current file directly. Otherwise, it just writes to queue, so there is no
+// blocking disk I/O operations and would not block even if queue is full.
+// In the meantime, the writeLoop goroutine will sink the queue to files
 // asynchronously in background.
 //
 // Write writes len(b) bytes from b to the File. It returns the number of bytes
 // written and an error, if any. Write returns a non-nil error when n != len(b).
 //
-// NOTE: It's an undefined behavior if you still call Write after Close called.
-// Maybe it would sink to files, maybe not, but it won't panic.
+// In buffered mode, Write returns ErrDiscarded if queue is full. It returns
+// ErrClosed after Close called.
 func (l *Logger) Write(b []byte) (n int, err error) {
+	if len(l.opts.redactors) > 0 {
+		size := len(b)
+		b = l.redact(b)
+		defer func() { n = redactedLen(n, err, size) }()
+	}
+	if l.closed.Load() {
+		l.discard(&l.metrics.DiscardsClosed, len(b), b)
+		return 0, ErrClosed
+	}
+	if l.opts.mirror != nil {
+		defer l.mirror(&err, b)
+	}
 	if l.opts.writeChSize <= 0 {
 		return l.write(b)
 	}
+	if !l.enqueue(len(b), b) {
+		return 0, ErrDiscarded
+	}
+	return len(b), nil
+}
+
+// WriteV writes all segments as a single log entry, so that structured
+// loggers which build entries from multiple buffers can avoid concatenating
+// them before writing. Rotation is evaluated based on the total length of
+// segments, so an entry never spans two files.
+//
+// It returns the total number of bytes written and an error, if any.
+// Otherwise, it behaves the same as Write.
+func (l *Logger) WriteV(segments ...[]byte) (n int, err error) {
+	if len(segments) == 1 {
+		return l.Write(segments[0])
+	}
+	size := 0
+	for _, seg := range segments {
+		size += len(seg)
+	}
+	if len(l.opts.redactors) > 0 {
+		// redactors need the whole log entry to match across segments.
+		buf := getBuffer(size)
+		defer putBuffer(buf)
+		for _, seg := range segments {
+			*buf = append(*buf, seg...)
+		}
+		return l.Write(*buf)
+	}
+	return l.writeV(size, segments...)
+}
 
+// WriteBatch writes multiple log entries at once, e.g.: from loggers which
+// already batch entries, like zap's buffered syncer. The size and interval
+// checks are performed once for the whole batch, which is written with a
+// single lock acquisition and write syscall, so a rotation never splits a
+// batch. Redactors are applied to each entry.
+//
+// It returns the total number of bytes written and an error, if any.
+// Otherwise, it behaves the same as Write.
+func (l *Logger) WriteBatch(entries [][]byte) (n int, err error) {
+	size := 0
+	for _, e := range entries {
+		size += len(e)
+	}
+	if len(l.opts.redactors) == 0 {
+		return l.writeV(size, entries...)
+	}
+	redacted := make([][]byte, len(entries))
+	redactedSize := 0
+	for i, e := range entries {
+		redacted[i] = l.redact(e)
+		redactedSize += len(redacted[i])
+	}
+	n, err = l.writeV(redactedSize, redacted...)
+	return redactedLen(n, err, size), err
+}
+
+// writeV writes the segments with total length size as a single log entry.
+func (l *Logger) writeV(size int, segments ...[]byte) (n int, err error) {
+	if l.closed.Load() {
+		l.discard(&l.metrics.DiscardsClosed, size, segments...)
+		return 0, ErrClosed
+	}
+	if l.opts.mirror != nil {
+		defer l.mirror(&err, segments...)
+	}
+	if l.opts.writeChSize > 0 {
+		if !l.enqueue(size, segments...) {
+			return 0, ErrDiscarded
+		}
+		return size, nil
+	}
+
+	buf := getBuffer(size)
+	defer putBuffer(buf)
+	for _, seg := range segments {
+		*buf = append(*buf, seg...)
+	}
+	return l.write(*buf)
+}
+
+// tryWriteSpins is the number of attempts TryWrite makes to lock l.mu
+// before giving up.
+const tryWriteSpins = 4
+
+// TryWrite is like Write, but it never blocks on the Logger: in buffered
+// mode, it returns ErrWouldBlock if queue is full, and in unbuffered mode,
+// it returns ErrWouldBlock if the internal lock is contended beyond a small
+// spin, e.g.: by a rotation in progress. Nothing is written on
+// ErrWouldBlock, so the caller may retry or drop the log line. Latency
+// critical paths can use it to log opportunistically without risking stalls.
+//
+// NOTE: it may still block on the file write itself, see WithWriteTimeout.
+func (l *Logger) TryWrite(b []byte) (n int, err error) {
+	if len(l.opts.redactors) > 0 {
+		size := len(b)
+		b = l.redact(b)
+		defer func() { n = redactedLen(n, err, size) }()
+	}
+	if l.closed.Load() {
+		l.discard(&l.metrics.DiscardsClosed, len(b), b)
+		return 0, ErrClosed
+	}
+	if l.opts.mirror != nil {
+		defer l.mirrorTry(&err, b)
+	}
+	if l.opts.writeChSize > 0 {
+		if !l.tryEnqueue(len(b), b) {
+			return 0, ErrWouldBlock
+		}
+		return len(b), nil
+	}
+	if l.Degraded() {
+		return 0, ErrWriteTimeout
+	}
+	if n, ok, err := l.writeFast(b); ok {
+		return n, err
+	}
+	for i := 0; ; i++ {
+		if l.mu.TryLock() {
+			break
+		}
+		if i >= tryWriteSpins {
+			return 0, ErrWouldBlock
+		}
+		runtime.Gosched()
+	}
+	n, err = l.writeLocked(b)
+	if cerr := l.unlock(); cerr != nil {
+		tracef(os.Stderr, "failed to close rotated file: %v", cerr)
+	}
+	return n, err
+}
+
+// enqueue is like tryEnqueue, but spills the segments if WithSpillDir, and
+// counts the discarded segments in metrics.
+func (l *Logger) enqueue(size int, segments ...[]byte) bool {
+	// keep the order with the spilled writes not yet replayed.
+	if l.spillWrite(size, false, segments...) {
+		return true
+	}
+	if l.tryEnqueue(size, segments...) {
+		return true
+	}
+	if l.spillWrite(size, true, segments...) {
+		return true
+	}
+	l.discard(&l.metrics.DiscardsQueueFull, size, segments...)
+	return false
+}
+
+// tryEnqueue copies the segments with total length size into a single
+// slice, and writes it to queue. It returns false if queue is full.
+func (l *Logger) tryEnqueue(size int, segments ...[]byte) bool {
 	// Should check whether the Logger was closed?
 	//
-	// NOTE: we must do value-copy and then write it to writeCh to avoid the
-	// data race problem, as the inputed byte slice "b" is usually reused by
+	// NOTE: we must do value-copy and then write it to queue to avoid the
+	// data race problem, as the inputed byte slices are usually reused by
 	// the caller.
 	//
 	// TODO: slice value-copy and GC cost is high, how to optimize? bufio?
-	if len(l.writeCh) < l.opts.writeChSize {
-		copied := make([]byte, len(b))
-		copy(copied, b)
-		select {
-		case l.writeCh <- copied:
-		default:
-			l.metrics.Discards.Add(1)
+	if l.opts.queue == nil && l.queue.Len() >= l.queueBound() {
+		if l.tuner != nil {
+			l.tuner.full.Add(1)
 		}
-	} else {
-		l.metrics.Discards.Add(1)
+		return false // avoid copying if the default queue is full
+	}
+	copied := make([]byte, 0, size)
+	for _, seg := range segments {
+		copied = append(copied, seg...)
 	}
+	if !l.queue.Enqueue(QueueEntry{Data: copied, Time: l.opts.clock.Now()}) {
+		return false
+	}
+	l.enqueued.Add(1)
+	l.notifyWrite()
+	return true
+}
 
-	return len(b), nil
+// notifyWrite notifies writeLoop of the queued ops. It's ok to skip if
+// notify is full.
+func (l *Logger) notifyWrite() {
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
 }
 
 // write writes len(b) bytes to the target file handle that is currently being
@@ -136,14 +529,240 @@ func (l *Logger) Write(b []byte) (n int, err error) {
 // reached a new rotation time (evaluated based on MaxInterval), the target
 // file would get automatically rotated, and old log files would also be purged
 // if necessary.
+//
+// With WithGroupCommit, it returns after b is committed to stable storage.
 func (l *Logger) write(b []byte) (n int, err error) {
+	n, err = l.writeUncommitted(b)
+	if err == nil {
+		err = l.commit.wait(l.commit.position())
+	}
+	return n, err
+}
+
+// writeUncommitted is like write, but it returns without waiting for the
+// group commit of b.
+func (l *Logger) writeUncommitted(b []byte) (n int, err error) {
+	if l.Degraded() {
+		return 0, ErrWriteTimeout
+	}
+	if n, ok, err := l.writeFast(b); ok {
+		return n, err
+	}
+
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	n, err = l.writeLocked(b)
+	if cerr := l.unlock(); cerr != nil {
+		tracef(os.Stderr, "failed to close rotated file: %v", cerr)
+	}
+	if err != nil {
+		l.recordError(err)
+	}
+	return n, err
+}
+
+// writeFast writes b to the current file without holding l.mu, so that
+// concurrent writes don't serialize on it. ok is false if there is no
+// current file, or a rotation is needed, then the caller must fall back
+// to the slow path under l.mu.
+func (l *Logger) writeFast(b []byte) (n int, ok bool, err error) {
+	h := l.file.Load()
+	if h == nil || !h.acquire() {
+		return 0, false, nil
+	}
 
+	// Factor 2: MaxInterval
+	if l.maxInterval > 0 {
+		due, err := l.rotationDue(h.rotationTime)
+		if err != nil {
+			l.handleError(err)
+		}
+		if due {
+			h.release()
+			return 0, false, nil
+		}
+	}
+	// Try to resume current log file even if removed by other processes,
+	// which is handled by the slow path.
+	fi, err := l.osStat(h.name)
+	if err != nil {
+		h.release()
+		return 0, false, nil
+	}
+	l.reconcileSize(h, fi)
+	// Factor 1: MaxSize. Reserve the write size ahead, so concurrent
+	// writers won't put the file over MaxSize together.
+	writeLen := int64(len(b))
+	if size := h.size.Add(writeLen); l.opts.maxSize > 0 && size > l.opts.sizeLimit() {
+		h.size.Add(-writeLen)
+		h.release()
+		return 0, false, nil
+	}
+	// Factor 3: MaxLines, reserved ahead as MaxSize.
+	var lines int64
+	if l.opts.maxLines > 0 {
+		lines = countLines(b)
+		if n := h.lines.Add(lines); n > int64(l.opts.maxLines) {
+			h.lines.Add(-lines)
+			h.size.Add(-writeLen)
+			h.release()
+			return 0, false, nil
+		}
+	}
+	// Factor 4: RotatePredicate
+	if l.opts.rotatePredicate != nil && l.opts.rotatePredicate(h.stats(), b) {
+		h.lines.Add(-lines)
+		h.size.Add(-writeLen)
+		h.release()
+		return 0, false, nil
+	}
+
+	n, err = l.writeHandle(h, b, false)
+	if n < len(b) {
+		h.size.Add(int64(n) - writeLen)
+	}
+	if n > 0 {
+		h.recordWrite(l.opts.clock.Now().UnixNano())
+	}
+	// must release before recovering, as closing h waits for all in-flight
+	// writes.
+	h.release()
+	if err != nil {
+		n, err = l.recoverWrite(h, b, n, err)
+		return n, true, err
+	}
+	return n, true, nil
+}
+
+// reconcileSize reconciles the write size of file handle h with the real
+// size fi.Size() at most once per SizeReconcileInterval, so bytes appended by
+// other processes are taken into account for MaxSize.
+func (l *Logger) reconcileSize(h *fileHandle, fi fs.FileInfo) {
+	interval := int64(l.opts.sizeReconcileInterval)
+	if interval <= 0 {
+		return
+	}
+	now := l.opts.clock.Now().UnixNano()
+	last := h.reconciledAt.Load()
+	if now-last < interval || !h.reconciledAt.CompareAndSwap(last, now) {
+		return
+	}
+	// The write size includes sizes reserved by in-flight writes, so it's
+	// only grown to the real size, never shrunk.
+	actual := fi.Size()
+	for {
+		size := h.size.Load()
+		if actual <= size || h.size.CompareAndSwap(size, actual) {
+			return
+		}
+	}
+}
+
+// recoverWrite tries to open existing or new file after a write error on
+// file handle h, and returns err joined with the open error, if any. If the
+// filesystem is read-only, it switches to the read-only mode and writes b to
+// the fallback writer instead.
+func (l *Logger) recoverWrite(h *fileHandle, b []byte, n int, err error) (int, error) {
+	if errors.Is(err, ErrWriteTimeout) {
+		// reopening would hang on the same filesystem.
+		return n, err
+	}
+	l.mu.Lock()
+	defer func() {
+		if cerr := l.unlock(); cerr != nil {
+			tracef(os.Stderr, "failed to close file: %v", cerr)
+		}
+	}()
+	if l.file.Load() != h {
+		// already rotated by another writer
+		return n, err
+	}
+	if isReadOnly(err) {
+		l.enterReadOnly(err)
+		if n == 0 {
+			return l.writeFallback(b, fmt.Errorf("%w: %w", ErrReadOnly, l.readOnlyErr))
+		}
+		return n, err
+	}
+	tracef(os.Stderr, "failed to write: %v, try to open existing or new file", err)
+	if err1 := l.openExistingOrNew(int64(len(b))); err1 != nil {
+		return n, errors.Join(err, err1)
+	}
+	return l.resumeWrite(b, n, err)
+}
+
+// resumeWrite writes the remainder of b, which failed with err after n
+// bytes written, to the file just reopened, if PartialWriteRecovery is
+// enabled. It returns the total bytes written, so callers like bufio.Writer
+// neither write the head again nor lose the tail, and nil if the remainder
+// is written entirely, in which case err is reported to the ErrorHandler.
+// l.mu must be held by the caller.
+func (l *Logger) resumeWrite(b []byte, n int, err error) (int, error) {
+	h := l.file.Load()
+	if !l.opts.partialWrite || h == nil {
+		return n, err
+	}
+	rest := b[n:]
+	m, err1 := l.writeHandle(h, rest, false)
+	h.size.Add(int64(m))
+	if l.opts.maxLines > 0 {
+		h.lines.Add(countLines(rest[:m]))
+	}
+	if m > 0 {
+		h.recordWrite(l.opts.clock.Now().UnixNano())
+	}
+	if err1 != nil {
+		return n + m, errors.Join(err, err1)
+	}
+	l.report(fmt.Errorf("%w: %w", ErrPartialWrite, err))
+	return n + m, nil
+}
+
+// writeLocked is the body of write. l.mu must be held by the caller.
+//
+// If the filesystem turns read-only, or opening the log file keeps failing,
+// it stops reopening files on every write, which would hot-loop, but writes
+// to the fallback writer instead, and probes for recovery on a backoff
+// schedule.
+func (l *Logger) writeLocked(b []byte) (n int, err error) {
+	if l.readOnly && l.opts.clock.Now().Before(l.nextProbe) {
+		return l.writeFallback(b, fmt.Errorf("%w: %w", ErrReadOnly, l.readOnlyErr))
+	}
+	if l.openFailing && l.opts.clock.Now().Before(l.nextOpen) {
+		return l.writeFallback(b, fmt.Errorf("%w: %w", ErrOpenBackoff, l.openErr))
+	}
+	n, err = l.writeFile(b)
+	if isReadOnly(err) {
+		l.enterReadOnly(err)
+		if n == 0 {
+			return l.writeFallback(b, fmt.Errorf("%w: %w", ErrReadOnly, l.readOnlyErr))
+		}
+		return n, err
+	}
+	if err != nil && l.file.Load() == nil {
+		// no file is open, so the error is from opening it.
+		l.enterOpenBackoff(err)
+		return n, err
+	}
+	if l.readOnly && err == nil {
+		tracef(os.Stderr, "filesystem recovered from read-only")
+		l.readOnly = false
+		l.probeBackoff = 0
+	}
+	if l.openFailing && err == nil {
+		tracef(os.Stderr, "log file reopened")
+		l.openFailing = false
+		l.openBackoff = 0
+	}
+	return n, err
+}
+
+// writeFile writes b to the current file, and rotates it if necessary. l.mu
+// must be held by the caller.
+func (l *Logger) writeFile(b []byte) (n int, err error) {
 	writeLen := int64(len(b))
 
 	// Try to resume current log file on New
-	if l.file == nil {
+	if l.file.Load() == nil {
 		if err = l.openExistingOrNew(writeLen); err != nil {
 			return 0, err
 		}
@@ -152,69 +771,319 @@ func (l *Logger) write(b []byte) (n int, err error) {
 	// TODO: to avoid stat cost on per write, we can stat periodically (e.g.: 1 times per second).
 	if l.currFilename != "" {
 		// The os.Stat method cost is: 256 B/op, 2 allocs/op
-		_, err = l.osStat(l.currFilename)
-		if l.file == nil || errors.Is(err, fs.ErrNotExist) {
+		fi, err := l.osStat(l.currFilename)
+		if l.file.Load() == nil || errors.Is(err, fs.ErrNotExist) {
 			if err = l.openExistingOrNew(writeLen); err != nil {
 				return 0, err
 			}
 		} else if err != nil {
 			return 0, err
+		} else {
+			l.reconcileSize(l.file.Load(), fi)
 		}
 	}
-	// Factor 1: MaxSize
-	if l.opts.maxSize > 0 && l.size+writeLen > int64(l.opts.maxSize) {
-		if err = l.rotate(); err != nil {
-			return 0, err
-		}
-	} else {
+	var lines int64
+	if l.opts.maxLines > 0 {
+		lines = countLines(b)
+	}
+	var reason RotateReason
+	if l.pins > 0 {
+		// deferred until the Writers are closed, and evaluated again on
+		// the next write.
+	} else if l.opts.maxSize > 0 && l.currSize()+writeLen > l.opts.sizeLimit() {
+		// Factor 1: MaxSize
+		reason = RotateBySize
+	} else if l.opts.maxLines > 0 && l.currLines()+lines > int64(l.opts.maxLines) {
+		// Factor 3: MaxLines
+		reason = RotateByLines
+	} else if l.maxInterval > 0 && l.rotationDueLocked(l.currRotationTime) {
 		// Factor 2: MaxInterval
-		if l.maxIntervalSeconds > 0 &&
-			l.currRotationTime != evalCurrRotationTime(l.opts.clock, l.tzOffsetSeconds, l.maxIntervalSeconds) {
-			if err = l.rotate(); err != nil {
-				return 0, err
-			}
+		reason = RotateByInterval
+	} else if l.opts.rotatePredicate != nil && l.opts.rotatePredicate(l.file.Load().stats(), b) {
+		// Factor 4: RotatePredicate
+		reason = RotateByPredicate
+	}
+	rotated := false
+	if reason != 0 {
+		// a vetoed rotation is evaluated again on the next write.
+		if rotated, err = l.rotateFor(reason); err != nil && !isVetoed(err) {
+			return 0, err
 		}
 	}
 
-	n, err = l.file.Write(b)
-	l.size += int64(n)
+	h := l.file.Load()
+	n, err = l.writeHandle(h, b, rotated)
+	h.size.Add(int64(n))
+	h.lines.Add(lines)
+	if n > 0 {
+		h.recordWrite(l.opts.clock.Now().UnixNano())
+	}
 
-	if err != nil {
+	if err != nil && !isReadOnly(err) && !errors.Is(err, ErrWriteTimeout) {
 		tracef(os.Stderr, "failed to write: %v, try to open existing or new file", err)
 		if err1 := l.openExistingOrNew(writeLen); err1 != nil {
 			err = errors.Join(err, err1)
 			return n, err
 		}
+		return l.resumeWrite(b, n, err)
 	}
 
 	return n, err
 }
 
-// writeLoop runs in a goroutine to sink the writeCh until Close is called.
+// enterReadOnly switches to the read-only mode after the error err caused by
+// the read-only filesystem, and schedules the next probe for recovery with
+// exponential backoff. l.mu must be held by the caller.
+func (l *Logger) enterReadOnly(err error) {
+	if !l.readOnly {
+		l.readOnly = true
+		l.report(fmt.Errorf("%w: %w", ErrReadOnly, err))
+	}
+	if l.probeBackoff == 0 {
+		l.probeBackoff = minProbeBackoff
+	} else if l.probeBackoff *= 2; l.probeBackoff > maxProbeBackoff {
+		l.probeBackoff = maxProbeBackoff
+	}
+	l.nextProbe = l.opts.clock.Now().Add(l.probeBackoff)
+	l.readOnlyErr = err
+	// the current file can't be written anymore, so detach it to make
+	// writes take the slow path.
+	l.detach()
+}
+
+// enterOpenBackoff stops reopening the log file on every write after the
+// error err opening it, and schedules the next attempt with exponential
+// backoff and jitter, so concurrent Loggers failing together won't retry in
+// lockstep. l.mu must be held by the caller.
+func (l *Logger) enterOpenBackoff(err error) {
+	if !l.openFailing {
+		l.openFailing = true
+		l.report(fmt.Errorf("%w: %w", ErrOpenBackoff, err))
+	}
+	if l.openBackoff == 0 {
+		l.openBackoff = minProbeBackoff
+	} else if l.openBackoff *= 2; l.openBackoff > maxProbeBackoff {
+		l.openBackoff = maxProbeBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(l.openBackoff) / 2))
+	l.nextOpen = l.opts.clock.Now().Add(l.openBackoff/2 + jitter)
+	l.openErr = err
+}
+
+// writeFallback writes b to the FallbackWriter in the read-only mode, or
+// while backing off from reopening. If not set, err is returned. l.mu must
+// be held by the caller.
+func (l *Logger) writeFallback(b []byte, err error) (int, error) {
+	if l.opts.fallback == nil {
+		return 0, err
+	}
+	return l.opts.fallback.Write(b)
+}
+
+// report reports err to the ErrorHandler after l.mu is released. l.mu must
+// be held by the caller.
+func (l *Logger) report(err error) {
+	l.reports = append(l.reports, err)
+}
+
+// warn reports the non-fatal err to the ErrorHandler on the next unlock.
+// Unlike report, l.mu needn't be held by the caller.
+func (l *Logger) warn(err error) {
+	l.warnMu.Lock()
+	l.warnings = append(l.warnings, err)
+	l.warnMu.Unlock()
+}
+
+// handleError calls the ErrorHandler with err, or traces it if not set. l.mu
+// must not be held by the caller.
+func (l *Logger) handleError(err error) {
+	l.recordError(err)
+	if l.opts.errorHandler == nil {
+		tracef(os.Stderr, "%v", err)
+		return
+	}
+	l.opts.errorHandler(err)
+}
+
+// unlock releases l.mu, and then closes the detached files and reports
+// errors to the ErrorHandler outside the lock, so queued writers won't be
+// blocked, and the handler can write to the Logger itself. It returns the
+// joined close errors.
+func (l *Logger) unlock() error {
+	detached := l.takeDetached()
+	reports := l.reports
+	l.reports = nil
+	l.warnMu.Lock()
+	reports = append(reports, l.warnings...)
+	l.warnings = nil
+	l.warnMu.Unlock()
+	l.mu.Unlock()
+
+	for _, err := range reports {
+		l.handleError(err)
+	}
+	return l.closeDetached(detached)
+}
+
+// writeLoop runs in a goroutine to sink the queue until Close is called.
 func (l *Logger) writeLoop() {
 	for {
+		if op, ok := l.nextOp(); ok {
+			l.runOp(op)
+			continue
+		}
+		// replay on each wakeup with an empty queue, as writes may be
+		// spilled while the queue drains, see spillWrite.
+		l.replaySpill(false)
 		select {
-		case <-l.quit:
-			// How long to drain on l.writeCh
+		case <-l.life.quit:
+			// How long to drain on l.queue
 			drainDu := 10 * time.Millisecond
-			if len(l.writeCh) > 100 {
+			if l.queued() > 100 {
 				// give more drain time
 				drainDu *= 10
 			}
 			timer := time.NewTimer(drainDu)
 			defer timer.Stop()
-			for {
-				select {
-				case <-timer.C:
-					return // quit
-				case b := <-l.writeCh:
-					_, _ = l.write(b)
-				}
+			l.drain(timer)
+			l.drainOps()
+			l.replaySpill(true)
+			return // quit
+		case <-l.notify:
+		}
+	}
+}
+
+// drain runs the queued ops, including the in-flight ones, until timer
+// fires.
+func (l *Logger) drain(timer *time.Timer) {
+	for {
+		if op, ok := l.nextOp(); ok {
+			l.runOp(op)
+			select {
+			case <-timer.C:
+				return
+			default:
 			}
-		case b := <-l.writeCh:
-			// what am I going to do, log this by tracef?
-			_, _ = l.write(b)
+			continue
 		}
+		select {
+		case <-timer.C:
+			return
+		case <-l.notify:
+		}
+	}
+}
+
+// nextOp returns the next op to run by priority: PriorityHigh writes in
+// highCh first, then the fn op whose preceding entries were all dequeued,
+// and then the entry at the head of queue. It returns false if there is
+// nothing to run.
+func (l *Logger) nextOp() (writeOp, bool) {
+	select {
+	case op := <-l.highCh:
+		return op, true
+	default:
+	}
+	l.opsMu.Lock()
+	if len(l.ops) > 0 && (l.ops[0].pos <= l.dequeued || l.queue.Len() == 0) {
+		op := l.ops[0]
+		l.ops[0] = writeOp{}
+		l.ops = l.ops[1:]
+		l.opsMu.Unlock()
+		return op, true
+	}
+	l.opsMu.Unlock()
+	if e, ok := l.queue.Dequeue(); ok {
+		l.dequeued++
+		return writeOp{b: e.Data, t: e.Time.UnixNano()}, true
+	}
+	return writeOp{}, false
+}
+
+// queued returns the number of ops queued in queue and highCh.
+func (l *Logger) queued() int {
+	return l.queue.Len() + len(l.highCh)
+}
+
+// writeOp is an operation run by writeLoop: a write of b, or fn to run in
+// order with the writes.
+type writeOp struct {
+	b   []byte
+	fn  func()
+	t   int64  // enqueue time in Unix nanoseconds
+	pos uint64 // count of entries enqueued before fn
+}
+
+// runOp runs op, and tracks its enqueue time as the oldest queued entry
+// while it's in progress, as all the entries still in queue were enqueued
+// after it. PriorityHigh writes may overtake, so the age is approximate if
+// they are mixed.
+func (l *Logger) runOp(op writeOp) {
+	l.metrics.oldestQueued.Store(op.t)
+	defer func() {
+		if l.queued() == 0 {
+			l.metrics.oldestQueued.Store(0)
+		}
+	}()
+	if op.fn != nil {
+		op.fn()
+		return
+	}
+	l.writeBuffered(op.b)
+}
+
+// drainOps drops the remaining writes in queue after the drain time, but
+// still runs the remaining fn ops, as their callers are waiting, and writes
+// the remaining PriorityHigh writes, as they are never discarded.
+func (l *Logger) drainOps() {
+	for {
+		select {
+		case op := <-l.highCh:
+			l.runOp(op)
+			continue
+		default:
+		}
+		op, ok := l.nextOp()
+		if !ok {
+			return
+		}
+		if op.fn != nil {
+			op.fn()
+		} else {
+			l.discard(&l.metrics.DiscardsClosed, len(op.b), op.b)
+		}
+	}
+}
+
+// submit runs fn in order with buffered writes: after all the previously
+// submitted writes and before the subsequent ones. In unbuffered mode, fn
+// is run directly. It returns false if the Logger was closed, and fn is
+// never run.
+func (l *Logger) submit(fn func()) bool {
+	l.opMu.RLock()
+	defer l.opMu.RUnlock()
+	if l.closed.Load() {
+		return false
+	}
+	if l.queue == nil {
+		fn()
+		return true
+	}
+	// never discarded, and run after the entries already enqueued.
+	l.opsMu.Lock()
+	l.ops = append(l.ops, writeOp{fn: fn, t: l.opts.clock.Now().UnixNano(), pos: l.enqueued.Load()})
+	l.opsMu.Unlock()
+	l.notifyWrite()
+	return true
+}
+
+// writeBuffered writes b taken from queue, and reports the error to the
+// ErrorHandler as there is no caller to return it to. ErrReadOnly is skipped
+// as it was already reported when entering the read-only mode.
+func (l *Logger) writeBuffered(b []byte) {
+	if _, err := l.writeUncommitted(b); err != nil && !errors.Is(err, ErrReadOnly) {
+		l.handleError(err)
 	}
 }
 
@@ -232,115 +1101,206 @@ func (l *Logger) mill() {
 func (l *Logger) millLoop() {
 	for {
 		select {
-		case <-l.quit:
-			// How long to drain on l.millCh
-			timer := time.NewTimer(10 * time.Millisecond)
-			defer timer.Stop()
-			for {
-				select {
-				case <-timer.C:
-					return // quit
-				case <-l.millCh:
-					_ = l.millRunOnce()
+		case <-l.life.quit:
+			// run the pending mill request, if any, and quit.
+			select {
+			case <-l.millCh:
+				if err := l.millRunOnce(); err != nil {
+					l.handleError(err)
 				}
+			default:
 			}
+			return // quit
 		case <-l.millCh:
-			// what am I going to do, log this by tracef?
-			_ = l.millRunOnce()
+			if err := l.millRunOnce(); err != nil {
+				l.handleError(err)
+			}
 		}
 	}
 }
 
-// millRunOnce performs removal of stale log files. Old log
-// files are removed, keeping at most MaxBackups files, as long as
-// none of them are older than MaxAge.
+// millRunOnce performs post-rotation processing of rotated log files, and
+// removal of stale log files. Old log files are removed, keeping at most
+// MaxBackups files, as long as none of them are older than MaxAge.
 func (l *Logger) millRunOnce() error {
-	files, err := l.getLogFiles()
-	if err != nil {
-		return err
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	if l.sweepDue.CompareAndSwap(true, false) {
+		// before processing, so resumed files are processed in this pass.
+		if err := l.sweepPartials(); err != nil {
+			l.handleError(err)
+		}
 	}
-	if len(files) == 0 {
-		return nil
+	l.processAllRotated()
+	if err := l.compressSavelog(); err != nil {
+		l.handleError(err)
+	}
+	if err := l.bundleDays(); err != nil {
+		l.handleError(err)
 	}
 
-	if l.opts.symlink != "" {
-		// NOTE: files already sorted by modification time in descending order.
-		latestFilename := files[0].path
-		if err := link(latestFilename, l.opts.symlink); err != nil {
+	// the current file is written since the previous pass.
+	l.index.touch(l.currentFilename())
+	rules := l.opts.symlinkRules()
+	var files []FileInfo
+	if l.dirGlob == "" || len(rules) > 0 || l.opts.quotaGroup != nil {
+		// directory retention alone never globs the files.
+		var err error
+		if files, err = l.getLogFiles(); err != nil {
 			return err
 		}
+		if len(files) == 0 {
+			return nil
+		}
 	}
 
-	if l.opts.maxBackups <= 0 && l.opts.maxAge <= 0 {
-		return nil
+	// the current file may not be the latest one by Time, e.g.: just
+	// created while a rotated file is still being processed.
+	current := l.currentFilename()
+	if fi, err := os.Lstat(current); err != nil || !fi.Mode().IsRegular() {
+		current = ""
+	}
+	now := l.opts.clock.Now()
+	rotationTime := evalCurrRotationTime(l.opts.clock, l.tzOffset, l.maxInterval)
+	if h := l.file.Load(); h != nil {
+		rotationTime = h.rotationTime
+	}
+	linked := make(map[string]bool)
+	for _, r := range rules {
+		// NOTE: files already sorted newest first, see byModTime. The
+		// symlink is re-pointed atomically before its previous target is
+		// removed.
+		target := r.rule(current, files, now)
+		if target == "" {
+			continue
+		}
+		name := l.evalSymlink(r.name, rotationTime)
+		if err := link(target, name); err != nil {
+			return &RotationError{Op: "symlink", Path: name, Err: err}
+		}
+		linked[target] = true
 	}
 
-	// TODO: compresess
-	var removals []*logfile
+	// the file being written or linked is never removed, so regard it as
+	// the newest one, and count it for policies like MaxBackups.
+	protected := func(f FileInfo) bool { return f.Path == current || linked[f.Path] }
+	files = moveToFront(files, protected)
 
-	if l.opts.maxAge > 0 {
-		var remaining []*logfile
-		cutoff := l.opts.clock.Now().Add(-1 * l.opts.maxAge)
-		for _, f := range files {
-			if f.ModTime().Before(cutoff) {
-				removals = append(removals, f)
-			} else {
-				remaining = append(remaining, f)
+	var errs []error
+	if l.dirGlob != "" {
+		var paths []string
+		for path := range linked {
+			paths = append(paths, path)
+		}
+		errs = l.purgeDirs(paths, now)
+	} else {
+		removals := applyRetentionPolicies(l.policies, files, now)
+		for _, f := range removals {
+			if protected(f) {
+				continue
 			}
+			if err := l.deferDeletion(f); err != nil {
+				// deferred to a later pass.
+				errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+				continue
+			}
+			if err := os.Remove(f.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+			}
+			l.index.touch(f.Path)
 		}
-		files = remaining
 	}
-
-	if l.opts.maxBackups > 0 && l.opts.maxBackups < len(files) {
-		preserved := make(map[string]bool)
-		for _, f := range files {
-			preserved[f.path] = true
-			if len(preserved) > l.opts.maxBackups {
-				// Only remove if we have more than MaxBackups
-				removals = append(removals, f)
-			}
+	if g := l.opts.quotaGroup; g != nil {
+		if current != "" {
+			linked[current] = true
+		}
+		if err := g.enforce(l, linked); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	for _, f := range removals {
-		// FIXME: need return if encounted an error
-		_ = os.Remove(f.path)
-	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// getLogFiles returns all log files matched the globPattern, and the ones
+// moved by the RotateHook, sorted newest first. With a FileIndex, the files
+// are listed from the index.
+func (l *Logger) getLogFiles() ([]FileInfo, error) {
+	scan := func() ([]FileInfo, error) {
+		return listLogFiles(l.globPattern, l.movedPaths(), l.opts.birthTime, l.opts.seqSuffix, l.names, l.untrackMoved)
+	}
+	if l.index == nil {
+		return scan()
+	}
+	return l.index.list(l.opts.clock.Now(), scan, l.statLogFile)
 }
 
-// getLogFiles returns all log files matched the globPattern, sorted by ModTime.
-func (l *Logger) getLogFiles() ([]*logfile, error) {
-	paths, err := filepath.Glob(l.globPattern)
+// listLogFiles returns all log files matched the globPattern, and the moved
+// ones not matched, sorted by the times parsed by names, falling back to
+// Time, and then by the sequence suffix in seqSuffix format, see byModTime.
+// gone is called with each moved file which no longer exists, if not nil.
+func listLogFiles(globPattern string, moved []string, useBirthTime bool, seqSuffix string, names *timeParser, gone func(path string)) ([]FileInfo, error) {
+	paths, err := filepath.Glob(globPattern)
 	if err != nil {
 		return nil, err
 	}
+	for _, path := range moved {
+		if matched, _ := filepath.Match(globPattern, path); !matched {
+			paths = append(paths, path)
+		}
+	}
 
-	logFiles := []*logfile{}
+	logFiles := []FileInfo{}
 	for _, path := range paths {
-		fi, err := os.Lstat(path)
+		f, ok, err := lstatLogFile(path, useBirthTime, names)
 		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && gone != nil {
+				gone(path)
+			}
 			// ignore error
 			continue
 		}
-		if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
-			// ignore symlink files
-			continue
+		if ok {
+			logFiles = append(logFiles, f)
 		}
-		logFiles = append(logFiles, &logfile{path, fi})
 	}
 
-	sort.Sort(byModTime(logFiles))
+	sort.Sort(byModTime{logFiles, seqSuffix})
 
 	return logFiles, nil
 }
 
+// lstatLogFile returns the FileInfo of the log file at path with the time
+// parsed by names, and false if it's a symlink, which is never regarded as a
+// log file.
+func lstatLogFile(path string, useBirthTime bool, names *timeParser) (FileInfo, bool, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return FileInfo{}, false, err
+	}
+	if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
+		return FileInfo{}, false, nil
+	}
+	f := FileInfo{Path: path, FileInfo: fi}
+	names.stamp(&f)
+	if useBirthTime {
+		if t, ok := birthTime(path, fi); ok {
+			f.birthTime = t
+		}
+	}
+	return f, true, nil
+}
+
 // openExistingOrNew opens the logfile if it exists and if the current write
 // would not put it over MaxSize. If there is no such file or the write would
 // put it over the MaxSize, a new file is created.
-func (l *Logger) openExistingOrNew(writeLen int64) error {
+func (l *Logger) openExistingOrNew(writeLen int64) (err error) {
 	defer l.mill()
+	defer func() {
+		if err == nil {
+			l.indexSegment(nil)
+		}
+	}()
 
 	// try close ahead, since l.file maybe not nil.
 	if err := l.close(); err != nil {
@@ -351,86 +1311,203 @@ func (l *Logger) openExistingOrNew(writeLen int64) error {
 	if overMaxSequence {
 		return l.openNew(filename)
 	}
+	if !l.recovered {
+		l.recovered = true
+		filename = l.adoptOrphan(filename)
+	}
 
 	info, err := l.osStat(filename)
-	if errors.Is(err, fs.ErrNotExist) {
+	if errors.Is(err, fs.ErrNotExist) || l.isStandby(filename) {
 		return l.openNew(filename)
 	} else if err != nil {
-		return fmt.Errorf("get logfile info: %w", err)
+		return &RotationError{Op: "stat", Path: filename, Err: err}
 	}
 
-	if l.opts.maxSize > 0 && info.Size()+writeLen >= int64(l.opts.maxSize) {
-		return l.rotate()
+	if l.staleActive(info) {
+		return l.rotate(RotateByRecovery)
+	}
+	if l.opts.maxSize > 0 && info.Size()+writeLen >= l.opts.sizeLimit() {
+		return l.rotate(RotateBySize)
+	}
+	var lines int64
+	if l.opts.maxLines > 0 {
+		// count lines of the existing file to resume MaxLines.
+		if lines, err = countFileLines(filename); err != nil {
+			return l.openUnreadable(filename)
+		}
+		if lines >= int64(l.opts.maxLines) {
+			return l.rotate(RotateByLines)
+		}
 	}
 
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|l.opts.durability.flag(), defaultFileMode)
 	if err != nil {
 		// if we fail to open the old log file for some reason, just ignore
 		// it and open a new log file.
-		return l.openNew(filename)
+		return l.openUnreadable(filename)
+	}
+	if err := l.lock(file); err != nil {
+		return err
 	}
-	l.file = file
-	l.size = info.Size()
+	h := newFileHandle(l.align(l.supervise(file), filename, info.Size()), filename, l.currRotationTime, info.Size())
+	h.lines.Store(lines)
+	l.file.Store(h)
 	return nil
 }
 
+// openUnreadable opens a new log file for writing instead of the existing
+// filename which fails to open, truncating it unless a RecoveryMode is set.
+func (l *Logger) openUnreadable(filename string) error {
+	if l.opts.recovery != RecoveryOff {
+		return l.rotate(RotateByRecovery)
+	}
+	return l.openNew(filename)
+}
+
 // openNew opens a new log file for writing, moving any old log file out of the
 // way.  This methods assumes the file has already been closed.
 func (l *Logger) openNew(filename string) error {
-	dirname := filepath.Dir(filename)
-	err := os.MkdirAll(dirname, 0755)
-	if err != nil {
-		return fmt.Errorf("can't make directories for new logfile: %s", err)
-	}
 	// we use truncate here because this should only get called when we've moved
 	// the file ourselves. if someone else creates the file in the meantime,
 	// just wipe out the contents.
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC | l.opts.durability.flag()
+	if l.opts.exclusive {
+		// truncate after the lock is taken, so we never clobber the file
+		// of another holder.
+		flag &^= os.O_TRUNC
+	}
+	f := l.takeStandby(filename)
+	if f == nil {
+		var err error
+		if f, err = l.createFile(filename, flag); err != nil {
+			return err
+		}
+	}
+	if l.opts.exclusive {
+		if err := l.lock(f); err != nil {
+			return err
+		}
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return &RotationError{Op: "truncate", Path: filename, Err: err}
+		}
+	}
+	if size := l.preallocateSize(); size > 0 {
+		// not fatal, as the space is allocated on demand anyway.
+		if err := preallocate(f, size); err != nil {
+			l.report(&RotationError{Op: "preallocate", Path: filename, Err: err})
+		}
+	}
+	l.file.Store(newFileHandle(l.align(l.supervise(f), filename, 0), filename, l.currRotationTime, 0))
+	return nil
+}
+
+// createFile opens filename with flag, making its directory on demand, and
+// applies permissions.
+func (l *Logger) createFile(filename string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(filename, flag, defaultFileMode)
+	for attempt := 0; errors.Is(err, fs.ErrNotExist) && attempt <= createRetries; attempt++ {
+		// The directory usually exists, so only make directories on demand
+		// to keep the expensive MkdirAll out of the rotation path. Retried
+		// with jitter, as the directory may be removed and recreated by
+		// concurrent creators in the meantime.
+		if attempt > 0 {
+			retryJitter(attempt)
+		}
+		dirname := filepath.Dir(filename)
+		if err := l.mkdirAll(dirname); err != nil {
+			return nil, &RotationError{Op: "mkdir", Path: dirname, Err: err}
+		}
+		f, err = os.OpenFile(filename, flag, defaultFileMode)
+	}
 	if err != nil {
-		return fmt.Errorf("can't open new logfile: %s", err)
+		return nil, &RotationError{Op: "open", Path: filename, Err: err}
+	}
+	l.index.touch(filename)
+	if err := l.applyPermissions(filename, false); err != nil {
+		f.Close()
+		return nil, &RotationError{Op: "setperm", Path: filename, Err: err}
+	}
+	return f, nil
+}
+
+// preallocateSize returns the size to preallocate for new files, which is
+// capped by MaxSize.
+func (l *Logger) preallocateSize() int64 {
+	size := l.opts.preallocate
+	if l.opts.maxSize > 0 && size > int64(l.opts.maxSize) {
+		size = int64(l.opts.maxSize)
+	}
+	return size
+}
+
+// lock takes an exclusive advisory lock on f if Exclusive is enabled. If
+// failed, f is closed and a descriptive error is returned.
+func (l *Logger) lock(f *os.File) error {
+	if !l.opts.exclusive {
+		return nil
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return &RotationError{Op: "lock", Path: f.Name(), Err: err}
 	}
-	l.file = f
-	l.size = 0
 	return nil
 }
 
 // l.mu must be held by the caller.
 // take MaxInterval, MaxSequence, and MaxSize into consideration.
 func (l *Logger) evalCurrentFilename(writeLen int64, forceNewFile bool) (string, bool) {
+	defer l.saveState()
+
 	baseFilename := l.currBaseFilename
 	if l.currBaseFilename == "" {
 		// init base filename if l.currBaseFilename not set
-		if l.maxIntervalSeconds > 0 {
-			l.currRotationTime = evalCurrRotationTime(l.opts.clock, l.tzOffsetSeconds, l.maxIntervalSeconds)
+		if rotationTime, sequence, ok := l.state.take(); ok {
+			// continue the schedule of the predecessor, so the restored
+			// file is rotated at its own interval boundary.
+			l.currRotationTime, l.currSequence = rotationTime, sequence
+			l.currBaseFilename = l.genBaseFilename(rotationTime)
+		} else if l.maxInterval > 0 {
+			l.currRotationTime = evalCurrRotationTime(l.opts.clock, l.tzOffset, l.maxInterval)
 		} else if l.currRotationTime == 0 {
 			// no rotation based on MaxInterval, just set currRotationTime
 			// to now only once if not set.
-			l.currRotationTime = l.opts.clock.Now().Unix()
+			l.currRotationTime = l.opts.clock.Now().UnixNano() + l.tzOffset
 		}
-		baseFilename = genBaseFilename(l.pattern, l.opts.clock, l.currRotationTime)
-	} else if l.maxIntervalSeconds > 0 {
-		rotationTime := evalCurrRotationTime(l.opts.clock, l.tzOffsetSeconds, l.maxIntervalSeconds)
-		if l.currRotationTime != rotationTime {
+		baseFilename = l.genBaseFilename(l.currRotationTime)
+	} else if l.maxInterval > 0 {
+		// never moves backwards, so already written files are never
+		// reopened on clock regression.
+		rotationTime := evalCurrRotationTime(l.opts.clock, l.tzOffset, l.maxInterval)
+		if rotationTime > l.currRotationTime {
 			l.currRotationTime = rotationTime
-			baseFilename = genBaseFilename(l.pattern, l.opts.clock, l.currRotationTime)
+			baseFilename = l.genBaseFilename(l.currRotationTime)
 		}
 	}
+	if l.opts.savelogCycle > 0 {
+		// old files are numbered by shiftSavelog on rotation.
+		l.currBaseFilename = baseFilename
+		l.currFilename = baseFilename
+		return baseFilename, false
+	}
+	if l.opts.activeFile != "" {
+		// the active file is renamed by archiveActive on rotation.
+		l.currBaseFilename = baseFilename
+		l.currFilename = l.opts.activeFile
+		return l.opts.activeFile, false
+	}
 	overMaxSequence := false
 	if baseFilename != l.currBaseFilename {
 		l.currBaseFilename = baseFilename
 		l.currSequence = 0
 	} else {
-		if forceNewFile || (l.opts.maxSize > 0 && l.size+writeLen > int64(l.opts.maxSize)) {
+		if forceNewFile || (l.opts.maxSize > 0 && l.currSize()+writeLen > l.opts.sizeLimit()) {
 			overMaxSequence = l.incrCurrSequence()
 		}
 	}
 
 	genFilename := func(basename string, seq uint) string {
-		if seq == 0 {
-			return basename
-		} else {
-			return fmt.Sprintf("%s.%d", basename, seq)
-		}
+		return sequenceFilename(basename, l.opts.seqSuffix, seq)
 	}
 
 	filename := genFilename(l.currBaseFilename, l.currSequence)
@@ -442,8 +1519,8 @@ func (l *Logger) evalCurrentFilename(writeLen int64, forceNewFile bool) (string,
 			if overMaxSequence {
 				break
 			}
-			if _, err := l.osStat(filename); err != nil {
-				// found the first not existed file
+			if _, err := l.osStat(filename); err != nil || l.isStandby(filename) {
+				// found the first not existed file, or the pre-opened one
 				break
 			}
 			overMaxSequence = l.incrCurrSequence()
@@ -466,32 +1543,172 @@ func (l *Logger) incrCurrSequence() bool {
 }
 
 // Close implements io.Closer. It closes the writeLoop and millLoop
-// goroutines and the current log file.
+// goroutines and the current log file. A shared Logger is only closed when
+// Close is called for each New returned it.
+//
+// It's safe to call Close multiple times and concurrently: the first call
+// shuts down the Logger, and the others wait until it's done, and then
+// return ErrClosed.
 func (l *Logger) Close() error {
-	close(l.quit) // tell writeLoop and millLoop to quit
-	l.wg.Wait()   // and wait until they have quitted
+	if !unregister(l) {
+		return nil // still referenced by others
+	}
+	first := false
+	l.closeOnce.Do(func() {
+		first = true
+		l.closeErr = l.shutdown()
+		// after the files are closed, so no more lines are written.
+		l.forward.close()
+	})
+	if !first {
+		return ErrClosed
+	}
+	return l.closeErr
+}
+
+// IsClosed reports whether Close has been called.
+func (l *Logger) IsClosed() bool {
+	return l.closed.Load()
+}
+
+// shutdown stops the goroutines, and then closes the files of l. It's
+// called once by Close.
+func (l *Logger) shutdown() error {
+	l.closed.Store(true)
+	// wait for in-progress submits, so all submitted ops are in ops
+	// and run by writeLoop.
+	l.opMu.Lock()
+	l.opMu.Unlock()
+	l.life.signal() // tell writeLoop and millLoop to quit
+	l.cancel()      // and cancel running post-rotation processors
+	l.life.wait()   // and wait until they have fully stopped
+	if l.opts.quotaGroup != nil {
+		l.opts.quotaGroup.leave(l)
+	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	// It's ok to not close writeCh and millCh explicitly, because we
+	// It's ok to not close notify and millCh explicitly, because we
 	// already closed the writeLoop and millLoop goroutines, so they will
-	// be garbage collected. Besides, if you still call Write after Close
-	// called, nothing will sink to file.
+	// be garbage collected. Besides, Write returns ErrClosed after Close
+	// called, so nothing will sink to file.
 	//
-	// close(l.writeCh)
+	// close(l.notify)
 	// close(l.millCh)
-	return l.close()
+	err := l.closeDetached(l.takeDetached())
+	l.discardStandby(l.standby)
+	l.closeRotationEvents()
+	if l.spill != nil {
+		err = errors.Join(err, l.spill.close())
+	}
+	if h := l.file.Load(); h != nil {
+		err = errors.Join(err, l.finalize(h))
+	}
+	return errors.Join(err, l.close())
+}
+
+// detach detaches the current file if it is open, so that it can be closed
+// later without holding l.mu. l.mu must be held by the caller.
+func (l *Logger) detach() *fileHandle {
+	h := l.file.Load()
+	if h == nil {
+		return nil
+	}
+	l.detached = append(l.detached, h)
+	l.file.Store(nil)
+	return h
+}
+
+// takeDetached takes all detached files. l.mu must be held by the caller.
+func (l *Logger) takeDetached() []*fileHandle {
+	detached := l.detached
+	l.detached = nil
+	return detached
+}
+
+// closeDetached closes all detached files, calls the RotateHook on and
+// queues the rotated ones for post-rotation processing, and returns the
+// joined close errors.
+func (l *Logger) closeDetached(detached []*fileHandle) error {
+	var errs []error
+	for _, h := range detached {
+		if h.finishing {
+			if err := l.finalize(h); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := l.closeHandle(h); err != nil {
+			errs = append(errs, &RotationError{Op: "close", Path: h.name, Err: err})
+		}
+		l.index.touch(h.name)
+		if h.rotated {
+			path := h.name
+			if h.archivedName != "" {
+				path = h.archivedName
+			}
+			journaled := path
+			l.index.touch(journaled)
+			if renamed := l.renameTimeRange(h, path); renamed != path {
+				l.trackMoved(renamed)
+				path = renamed
+			}
+			h.finalName = l.finishRotated(path)
+			var jerr error
+			if !h.takenOver && h.finalName != "" && len(l.processors) > 0 {
+				jerr = l.journal.close(journaled, h.finalName)
+				l.queueRotated(h.finalName)
+			} else {
+				jerr = l.journal.done(journaled)
+			}
+			if jerr != nil {
+				errs = append(errs, &RotationError{Op: "journal", Path: l.opts.journal, Err: jerr})
+			}
+			stats := h.stats()
+			stats.Path = h.finalName
+			l.sendRotationEvent(RotationEvent{
+				Time:    l.opts.clock.Now(),
+				Path:    h.finalName,
+				Current: h.nextName,
+				Reason:  h.reason,
+				Stats:   stats,
+			})
+		}
+	}
+	if len(detached) > 0 {
+		l.mill()
+	}
+	return errors.Join(errs...)
 }
 
 // close closes the file if it is open.
 func (l *Logger) close() error {
-	if l.file == nil {
+	h := l.file.Load()
+	if h == nil {
 		return nil
 	}
-	err := l.file.Close()
-	l.file = nil
-	l.size = 0
-	return err
+	l.file.Store(nil)
+	if err := l.closeHandle(h); err != nil {
+		return &RotationError{Op: "close", Path: h.name, Err: err}
+	}
+	return nil
+}
+
+// currSize returns the write size of current file. l.mu must be held by
+// the caller.
+func (l *Logger) currSize() int64 {
+	if h := l.file.Load(); h != nil {
+		return h.size.Load()
+	}
+	return 0
+}
+
+// currLines returns the line count of current file. l.mu must be held by
+// the caller.
+func (l *Logger) currLines() int64 {
+	if h := l.file.Load(); h != nil {
+		return h.lines.Load()
+	}
+	return 0
 }
 
 // Rotate forcefully rotates the log files. It will close the existing log file
@@ -503,26 +1720,136 @@ func (l *Logger) close() error {
 // If the new generated log file name clash because file already exists,
 // a sequence suffix of the form ".1", ".2", ".3" and so forth are appended to
 // the end of the log file.
+//
+// In buffered mode, the rotation takes effect after the previously submitted
+// writes and before the subsequent ones. While a Writer is open, the
+// rotation is deferred until it's closed. If the PreRotateHook vetoes the
+// rotation, an error wrapping ErrRotationVetoed is returned.
 func (l *Logger) Rotate() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.rotate()
+	return <-l.RotateAsync()
 }
 
-// rotate closes the current file, opens a new file based on rotation rule,
-// and then runs post-rotation processing and removal.
-func (l *Logger) rotate() error {
-	if err := l.close(); err != nil {
-		return err
+// RotateAsync is like Rotate, but it returns immediately with a channel
+// which receives the result once the rotation actually occurs, and then is
+// closed. In buffered mode, the rotation is queued in order with writes.
+//
+// NOTE: in buffered mode, don't wait on it in the ErrorHandler, which may be
+// called by the write goroutine running the rotation.
+func (l *Logger) RotateAsync() <-chan error {
+	done := make(chan error, 1)
+	ok := l.submit(func() {
+		_, err := l.rotateNow(false)
+		done <- err
+		close(done)
+	})
+	if !ok {
+		done <- ErrClosed
+		close(done)
+	}
+	return done
+}
+
+// RotateAndGet forcefully rotates the log files the same as Rotate, and
+// returns the path of the just-closed file (moved by the RotateHook if
+// any), so callers can immediately hand it to an uploader or parser. The
+// returned file is excluded from the post-rotation processors, as the
+// caller takes it over, but retention still applies to it.
+//
+// It returns an empty path if there was no file being written to, the
+// file was truncated and reused because of MaxSequence, or the rotation is
+// deferred by a Writer.
+//
+// In buffered mode, it's ordered with writes the same as Rotate.
+func (l *Logger) RotateAndGet() (closedFilePath string, err error) {
+	done := make(chan struct{})
+	ok := l.submit(func() {
+		closedFilePath, err = l.rotateNow(true)
+		close(done)
+	})
+	if !ok {
+		return "", ErrClosed
+	}
+	<-done
+	return closedFilePath, err
+}
+
+// rotateNow rotates the current file with l.mu held. If takeOver is true,
+// the rotated file is excluded from the post-rotation processors, and its
+// path is returned.
+func (l *Logger) rotateNow(takeOver bool) (closedFilePath string, err error) {
+	l.mu.Lock()
+	prev := l.file.Load()
+	err = l.rotateOrDefer(RotateByRequest)
+	takenOver := takeOver && prev != nil && prev.rotated
+	if takenOver {
+		prev.takenOver = true
+	}
+	if cerr := l.unlock(); cerr != nil {
+		err = errors.Join(err, cerr)
+	}
+	if takenOver {
+		// closed by unlock, and moved by the RotateHook if any.
+		closedFilePath = prev.finalName
 	}
+	return closedFilePath, err
+}
+
+// rotate detaches the current file, opens a new file based on rotation rule,
+// and then runs post-rotation processing and removal. The detached file is
+// closed by the caller after l.mu is released. The rotation is counted in
+// metrics by reason.
+func (l *Logger) rotate(reason RotateReason) error {
+	prev := l.detach()
 	filename, _ := l.evalCurrentFilename(0, true)
+	var archived string
+	if l.opts.savelogCycle > 0 {
+		// in-flight writes on prev still go to the renamed file.
+		if err := l.shiftSavelog(filename); err != nil {
+			return err
+		}
+	} else if l.opts.activeFile != "" {
+		// in-flight writes on prev still go to the renamed file.
+		var err error
+		if archived, err = l.archiveActive(prev); err != nil {
+			return err
+		}
+	}
 	if err := l.openNew(filename); err != nil {
 		return err
 	}
+	l.indexSegment(prev)
+	l.metrics.countRotation(reason)
+	if prev != nil && (prev.name != filename || archived != "") {
+		// processed after closed, so no in-flight writes are missed.
+		prev.rotated = true
+		prev.reason = reason
+		prev.archivedName = archived
+		prev.nextName = l.file.Load().name
+		path := prev.name
+		if archived != "" {
+			path = archived
+		}
+		if err := l.journal.begin(path, prev.nextName, reason); err != nil {
+			l.report(&RotationError{Op: "journal", Path: l.opts.journal, Err: err})
+		}
+	}
+	if prev != nil && (prev.rotated || l.opts.savelogCycle > 0) {
+		// not reused by the new file, e.g.: over MaxSequence.
+		prev.finishing = true
+	}
 	l.mill()
 	return nil
 }
 
+// CurrentStats returns the FileStats of the current file, or the zero
+// FileStats if no file is open, e.g.: before the first write.
+func (l *Logger) CurrentStats() FileStats {
+	if h := l.file.Load(); h != nil {
+		return h.stats()
+	}
+	return FileStats{}
+}
+
 // currentFilename returns filename the Logger object is writing to.
 func (l *Logger) currentFilename() string {
 	l.mu.RLock()
@@ -531,7 +1858,76 @@ func (l *Logger) currentFilename() string {
 	return l.currFilename
 }
 
-// Metrics returns metrics of this Logger.
+// AccessFile calls fn with the current file under the internal lock, so
+// that integrations needing the underlying handle, e.g.: to sendfile or
+// splice into the log, or fstat-based checks, can access it without
+// breaking the rotation invariants. The current file is opened if not yet.
+// It returns the error of fn, or ErrClosed after Close called.
+//
+// The file is neither rotated nor closed while fn is running, but the
+// writes on the lock-free fast path may still be appended concurrently. fn
+// must not retain f, close it, or call methods of l which take the lock,
+// e.g.: Write in unbuffered mode and Rotate. The size of the file is
+// reconciled after fn, but the line count for MaxLines is not.
+func (l *Logger) AccessFile(fn func(f *os.File) error) error {
+	if l.closed.Load() {
+		return ErrClosed
+	}
+	l.mu.Lock()
+	err := l.accessFile(fn)
+	if cerr := l.unlock(); cerr != nil {
+		tracef(os.Stderr, "failed to close rotated file: %v", cerr)
+	}
+	return err
+}
+
+// accessFile calls fn with the current file. l.mu must be held by the
+// caller.
+func (l *Logger) accessFile(fn func(f *os.File) error) error {
+	if l.file.Load() == nil {
+		if err := l.openExistingOrNew(0); err != nil {
+			return err
+		}
+	}
+	h := l.file.Load()
+	f, ok := h.osFile()
+	if !ok {
+		return &RotationError{Op: "access", Path: h.name, Err: errors.New("not an *os.File")}
+	}
+	if err := h.flush(); err != nil {
+		return &RotationError{Op: "flush", Path: h.name, Err: err}
+	}
+	err := fn(f)
+	// fn may have written to f, even if it failed.
+	if fi, serr := f.Stat(); serr == nil {
+		h.size.Store(fi.Size())
+	}
+	return err
+}
+
+// Options returns a read-only snapshot of the effective options of this
+// Logger.
+func (l *Logger) Options() OptionsSnapshot {
+	snapshot := l.opts.snapshot()
+	snapshot.Pattern = l.patternString()
+	snapshot.Name = l.Name()
+	return snapshot
+}
+
+// Metrics returns a snapshot of metrics of this Logger. It performs no
+// allocations, so it can be polled frequently. Use Metrics.Delta to get the
+// increments since the previous snapshot.
 func (l *Logger) Metrics() Metrics {
-	return l.metrics.toMetrics()
+	m := l.metrics.toMetrics(l.opts.clock.Now())
+	if l.queue != nil {
+		m.QueuedBytes = l.queue.Bytes()
+		if l.opts.queue == nil {
+			m.QueueBound = l.queueBound()
+		}
+	}
+	if l.commit != nil {
+		m.CommittedBytes = l.commit.watermark()
+		m.GroupCommits = l.commit.commits.Load()
+	}
+	return m
 }
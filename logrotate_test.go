@@ -1,6 +1,7 @@
 package logrotate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,7 +9,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -71,6 +75,26 @@ func Benchmark_MaxBackups1000(b *testing.B) {
 	// time.Sleep(time.Second)
 }
 
+func Benchmark_MaxBackups1000Index(b *testing.B) {
+	dir := filepath.Join(baseLogDir, "Benchmark_MaxBackups1000Index")
+	defer os.RemoveAll(dir)
+	l, err := New(filepath.Join(dir, "log%Y%m%d%H%M%S"),
+		WithSymlink(filepath.Join(dir, "log")),
+		WithMaxSize(10),
+		WithMaxBackups(1000),
+		WithFileIndex(time.Minute),
+	)
+	require.NoError(b, err, "New should succeed")
+	defer l.Close()
+
+	log.SetOutput(l)
+
+	logline := "Hello, World"
+	for i := 0; i < b.N; i++ {
+		log.Println(logline)
+	}
+}
+
 func Benchmark_MaxInterval(b *testing.B) {
 	dir := filepath.Join(baseLogDir, "Benchmark_MaxInterval")
 	defer os.RemoveAll(dir)
@@ -111,6 +135,42 @@ func Benchmark_WriteWithoutRotate(b *testing.B) {
 	}
 }
 
+// Benchmark_AlignedWriteWithoutRotate compares with
+// Benchmark_WriteWithoutRotate, as small writes are coalesced into whole
+// 4 KiB blocks.
+func Benchmark_AlignedWriteWithoutRotate(b *testing.B) {
+	dir := filepath.Join(baseLogDir, "BenchmarkAlignedNoRotate")
+	defer os.RemoveAll(dir)
+	l, err := New(filepath.Join(dir, "log"),
+		WithMaxSize(0),
+		WithAlignedWrites(4096, time.Second),
+	)
+	require.NoError(b, err, "New should succeed")
+	defer l.Close()
+
+	for i := 0; i < b.N; i++ {
+		n, err := l.Write(logline50)
+		require.NoError(b, err, "Write should succeed")
+		require.Equal(b, len(logline50), n, "Write length should match")
+	}
+}
+
+func Benchmark_WriteParallelWithoutRotate(b *testing.B) {
+	dir := filepath.Join(baseLogDir, "BenchmarkParallelNoRotate")
+	defer os.RemoveAll(dir)
+	l, err := New(filepath.Join(dir, "log"),
+		WithMaxSize(0),
+	)
+	require.NoError(b, err, "New should succeed")
+	defer l.Close()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = l.Write(logline50)
+		}
+	})
+}
+
 func Benchmark_BufferedWriteWithoutRotate(b *testing.B) {
 	dir := filepath.Join(baseLogDir, "BenchmarkNoRotate")
 	defer os.RemoveAll(dir)
@@ -128,6 +188,27 @@ func Benchmark_BufferedWriteWithoutRotate(b *testing.B) {
 	}
 }
 
+// Benchmark_RotateParallel measures write latency of parallel writers when
+// rotation happens frequently, as rotation work done under the write mutex
+// blocks all queued writers.
+func Benchmark_RotateParallel(b *testing.B) {
+	dir := filepath.Join(baseLogDir, "Benchmark_RotateParallel")
+	defer os.RemoveAll(dir)
+	l, err := New(filepath.Join(dir, "log%Y%m%d%H%M%S"),
+		WithSymlink(filepath.Join(dir, "log")),
+		WithMaxSize(10*len(logline50)),
+		WithMaxBackups(10),
+	)
+	require.NoError(b, err, "New should succeed")
+	defer l.Close()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = l.Write(logline50)
+		}
+	})
+}
+
 func Test_Rotate(t *testing.T) {
 	testCases := []struct {
 		Name        string
@@ -278,11 +359,42 @@ func Test_MaxInterval0(t *testing.T) {
 		WithMaxInterval(0),
 	)
 	require.NoError(t, err, `New should succeed`)
+	defer l.Close()
 	for i := 0; i < 10; i++ {
 		l.Write([]byte("Hello, World"))
 	}
 }
 
+func Test_SubSecondInterval(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SubSecondInterval")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l, err := New(filepath.Join(dir, "app.%H%M%S.%L.log"),
+		WithClock(clock),
+		WithMaxInterval(100*time.Millisecond),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	clock.Advance(150 * time.Millisecond)
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	clock.Advance(30 * time.Millisecond)
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+	clock.Advance(100 * time.Millisecond)
+	_, err = l.Write([]byte("3"))
+	require.NoError(t, err, "Write should succeed")
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.000000.100.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "12", string(data), "writes in the same interval should be in the same file")
+	data, err = os.ReadFile(filepath.Join(dir, "app.000000.200.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "3", string(data), "write in the next interval should be rotated")
+}
+
 func Test_BufferedWrite(t *testing.T) {
 	dir := filepath.Join(baseLogDir, "Test_BufferedWrite")
 	defer os.RemoveAll(dir)
@@ -301,7 +413,6 @@ func Test_BufferedWrite(t *testing.T) {
 		l.Write([]byte("Hello, World"))
 	}
 	l.Close()
-	time.Sleep(1 * time.Second)
 	files, _ := filepath.Glob(filepath.Join(dir, "log*"))
 	require.GreaterOrEqual(t, len(files), 20, "count of rotated log files is wrong")
 }
@@ -375,6 +486,45 @@ func Test_MaxBackups(t *testing.T) {
 	})
 }
 
+func Test_MaxBackupsPerInterval(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MaxBackupsPerInterval")
+	defer os.RemoveAll(dir)
+
+	now := time.Now().Truncate(time.Hour)
+	clock := clockwork.NewFakeClockAt(now.Add(30 * time.Minute))
+
+	// 3 files in the previous hour, and 5 files in the current hour.
+	createFile := func(name string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+		require.NoError(t, os.WriteFile(path, []byte("test file\n"), 0644), "WriteFile should succeed")
+		require.NoError(t, os.Chtimes(path, modTime, modTime), "Chtimes should succeed")
+	}
+	prevHour := now.Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		createFile(fmt.Sprintf("log%s.%d", prevHour.Format("2006010215"), i+1), prevHour.Add(time.Duration(i)*time.Minute))
+	}
+	for i := 0; i < 5; i++ {
+		createFile(fmt.Sprintf("log%s.%d", now.Format("2006010215"), i+1), now.Add(time.Duration(i)*time.Minute))
+	}
+
+	l, err := New(
+		filepath.Join(dir, "log%Y%m%d%H"),
+		WithClock(clock),
+		WithMaxInterval(time.Hour),
+		WithMaxBackupsPerInterval(2),
+		WithMaxBackups(10),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+
+	prevFiles, _ := filepath.Glob(filepath.Join(dir, "log"+prevHour.Format("2006010215")+"*"))
+	require.Equal(t, 2, len(prevFiles), "2 files of the previous hour should be kept")
+	currFiles, _ := filepath.Glob(filepath.Join(dir, "log"+now.Format("2006010215")+"*"))
+	require.Equal(t, 2, len(currFiles), "2 files of the current hour should be kept")
+}
+
 func Test_SetOutput(t *testing.T) {
 	dir := filepath.Join(baseLogDir, "Test_SetOutput")
 	defer os.RemoveAll(dir)
@@ -469,6 +619,39 @@ func (f ClockFunc) Now() time.Time {
 	return f()
 }
 
+func Test_RotateAndGet(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotateAndGet")
+	defer os.RemoveAll(dir)
+
+	var processed atomic.Int32
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithPostRotateProcessors(ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+			processed.Add(1)
+			return path, nil
+		})),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	path, err := l.RotateAndGet()
+	require.NoError(t, err, "RotateAndGet should succeed")
+	require.Equal(t, "", path, "no file should be closed before the first write")
+
+	l.Write([]byte("Hello, World"))
+	path, err = l.RotateAndGet()
+	require.NoError(t, err, "RotateAndGet should succeed")
+	require.Equal(t, filepath.Join(dir, "app.log"), path, "closed file path should match")
+	require.Equal(t, filepath.Join(dir, "app.log.1"), l.currentFilename(), "current file should be rotated")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "Hello, World", string(content), "closed file content should match")
+
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int32(0), processed.Load(), "closed file should not be processed")
+}
+
 func Test_TimeZone(t *testing.T) {
 	dir := filepath.Join(baseLogDir, "Test_TimeZone")
 	defer os.RemoveAll(dir)
@@ -502,6 +685,7 @@ func Test_TimeZone(t *testing.T) {
 					WithClock(test.Clock), // we're not using WithLocation, but it's the same thing
 				)
 				require.NoError(t, err, "New should succeed")
+				defer l.Close()
 
 				t.Logf("expected %s", test.Expected)
 				l.Rotate()
@@ -511,6 +695,39 @@ func Test_TimeZone(t *testing.T) {
 	}
 }
 
+func Test_UTC(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_UTC")
+	defer os.RemoveAll(dir)
+
+	// 2024-01-01 02:30 in JST is 2023-12-31 17:30 in UTC.
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 2, 30, 0, 0, time.FixedZone("JST", 9*3600)))
+	for _, tc := range []struct {
+		utc      bool
+		expected string
+		next     time.Time
+	}{
+		{false, "app.20240101+0900JST.log", time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)},
+		{true, "app.20231231+0000UTC.log", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		l, err := New(
+			filepath.Join(dir, "app.%Y%m%d%z%Z.log"),
+			WithClock(clock),
+			WithMaxInterval(24*time.Hour),
+			WithUTC(tc.utc),
+		)
+		require.NoError(t, err, "New should succeed")
+		require.Equal(t, tc.utc, l.Options().UTC, "UTC should match")
+		_, err = l.Write([]byte("a"))
+		require.NoError(t, err, "Write should succeed")
+		require.Equal(t, filepath.Join(dir, tc.expected), l.currentFilename(), "filename should match")
+		require.True(t, l.NextRotation().Equal(tc.next), "NextRotation should be the next boundary")
+		matched, err := filepath.Match(l.globPattern, l.currentFilename())
+		require.NoError(t, err, "Match should succeed")
+		require.True(t, matched, "glob pattern should match the filename")
+		require.NoError(t, l.Close(), "Close should succeed")
+	}
+}
+
 func Test_CreateNewFileWhenRemovedOnWrite(t *testing.T) {
 	dir := filepath.Join(baseLogDir, "Test_CreateNewFileWhenRemovedOnWrite")
 	defer os.RemoveAll(dir)
@@ -530,7 +747,6 @@ func Test_CreateNewFileWhenRemovedOnWrite(t *testing.T) {
 
 	err = l.Close()
 	require.NoError(t, err, "Close should succeed")
-	time.Sleep(100 * time.Millisecond)
 	files, _ = os.ReadDir(dir)
 	require.Equal(t, 2, len(files), "should auto create new log files after removed")
 }
@@ -544,14 +760,19 @@ func Test_DiscardsWithWriteChan(t *testing.T) {
 		WithWriteChan(1),
 	)
 	require.NoError(t, err, "New should succeed")
+	defer l.Close()
 
 	log.SetOutput(l)
 
+	var wg sync.WaitGroup
 	for i := 0; i < 1000; i++ {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			log.Println(logline50)
 		}()
 	}
+	wg.Wait()
 	metrics := l.Metrics()
 	require.Greaterf(t, metrics.Discards, uint64(0), "Discarded log lines (%d) should be >= 1", metrics.Discards)
 }
@@ -572,7 +793,6 @@ func Test_MaxSequence(t *testing.T) {
 	}
 	err = l.Close()
 	require.NoError(t, err, "Close should succeed")
-	time.Sleep(100 * time.Millisecond)
 	files, _ := os.ReadDir(dir)
 	require.Equal(t, 11, len(files), "should auto create new log files after removed")
 
@@ -589,7 +809,6 @@ func Test_MaxSequence(t *testing.T) {
 	}
 	err = l.Close()
 	require.NoError(t, err, "Close should succeed")
-	time.Sleep(100 * time.Millisecond)
 	files, _ = os.ReadDir(dir)
 	require.Equal(t, 11, len(files), "should auto create new log files after removed")
 }
@@ -620,6 +839,76 @@ func Test_SymlinkTologfileWithSuffix(t *testing.T) {
 	require.NoError(t, err, "Close should succeed")
 }
 
+func Test_RetentionKeepsCurrentAndLinked(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RetentionKeepsCurrentAndLinked")
+	defer os.RemoveAll(dir)
+
+	symlink := filepath.Join(dir, "current")
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithSymlink(symlink),
+		WithMaxBackups(1),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+
+	// the rotated file looks newer than the current one, e.g.: touched by
+	// a post-rotation processor.
+	rotated, current := filepath.Join(dir, "app.log"), l.currentFilename()
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(rotated, future, future), "Chtimes should succeed")
+
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.FileExists(t, current, "current file should never be removed")
+	target, err := os.Readlink(symlink)
+	require.NoError(t, err, "Readlink should succeed")
+	require.Equal(t, filepath.Base(current), target, "symlink should point to the current file")
+	require.NoFileExists(t, rotated, "retention should still remove the others")
+}
+
+func Test_New_ConcurrentSameDir(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_New_ConcurrentSameDir")
+	defer os.RemoveAll(dir)
+
+	// a stale temporary symlink left by a crashed process.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "a", "b"), 0755), "MkdirAll should succeed")
+	require.NoError(t, os.Symlink("app-0.log", filepath.Join(dir, "a", "b", "app-0.log.symlink#")), "Symlink should succeed")
+
+	const n = 32
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	loggers := make([]*Logger, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			loggers[i], errs[i] = New(
+				filepath.Join(dir, "a", "b", fmt.Sprintf("app-%d.log", i)),
+				WithSymlink(filepath.Join(dir, "a", "b", "current")),
+				WithCreateOnNew(true),
+			)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		require.NoError(t, errs[i], "New should succeed with concurrent creators")
+		require.NoError(t, loggers[i].Close(), "Close should succeed")
+	}
+	_, err := os.Readlink(filepath.Join(dir, "a", "b", "current"))
+	require.NoError(t, err, "symlink should be created")
+	// the mill goroutines may still be linking in the meantime.
+	require.Eventually(t, func() bool {
+		tmps, _ := filepath.Glob(filepath.Join(dir, "a", "b", "*.symlink#*.*"))
+		return len(tmps) == 0
+	}, time.Second, time.Millisecond, "temporary symlinks should be renamed")
+}
+
 func Test_Stat_ErrPermission(t *testing.T) {
 	dir := filepath.Join(baseLogDir, "Test_Stat_ErrPermission")
 	defer os.RemoveAll(dir)
@@ -628,6 +917,7 @@ func Test_Stat_ErrPermission(t *testing.T) {
 		filepath.Join(dir, "app.log"),
 	)
 	require.NoError(t, err, "New should succeed")
+	defer l.Close()
 	l.Write([]byte("1"))
 	// hook l.osStat
 	l.osStat = func(string) (os.FileInfo, error) {
@@ -708,26 +998,699 @@ func Test_Write_Error(t *testing.T) {
 		filepath.Join(dir, "app.log"),
 	)
 	require.NoError(t, err, "New should succeed")
+	defer l.Close()
 
 	_, err = l.Write([]byte("1"))
 	require.NoError(t, err, "Write should succeed")
 
+	hookFile := func(f testFile) {
+		l.file.Store(newFileHandle(f, l.currentFilename(), l.currRotationTime, 0))
+	}
+
 	// hook l.file
-	oldFile := l.file
-	l.file = testFile{werr: io.ErrShortWrite}
+	oldFile := l.file.Load()
+	hookFile(testFile{werr: io.ErrShortWrite})
 	_, err = l.Write([]byte("1"))
 	require.Equal(t, true, errors.Is(err, io.ErrShortWrite), "Should return error: io.ErrShortWrite")
 
 	// hook l.file
-	l.file = testFile{werr: syscall.ENOSPC} // No space left on device
+	hookFile(testFile{werr: syscall.ENOSPC}) // No space left on device
 	_, err = l.Write([]byte("1"))
 	require.Equal(t, true, errors.Is(err, syscall.ENOSPC), "Should return error: syscall.ENOSPC")
 
 	// hook l.file
-	l.file = testFile{werr: io.ErrShortWrite, cerr: fs.ErrClosed}
+	hookFile(testFile{werr: io.ErrShortWrite, cerr: fs.ErrClosed})
 	_, err = l.Write([]byte("1"))
 	require.Equal(t, true, errors.Is(err, fs.ErrClosed), "Should return error: fs.ErrClosed")
 
 	// restored
-	l.file = oldFile
+	l.file.Store(oldFile)
+}
+
+// shortFile is a fake file whose writes fail with ENOSPC after limit bytes
+// written to the underlying file.
+type shortFile struct {
+	*os.File
+	limit int
+}
+
+func (f shortFile) Write(b []byte) (int, error) {
+	if len(b) <= f.limit {
+		return f.File.Write(b)
+	}
+	n, _ := f.File.Write(b[:f.limit])
+	return n, syscall.ENOSPC
+}
+
+func Test_PartialWriteRecovery(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PartialWriteRecovery")
+	defer os.RemoveAll(dir)
+
+	for _, enable := range []bool{false, true} {
+		enable := enable
+		t.Run(fmt.Sprintf("enable %v", enable), func(t *testing.T) {
+			filename := filepath.Join(dir, fmt.Sprintf("app-%v.log", enable))
+			var reported []error
+			l, err := New(
+				filename,
+				WithPartialWriteRecovery(enable),
+				WithErrorHandler(func(err error) {
+					reported = append(reported, err)
+				}),
+			)
+			require.NoError(t, err, "New should succeed")
+			defer l.Close()
+
+			_, err = l.Write([]byte("1\n"))
+			require.NoError(t, err, "Write should succeed")
+
+			// hook l.file
+			f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+			require.NoError(t, err, "OpenFile should succeed")
+			oldFile := l.file.Load()
+			l.file.Store(newFileHandle(shortFile{File: f, limit: 5}, l.currentFilename(), l.currRotationTime, 2))
+			require.NoError(t, oldFile.Close(), "Close should succeed")
+
+			b := []byte("hello, world\n")
+			n, err := l.Write(b)
+			content, rerr := os.ReadFile(filename)
+			require.NoError(t, rerr, "ReadFile should succeed")
+			if !enable {
+				require.ErrorIs(t, err, syscall.ENOSPC, "partial write should fail")
+				require.Equal(t, 5, n, "Write length should be the bytes written")
+				require.Equal(t, "1\nhello", string(content), "file should have the head only")
+				return
+			}
+			require.NoError(t, err, "partial write should be recovered")
+			require.Equal(t, len(b), n, "Write length should be the full length")
+			require.Equal(t, "1\nhello, world\n", string(content), "remainder should be written to the reopened file")
+			require.Equal(t, 1, len(reported), "recovered error should be reported")
+			require.ErrorIs(t, reported[0], ErrPartialWrite, "Should report ErrPartialWrite")
+			require.ErrorIs(t, reported[0], syscall.ENOSPC, "Should report syscall.ENOSPC")
+		})
+	}
+}
+
+func Test_WriteV(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WriteV")
+	defer os.RemoveAll(dir)
+
+	for _, size := range []int{0, 10} {
+		size := size
+		t.Run(fmt.Sprintf("WriteChan %d", size), func(t *testing.T) {
+			filename := filepath.Join(dir, fmt.Sprintf("app-%d.log", size))
+			l, err := New(
+				filename,
+				WithMaxSize(12),
+				WithWriteChan(size),
+			)
+			require.NoError(t, err, "New should succeed")
+
+			n, err := l.WriteV([]byte("Hello"), []byte(", "), []byte("World"))
+			require.NoError(t, err, "WriteV should succeed")
+			require.Equal(t, 12, n, "WriteV length should match")
+			// total length is over MaxSize, so it should be rotated as a whole.
+			n, err = l.WriteV([]byte("foo"), []byte("bar"))
+			require.NoError(t, err, "WriteV should succeed")
+			require.Equal(t, 6, n, "WriteV length should match")
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, l.Close(), "Close should succeed")
+
+			content, err := os.ReadFile(filename)
+			require.NoError(t, err, "ReadFile should succeed")
+			require.Equal(t, "Hello, World", string(content), "first entry should not be split")
+			content, err = os.ReadFile(filename + ".1")
+			require.NoError(t, err, "ReadFile should succeed")
+			require.Equal(t, "foobar", string(content), "second entry should be rotated as a whole")
+		})
+	}
+}
+
+func Test_WriteBatch(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WriteBatch")
+	defer os.RemoveAll(dir)
+
+	for _, size := range []int{0, 10} {
+		size := size
+		t.Run(fmt.Sprintf("WriteChan %d", size), func(t *testing.T) {
+			filename := filepath.Join(dir, fmt.Sprintf("app-%d.log", size))
+			l, err := New(
+				filename,
+				WithMaxSize(12),
+				WithWriteChan(size),
+				WithRedactors(NewRegexpRedactor(regexp.MustCompile(`secret`), "***")),
+			)
+			require.NoError(t, err, "New should succeed")
+
+			n, err := l.WriteBatch([][]byte{[]byte("a\n"), []byte("secret\n")})
+			require.NoError(t, err, "WriteBatch should succeed")
+			require.Equal(t, 9, n, "WriteBatch should return the original length")
+			// total length is over MaxSize, so it should be rotated as a whole.
+			n, err = l.WriteBatch([][]byte{[]byte("bb\n"), []byte("c\n"), []byte("d\n")})
+			require.NoError(t, err, "WriteBatch should succeed")
+			require.Equal(t, 7, n, "WriteBatch length should match")
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, l.Close(), "Close should succeed")
+
+			content, err := os.ReadFile(filename)
+			require.NoError(t, err, "ReadFile should succeed")
+			require.Equal(t, "a\n***\n", string(content), "each entry should be redacted")
+			content, err = os.ReadFile(filename + ".1")
+			require.NoError(t, err, "ReadFile should succeed")
+			require.Equal(t, "bb\nc\nd\n", string(content), "batch should be rotated as a whole")
+		})
+	}
+}
+
+func Test_ReadOnlyFilesystem(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_ReadOnlyFilesystem")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClock()
+	var fallback strings.Builder
+	var reported []error
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithClock(clock),
+		WithFallbackWriter(&fallback),
+		WithErrorHandler(func(err error) {
+			reported = append(reported, err)
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+
+	// hook l.file to simulate the filesystem turning read-only
+	l.file.Store(newFileHandle(testFile{werr: syscall.EROFS}, l.currentFilename(), l.currRotationTime, 1))
+	n, err := l.Write([]byte("2"))
+	require.NoError(t, err, "Write should fall back")
+	require.Equal(t, 1, n, "Write length should match")
+	require.Equal(t, 1, len(reported), "read-only error should be reported once")
+	require.True(t, errors.Is(reported[0], ErrReadOnly), "Should report ErrReadOnly")
+	require.True(t, errors.Is(reported[0], syscall.EROFS), "Should report syscall.EROFS")
+
+	// no probe before backoff
+	_, err = l.Write([]byte("3"))
+	require.NoError(t, err, "Write should fall back")
+	require.Equal(t, "23", fallback.String(), "Writes should go to fallback writer")
+
+	// probe for recovery after backoff
+	clock.Advance(minProbeBackoff)
+	_, err = l.Write([]byte("4"))
+	require.NoError(t, err, "Write should succeed after recovered")
+	require.Equal(t, "23", fallback.String(), "Write should not go to fallback writer after recovered")
+	require.Equal(t, 1, len(reported), "read-only error should be reported once")
+
+	content, err := os.ReadFile(l.currentFilename())
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "14", string(content), "file content should match")
+}
+
+func Test_OpenBackoff(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_OpenBackoff")
+	defer os.RemoveAll(dir)
+
+	// a regular file in place of the directory, so opening keeps failing.
+	blocker := filepath.Join(dir, "sub")
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	require.NoError(t, os.WriteFile(blocker, nil, 0644), "WriteFile should succeed")
+
+	clock := clockwork.NewFakeClock()
+	var reported []error
+	l, err := New(
+		filepath.Join(blocker, "app.log"),
+		WithClock(clock),
+		WithErrorHandler(func(err error) {
+			reported = append(reported, err)
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("1"))
+	require.Error(t, err, "Write should fail to open")
+	require.False(t, errors.Is(err, ErrOpenBackoff), "first failure should not be backed off")
+	require.Len(t, reported, 1, "open error should be reported once")
+	require.ErrorIs(t, reported[0], ErrOpenBackoff, "Should report ErrOpenBackoff")
+
+	// no reopen before backoff, even if it would succeed now.
+	require.NoError(t, os.Remove(blocker), "Remove should succeed")
+	_, err = l.Write([]byte("2"))
+	require.ErrorIs(t, err, ErrOpenBackoff, "Write should be backed off")
+	require.NoFileExists(t, filepath.Join(blocker, "app.log"), "file should not be reopened")
+
+	// reopen after backoff, with jitter up to the backoff.
+	clock.Advance(minProbeBackoff)
+	_, err = l.Write([]byte("3"))
+	require.NoError(t, err, "Write should succeed after backoff")
+	require.Len(t, reported, 1, "open error should be reported once")
+
+	content, err := os.ReadFile(filepath.Join(blocker, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "3", string(content), "file content should match")
+}
+
+func Test_ErrClosed(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_ErrClosed")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "New should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	require.ErrorIs(t, l.Close(), ErrClosed, "Close twice should return ErrClosed")
+	_, err = l.Write([]byte("1"))
+	require.ErrorIs(t, err, ErrClosed, "Write should return ErrClosed")
+	_, err = l.WriteV([]byte("1"), []byte("2"))
+	require.ErrorIs(t, err, ErrClosed, "WriteV should return ErrClosed")
+	require.ErrorIs(t, l.Rotate(), ErrClosed, "Rotate should return ErrClosed")
+
+	metrics := l.Metrics()
+	require.Equal(t, uint64(2), metrics.DiscardedClosed, "writes after Close should be counted")
+	require.Equal(t, uint64(3), metrics.DiscardedBytes, "discarded bytes should be counted")
+	require.Equal(t, uint64(0), metrics.DiscardedQueueFull, "nothing should be discarded as queue full")
+}
+
+func Test_Close_Concurrent(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Close_Concurrent")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(100))
+	require.NoError(t, err, "New should succeed")
+	require.False(t, l.IsClosed(), "Logger should not be closed")
+	for i := 0; i < 10; i++ {
+		_, err = l.Write([]byte("1"))
+		require.NoError(t, err, "Write should succeed")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = l.Close()
+		}(i)
+	}
+	wg.Wait()
+	closed := 0
+	for _, err := range errs {
+		if err == nil {
+			closed++
+		} else {
+			require.ErrorIs(t, err, ErrClosed, "other Close calls should return ErrClosed")
+		}
+	}
+	require.Equal(t, 1, closed, "only the first Close should close the Logger")
+	require.True(t, l.IsClosed(), "Logger should be closed")
+
+	// writeLoop has quitted, so the queued writes are all written.
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "1111111111", string(content), "queued writes should be written on Close")
+}
+
+func Test_ErrDiscarded(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_ErrDiscarded")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"), WithWriteChan(1))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	// hold l.mu, so writeLoop is blocked on writing the first line.
+	l.mu.Lock()
+	discarded := false
+	for i := 0; i < 10 && !discarded; i++ {
+		_, err = l.Write([]byte("1"))
+		discarded = errors.Is(err, ErrDiscarded)
+	}
+	l.mu.Unlock()
+	require.True(t, discarded, "Write should return ErrDiscarded if writeCh is full")
+
+	metrics := l.Metrics()
+	require.Equal(t, uint64(1), metrics.DiscardedQueueFull, "discard should be counted as queue full")
+	require.Equal(t, uint64(1), metrics.DiscardedBytes, "discarded bytes should be counted")
+	require.Equal(t, metrics.Discards, metrics.DiscardedEntries, "Discards should be the total")
+}
+
+func Test_RotationError(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotationError")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	l.osStat = func(string) (os.FileInfo, error) {
+		return nil, fs.ErrPermission
+	}
+	_, err = l.Write([]byte("1"))
+	var rerr *RotationError
+	require.True(t, errors.As(err, &rerr), "Write should return RotationError")
+	require.Equal(t, "stat", rerr.Op, "Op should match")
+	require.Equal(t, filepath.Join(dir, "app.log"), rerr.Path, "Path should match")
+	require.ErrorIs(t, err, fs.ErrPermission, "RotationError should unwrap to the cause")
+}
+
+func Test_TryWrite(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_TryWrite")
+	defer os.RemoveAll(dir)
+
+	t.Run("Unbuffered", func(t *testing.T) {
+		l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(2))
+		require.NoError(t, err, "New should succeed")
+		defer l.Close()
+
+		n, err := l.TryWrite([]byte("1"))
+		require.NoError(t, err, "TryWrite should succeed")
+		require.Equal(t, 1, n, "TryWrite should write all bytes")
+
+		// hold l.mu as a rotation in progress, and the write needs to
+		// rotate, so it can't take the fast path.
+		l.mu.Lock()
+		_, err = l.TryWrite([]byte("23"))
+		l.mu.Unlock()
+		require.ErrorIs(t, err, ErrWouldBlock, "TryWrite should not block on contended lock")
+
+		_, err = l.TryWrite([]byte("23"))
+		require.NoError(t, err, "TryWrite should succeed after lock released")
+	})
+
+	t.Run("Buffered", func(t *testing.T) {
+		l, err := New(filepath.Join(dir, "buffered.log"), WithWriteChan(1))
+		require.NoError(t, err, "New should succeed")
+		defer l.Close()
+
+		// hold l.mu, so writeLoop is blocked on writing the first line.
+		l.mu.Lock()
+		wouldBlock := false
+		for i := 0; i < 10 && !wouldBlock; i++ {
+			_, err = l.TryWrite([]byte("1"))
+			wouldBlock = errors.Is(err, ErrWouldBlock)
+		}
+		l.mu.Unlock()
+		require.True(t, wouldBlock, "TryWrite should return ErrWouldBlock if writeCh is full")
+		require.Equal(t, uint64(0), l.Metrics().Discards, "TryWrite should not count discards")
+	})
+}
+
+func Test_SizeReconcileInterval(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SizeReconcileInterval")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(
+		filename,
+		WithMaxSize(10),
+		WithSizeReconcileInterval(time.Nanosecond),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("12345"))
+	require.NoError(t, err, "Write should succeed")
+
+	// another process appends to the same file.
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err, "OpenFile should succeed")
+	_, err = f.Write([]byte("123456"))
+	require.NoError(t, err, "external Write should succeed")
+	require.NoError(t, f.Close(), "Close should succeed")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, filename+".1", l.currentFilename(), "file should be rotated as the real size is over MaxSize")
+}
+
+func Test_MaxLines(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MaxLines")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithMaxLines(2))
+	require.NoError(t, err, "New should succeed")
+	for i := 0; i < 3; i++ {
+		_, err = l.Write([]byte("line\n"))
+		require.NoError(t, err, "Write should succeed")
+	}
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	// resume: the existing file already has 2 lines, so it is rotated.
+	l, err = New(filename, WithMaxLines(2))
+	require.NoError(t, err, "New should succeed")
+	_, err = l.Write([]byte("line\nline\n"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	for path, want := range map[string]string{
+		filename:        "line\nline\n",
+		filename + ".1": "line\n",
+		filename + ".2": "line\nline\n",
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Equal(t, want, string(data), "content of %s should match", path)
+	}
+}
+
+func Test_RotateHeadroom(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotateHeadroom")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	_, err := New(filename, WithMaxSize(10), WithRotateHeadroom(10))
+	require.Error(t, err, "New should fail if headroom is not less than MaxSize")
+
+	l, err := New(filename, WithMaxSize(10), WithRotateHeadroom(4))
+	require.NoError(t, err, "New should succeed")
+	require.Equal(t, 4, l.Options().RotateHeadroom, "RotateHeadroom should match")
+	for _, line := range []string{"aaa", "bbb", "cc", "d"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	// rotated before the file goes over 6 bytes, rather than 10.
+	for path, want := range map[string]string{
+		filename:        "aaabbb",
+		filename + ".1": "ccd",
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Equal(t, want, string(data), "content of %s should match", path)
+	}
+}
+
+func Test_RotatePredicate(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotatePredicate")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(
+		filename,
+		WithRotatePredicate(func(stats FileStats, b []byte) bool {
+			// rotate on epoch changes, unless the file is empty.
+			return stats.Size > 0 && strings.HasPrefix(string(b), "EPOCH")
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	for _, line := range []string{"a\n", "EPOCH 2\n", "b\n"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "a\n", string(data), "content before rotation should match")
+	data, err = os.ReadFile(filename + ".1")
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "EPOCH 2\nb\n", string(data), "content after rotation should match")
+}
+
+func Test_RotateOrderedWithBufferedWrites(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotateOrderedWithBufferedWrites")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(100))
+	require.NoError(t, err, "New should succeed")
+
+	for i := 0; i < 10; i++ {
+		_, err = l.Write([]byte("a"))
+		require.NoError(t, err, "Write should succeed")
+	}
+	done := l.RotateAsync()
+	_, err = l.Write([]byte("b"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, <-done, "RotateAsync should succeed")
+	_, ok := <-done
+	require.False(t, ok, "channel should be closed after rotation")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.ErrorIs(t, <-l.RotateAsync(), ErrClosed, "RotateAsync should return ErrClosed after Close")
+
+	for path, want := range map[string]string{
+		filename:        "aaaaaaaaaa",
+		filename + ".1": "b",
+		filename + ".2": "",
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Equal(t, want, string(data), "content of %s should match", path)
+	}
+}
+
+func Test_Options(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Options")
+	defer os.RemoveAll(dir)
+
+	pattern := filepath.Join(dir, "app.%Y%m%d.log")
+	l, err := New(
+		pattern,
+		WithMaxSize(1024),
+		WithMaxAge(time.Hour),
+		WithMillConcurrency(0),
+		WithHashSuffix(100),
+		WithErrorHandler(func(error) {}),
+		WithPostRotateProcessors(NewGzipProcessor()),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	opts := l.Options()
+	require.Equal(t, pattern, opts.Pattern, "Pattern should match")
+	require.Equal(t, 1024, opts.MaxSize, "MaxSize should match")
+	require.Equal(t, time.Hour, opts.MaxAge, "MaxAge should match")
+	require.Equal(t, 24*time.Hour, opts.MaxInterval, "MaxInterval should be default")
+	require.Equal(t, 1, opts.MillConcurrency, "MillConcurrency should be normalized")
+	require.Equal(t, 64, opts.HashSuffix, "HashSuffix should be normalized")
+	require.True(t, opts.HasErrorHandler, "HasErrorHandler should be set")
+	require.False(t, opts.HasFallbackWriter, "HasFallbackWriter should not be set")
+	require.Equal(t, 1, opts.PostRotateProcessors, "PostRotateProcessors should be counted")
+}
+
+func Test_New_OptionError(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_New_OptionError")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithMaxSizeString("10MiBB"))
+	require.Error(t, err, "New should fail with invalid option")
+
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSizeString("10MiB"))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, 10<<20, l.Options().MaxSize, "MaxSize should be parsed")
+}
+
+func Test_CreateOnNew(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_CreateOnNew")
+	defer os.RemoveAll(dir)
+
+	symlink := filepath.Join(dir, "current.log")
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithSymlink(symlink),
+		WithCreateOnNew(true),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	require.FileExists(t, filepath.Join(dir, "app.log"), "file should be created on New")
+	target, err := os.Readlink(symlink)
+	require.NoError(t, err, "symlink should be set up on New")
+	require.Equal(t, "app.log", target, "symlink should link to the current file")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	data, err := os.ReadFile(symlink)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "1", string(data), "Write should write to the created file")
+}
+
+func Test_Durability(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Durability")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithDurability(Durability(100)))
+	require.Error(t, err, "New should fail with invalid durability")
+
+	l, err := New(filepath.Join(dir, "app.log"), WithDurability(DurabilityDSync))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, DurabilityDSync, l.Options().Durability, "Durability should match")
+
+	_, err = l.Write([]byte("Hello"))
+	require.NoError(t, err, "Write should succeed")
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "Hello", string(data), "content should match")
+}
+
+func Test_OldestQueuedAge(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_OldestQueuedAge")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Now())
+	l, err := New(filepath.Join(dir, "app.log"), WithClock(clock), WithWriteChan(10))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Zero(t, l.Metrics().OldestQueuedAge, "OldestQueuedAge should be 0 if nothing queued")
+
+	// block writeLoop, so the queued entries fall behind.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+	_, err = l.Write([]byte("Hello"))
+	require.NoError(t, err, "Write should succeed")
+
+	clock.Advance(time.Minute)
+	require.Equal(t, time.Minute, l.Metrics().OldestQueuedAge, "OldestQueuedAge should grow while falling behind")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return l.Metrics().OldestQueuedAge == 0
+	}, time.Second, time.Millisecond, "OldestQueuedAge should be 0 after the queue drained")
+}
+
+func Test_AccessFile(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_AccessFile")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithMaxSize(10))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	err = l.AccessFile(func(f *os.File) error {
+		require.Equal(t, filename, f.Name(), "current file should be opened")
+		_, err := f.Write([]byte("12345678"))
+		return err
+	})
+	require.NoError(t, err, "AccessFile should succeed")
+
+	// the size is reconciled, so the write over MaxSize rotates the file.
+	_, err = l.Write([]byte("abc"))
+	require.NoError(t, err, "Write should succeed")
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "12345678", string(data), "content written by fn should be kept")
+	data, err = os.ReadFile(filename + ".1")
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "abc", string(data), "Write should go to the rotated file")
+
+	fnErr := errors.New("fn failed")
+	require.ErrorIs(t, l.AccessFile(func(*os.File) error { return fnErr }), fnErr, "error of fn should be returned")
+
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.ErrorIs(t, l.AccessFile(func(*os.File) error { return nil }), ErrClosed, "AccessFile should return ErrClosed")
 }
@@ -0,0 +1,16 @@
+module github.com/gounknown/logrotate/logrushook
+
+go 1.20
+
+require (
+	github.com/gounknown/logrotate v0.0.0-20240405083505-0c6d6d14f42e
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require (
+	github.com/lestrrat-go/strftime v1.0.6 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
+
+replace github.com/gounknown/logrotate => ../
@@ -0,0 +1,70 @@
+// Package logrushook provides a logrus hook which writes log entries to
+// rotating log files by logrotate.Logger:
+//
+//	hook, err := logrushook.New("/var/log/app.%Y%m%d.log", logrotate.WithMaxAge(720*time.Hour))
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer hook.Close()
+//	logrus.AddHook(hook)
+package logrushook
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/gounknown/logrotate"
+)
+
+// ensure we always implement logrus.Hook
+var _ logrus.Hook = (*Hook)(nil)
+
+// Hook is a logrus.Hook which writes log entries to rotated log files.
+type Hook struct {
+	*logrotate.Logger
+
+	// LogLevels are the levels the hook fires on. If empty, it fires on
+	// all levels.
+	LogLevels []logrus.Level
+
+	// Formatter formats log entries. If nil, the formatter of the logrus
+	// logger which fired the entry is used.
+	Formatter logrus.Formatter
+}
+
+// New creates a Hook with the provided filename pattern and options.
+func New(pattern string, options ...logrotate.Option) (*Hook, error) {
+	l, err := logrotate.New(pattern, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Hook{Logger: l}, nil
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	if len(h.LogLevels) == 0 {
+		return logrus.AllLevels
+	}
+	return h.LogLevels
+}
+
+// Fire implements logrus.Hook. As logrus exits right after firing a fatal
+// entry, the Logger is closed on FatalLevel to flush the buffered log lines
+// (see logrotate.WithWriteChan) to files.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	var line []byte
+	var err error
+	if h.Formatter != nil {
+		line, err = h.Formatter.Format(entry)
+	} else {
+		line, err = entry.Bytes()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = h.Write(line)
+	if entry.Level == logrus.FatalLevel {
+		_ = h.Close()
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package logrushook
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/gounknown/logrotate"
+)
+
+type formatterFunc func(entry *logrus.Entry) ([]byte, error)
+
+func (f formatterFunc) Format(entry *logrus.Entry) ([]byte, error) {
+	return f(entry)
+}
+
+func readFile(t *testing.T, filename string) string {
+	t.Helper()
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	return string(b)
+}
+
+func Test_Fire(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	hook, err := New(filename)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer hook.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	logger.AddHook(hook)
+
+	// formatted by the formatter of the logrus logger.
+	logger.Info("hello")
+	if got, want := readFile(t, filename), "level=info msg=hello\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	hook.Formatter = formatterFunc(func(entry *logrus.Entry) ([]byte, error) {
+		return []byte(entry.Level.String() + ": " + entry.Message + "\n"), nil
+	})
+	logger.Warn("world")
+	if got, want := readFile(t, filename), "level=info msg=hello\nwarning: world\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func Test_Levels(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	hook, err := New(filename)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer hook.Close()
+	if got := hook.Levels(); !reflect.DeepEqual(got, logrus.AllLevels) {
+		t.Errorf("Levels() = %v, want %v", got, logrus.AllLevels)
+	}
+
+	hook.LogLevels = []logrus.Level{logrus.ErrorLevel}
+	if got := hook.Levels(); !reflect.DeepEqual(got, hook.LogLevels) {
+		t.Errorf("Levels() = %v, want %v", got, hook.LogLevels)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	logger.AddHook(hook)
+	logger.Info("skipped")
+	logger.Error("fired")
+	if got, want := readFile(t, filename), "level=error msg=fired\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func Test_Fire_Fatal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	hook, err := New(filename, logrotate.WithWriteChan(10))
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer hook.Close()
+	hook.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+
+	// logrus calls os.Exit after firing a fatal entry, so call Fire directly.
+	logger := logrus.New()
+	for _, level := range []logrus.Level{logrus.InfoLevel, logrus.FatalLevel} {
+		entry := logrus.NewEntry(logger)
+		entry.Level, entry.Message = level, level.String()
+		if err := hook.Fire(entry); err != nil {
+			t.Errorf("Fire(%v) error: %v", level, err)
+		}
+	}
+	if _, err := hook.Write([]byte("after fatal\n")); !errors.Is(err, logrotate.ErrClosed) {
+		t.Errorf("Write after fatal error = %v, want %v", err, logrotate.ErrClosed)
+	}
+	// the buffered lines are flushed by Close.
+	if got, want := readFile(t, filename), "level=info msg=info\nlevel=fatal msg=fatal\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
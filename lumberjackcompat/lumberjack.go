@@ -0,0 +1,155 @@
+// Package lumberjackcompat provides a drop-in replacement of
+// lumberjack.Logger (gopkg.in/natefinch/lumberjack.v2) backed by logrotate,
+// easing migration from lumberjack:
+//
+//	log.SetOutput(&lumberjackcompat.Logger{
+//		Filename:   "/var/log/myapp/foo.log",
+//		MaxSize:    500, // megabytes
+//		MaxBackups: 3,
+//		MaxAge:     28, // days
+//		Compress:   true,
+//	})
+//
+// NOTE: as lumberjack, Filename is always the current file, but old files
+// are numbered in savelog-style (see logrotate.WithSavelog), e.g.:
+// "foo.log.0", "foo.log.1.gz", rather than named by timestamps, and the
+// newest one is never compressed.
+package lumberjackcompat
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gounknown/logrotate"
+)
+
+// ensure we always implement io.WriteCloser
+var _ io.WriteCloser = (*Logger)(nil)
+
+const (
+	megabyte       = 1024 * 1024
+	defaultMaxSize = 100 // megabytes
+
+	// maxCycle is the savelog cycle used to retain all old log files.
+	maxCycle = 1000
+)
+
+// Logger mirrors the fields of lumberjack.Logger. The underlying
+// logrotate.Logger is created on the first Write with the fields, so
+// changing them after that takes no effect until Close.
+type Logger struct {
+	// Filename is the file to write logs to. It uses
+	// <processname>-lumberjack.log in os.TempDir() if empty.
+	Filename string `json:"filename" yaml:"filename"`
+
+	// MaxSize is the maximum size in megabytes of the log file before it
+	// gets rotated. It defaults to 100 megabytes.
+	MaxSize int `json:"maxsize" yaml:"maxsize"`
+
+	// MaxAge is the maximum number of days to retain old log files. The
+	// default is not to remove old log files based on age.
+	MaxAge int `json:"maxage" yaml:"maxage"`
+
+	// MaxBackups is the maximum number of old log files to retain. The
+	// default is to retain all old log files (though MaxAge may still cause
+	// them to get deleted), up to 1000.
+	MaxBackups int `json:"maxbackups" yaml:"maxbackups"`
+
+	// LocalTime is accepted for compatibility. It makes no difference, as
+	// old log files are numbered rather than named by timestamps.
+	LocalTime bool `json:"localtime" yaml:"localtime"`
+
+	// Compress determines if the rotated log files should be compressed
+	// using gzip. The default is not to perform compression.
+	Compress bool `json:"compress" yaml:"compress"`
+
+	mu sync.Mutex
+	l  *logrotate.Logger
+}
+
+// Write implements io.Writer. It creates the underlying logrotate.Logger on
+// the first call.
+func (l *Logger) Write(p []byte) (n int, err error) {
+	rl, err := l.logger()
+	if err != nil {
+		return 0, err
+	}
+	return rl.Write(p)
+}
+
+// Close implements io.Closer, and closes the current logfile. A later
+// Write reopens it.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.l == nil {
+		return nil
+	}
+	err := l.l.Close()
+	l.l = nil
+	return err
+}
+
+// Rotate causes Logger to close the existing log file and immediately
+// create a new one.
+func (l *Logger) Rotate() error {
+	rl, err := l.logger()
+	if err != nil {
+		return err
+	}
+	return rl.Rotate()
+}
+
+// logger returns the underlying logrotate.Logger, creating it if not yet.
+func (l *Logger) logger() (*logrotate.Logger, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.l != nil {
+		return l.l, nil
+	}
+	rl, err := logrotate.New(escapePattern(l.filename()), l.options()...)
+	if err != nil {
+		return nil, err
+	}
+	l.l = rl
+	return rl, nil
+}
+
+// options converts the lumberjack fields to logrotate options.
+func (l *Logger) options() []logrotate.Option {
+	maxSize := l.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	options := []logrotate.Option{
+		logrotate.WithMaxSize(maxSize * megabyte),
+		logrotate.WithMaxInterval(0), // rotate by size only
+	}
+	if l.MaxAge > 0 {
+		options = append(options, logrotate.WithMaxAge(time.Duration(l.MaxAge)*24*time.Hour))
+	}
+	cycle := l.MaxBackups
+	if cycle <= 0 || cycle > maxCycle {
+		cycle = maxCycle
+	}
+	// keep Filename as the current file.
+	return append(options, logrotate.WithSavelog(cycle, l.Compress))
+}
+
+func (l *Logger) filename() string {
+	if l.Filename != "" {
+		return l.Filename
+	}
+	name := filepath.Base(os.Args[0]) + "-lumberjack.log"
+	return filepath.Join(os.TempDir(), name)
+}
+
+// escapePattern escapes "%" in filename, as logrotate takes a strftime
+// pattern.
+func escapePattern(filename string) string {
+	return strings.ReplaceAll(filename, "%", "%%")
+}
@@ -0,0 +1,47 @@
+package lumberjackcompat
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Logger(t *testing.T) {
+	dir := filepath.Join("_testlogs", "Test_Logger")
+	defer os.RemoveAll("_testlogs")
+
+	filename := filepath.Join(dir, "foo.log")
+	l := &Logger{
+		Filename:   filename,
+		MaxSize:    1, // megabytes
+		MaxBackups: 2,
+		Compress:   true,
+	}
+	for _, c := range []byte("1234") {
+		_, err := l.Write(bytes.Repeat([]byte{c}, 600*1024))
+		require.NoError(t, err, "Write should succeed")
+	}
+
+	require.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filename + "*")
+		return len(files) == 3
+	}, time.Second, 10*time.Millisecond, "old files should be compressed and purged")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, byte('4'), data[0], "Filename should be the current file")
+	data, err = os.ReadFile(filename + ".0")
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, byte('3'), data[0], "the newest old file should not be compressed")
+	require.FileExists(t, filename+".1.gz", "older file should be compressed")
+
+	// reopened by Write after Close
+	_, err = l.Write([]byte("5"))
+	require.NoError(t, err, "Write should succeed after Close")
+	require.NoError(t, l.Close(), "Close should succeed")
+}
@@ -0,0 +1,265 @@
+package logrotate
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// patternField is a time field captured from a filename.
+type patternField int
+
+const (
+	fieldNone patternField = iota
+	fieldYear
+	fieldYear2 // 2-digit year
+	fieldCentury
+	fieldMonth
+	fieldMonthName
+	fieldDay
+	fieldYearDay
+	fieldHour
+	fieldHour12
+	fieldAMPM
+	fieldMinute
+	fieldSecond
+	fieldFraction
+	fieldZone
+)
+
+// patternVerbs maps the strftime verbs to the regexp matching the formatted
+// text, and the time field captured.
+var patternVerbs = map[byte]struct {
+	re    string
+	field patternField
+}{
+	'A': {`[A-Za-z]+`, fieldNone},
+	'a': {`[A-Za-z]+`, fieldNone},
+	'B': {`[A-Za-z]+`, fieldMonthName},
+	'b': {`[A-Za-z]{3}`, fieldMonthName},
+	'h': {`[A-Za-z]{3}`, fieldMonthName},
+	'C': {`\d{2}`, fieldCentury},
+	'd': {`\d{2}`, fieldDay},
+	'e': {`[ \d]\d`, fieldDay},
+	'H': {`\d{2}`, fieldHour},
+	'I': {`\d{2}`, fieldHour12},
+	'j': {`\d{3}`, fieldYearDay},
+	'k': {`[ \d]\d`, fieldHour},
+	'l': {`[ \d]\d`, fieldHour12},
+	'M': {`\d{2}`, fieldMinute},
+	'm': {`\d{2}`, fieldMonth},
+	'n': {`\n`, fieldNone},
+	'p': {`AM|PM`, fieldAMPM},
+	'S': {`\d{2}`, fieldSecond},
+	't': {`\t`, fieldNone},
+	'U': {`\d{2}`, fieldNone},
+	'u': {`\d`, fieldNone},
+	'V': {`\d{2}`, fieldNone},
+	'W': {`\d{2}`, fieldNone},
+	'w': {`\d`, fieldNone},
+	'Y': {`\d{4}`, fieldYear},
+	'y': {`\d{2}`, fieldYear2},
+	'Z': {`[A-Za-z0-9+-]+`, fieldNone},
+	'z': {`[+-]\d{4}`, fieldZone},
+	'L': {`\d{3}`, fieldFraction},
+	'f': {`\d{6}`, fieldFraction},
+	'N': {`\d{9}`, fieldFraction},
+	'%': {`%`, fieldNone},
+}
+
+// patternComposites maps the composite strftime verbs to their expansions.
+var patternComposites = map[byte]string{
+	'c': "%a %b %e %H:%M:%S %Y",
+	'D': "%m/%d/%y",
+	'F': "%Y-%m-%d",
+	'R': "%H:%M",
+	'r': "%I:%M:%S %p",
+	'T': "%H:%M:%S",
+	'v': "%e-%b-%Y",
+	'X': "%H:%M:%S",
+	'x': "%m/%d/%y",
+}
+
+// MatchPattern reports whether the filename was generated by the strftime
+// pattern, optionally with a sequence suffix, e.g.: "app.20240101.log.2",
+// and returns the time parsed from the filename in the local time zone,
+// and the sequence. Fields not in the pattern are their zero values, e.g.:
+// the time is midnight for a daily pattern, and the zero Time if the pattern
+// has no time fields. The filename is matched against the whole pattern, so
+// both should be paths, or base names.
+func MatchPattern(pattern, filename string) (ts time.Time, seq int, ok bool) {
+	var err error
+	re, fields := compilePattern(pattern, defaultSequenceSuffix)
+	m := re.FindStringSubmatch(filename)
+	if m == nil {
+		return time.Time{}, 0, false
+	}
+	if s := m[len(m)-1]; s != "" {
+		if seq, err = strconv.Atoi(s); err != nil {
+			return time.Time{}, 0, false
+		}
+	}
+	return parsePatternTime(fields, m[1:len(m)-1]), seq, true
+}
+
+// timeParser parses the times of log files from their filenames generated
+// by a pattern, which order them ahead of FileInfo.Time, see byModTime.
+type timeParser struct {
+	re     *regexp.Regexp
+	fields []patternField
+}
+
+// newTimeParser returns the timeParser of the strftime pattern with a
+// sequence suffix in seqSuffix format, or nil if the pattern has no time
+// fields.
+func newTimeParser(pattern, seqSuffix string) *timeParser {
+	re, fields := compilePattern(pattern, seqSuffix)
+	if len(fields) == 0 {
+		return nil
+	}
+	return &timeParser{re: re, fields: fields}
+}
+
+// parse returns the time and the sequence parsed from path, with an
+// optional extension added by processors, e.g.: ".gz", and false if path is
+// not matched, e.g.: moved by the RotateHook. It returns false on a nil
+// timeParser.
+func (p *timeParser) parse(path string) (ts time.Time, seq int, ok bool) {
+	if p == nil {
+		return time.Time{}, 0, false
+	}
+	m := p.re.FindStringSubmatch(path)
+	if m == nil {
+		if m = p.re.FindStringSubmatch(strings.TrimSuffix(path, filepath.Ext(path))); m == nil {
+			return time.Time{}, 0, false
+		}
+	}
+	if s := m[len(m)-1]; s != "" {
+		seq, _ = strconv.Atoi(s)
+	}
+	return parsePatternTime(p.fields, m[1:len(m)-1]), seq, true
+}
+
+// stamp sets the time and the sequence of f parsed from its path, if
+// matched.
+func (p *timeParser) stamp(f *FileInfo) {
+	if ts, seq, ok := p.parse(f.Path); ok {
+		f.nameTime, f.nameSeq = ts, seq
+	}
+}
+
+// compilePattern compiles the strftime pattern to a regexp matching its
+// formatted filenames with an optional sequence suffix in seqSuffix format,
+// which captures the time fields in order, and then the sequence.
+func compilePattern(pattern, seqSuffix string) (*regexp.Regexp, []patternField) {
+	var b strings.Builder
+	var fields []patternField
+	var compile func(pattern string)
+	compile = func(pattern string) {
+		for i := 0; i < len(pattern); i++ {
+			c := pattern[i]
+			if c != '%' || i+1 >= len(pattern) {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			i++
+			if pattern[i] == '{' {
+				if end := strings.IndexByte(pattern[i:], '}'); end >= 0 {
+					// template function, see WithPatternFuncs
+					b.WriteString(`(?:[^/]*)`)
+					i += end
+					continue
+				}
+			}
+			if expansion, ok := patternComposites[pattern[i]]; ok {
+				compile(expansion)
+				continue
+			}
+			v, ok := patternVerbs[pattern[i]]
+			if !ok {
+				b.WriteString(regexp.QuoteMeta(pattern[i-1 : i+1]))
+				continue
+			}
+			if v.field == fieldNone {
+				b.WriteString("(?:" + v.re + ")")
+				continue
+			}
+			b.WriteString("(" + v.re + ")")
+			fields = append(fields, v.field)
+		}
+	}
+	b.WriteString("^")
+	compile(pattern)
+	b.WriteString(`(?:` + sequenceExpr(seqSuffix) + `)?$`)
+	return regexp.MustCompile(b.String()), fields
+}
+
+// parsePatternTime returns the time of the captured values of fields.
+func parsePatternTime(fields []patternField, values []string) time.Time {
+	if len(fields) == 0 {
+		return time.Time{}
+	}
+	year, month, day := 0, time.January, 1
+	hour, min, sec, nsec := 0, 0, 0, 0
+	yearDay, century, pm, hour12 := 0, -1, false, false
+	loc := time.Local
+	for i, f := range fields {
+		s := strings.TrimSpace(values[i])
+		n, _ := strconv.Atoi(s)
+		switch f {
+		case fieldYear:
+			year = n
+		case fieldYear2:
+			// POSIX: 69-99 are 1969-1999, and 00-68 are 2000-2068.
+			if year = 2000 + n; n >= 69 {
+				year = 1900 + n
+			}
+		case fieldCentury:
+			century = n
+		case fieldMonth:
+			month = time.Month(n)
+		case fieldMonthName:
+			for m := time.January; m <= time.December; m++ {
+				if strings.HasPrefix(m.String(), s) {
+					month = m
+					break
+				}
+			}
+		case fieldDay:
+			day = n
+		case fieldYearDay:
+			yearDay = n
+		case fieldHour:
+			hour = n
+		case fieldHour12:
+			hour, hour12 = n%12, true
+		case fieldAMPM:
+			pm = s == "PM"
+		case fieldMinute:
+			min = n
+		case fieldSecond:
+			sec = n
+		case fieldFraction:
+			for i := len(s); i < 9; i++ {
+				n *= 10
+			}
+			nsec = n
+		case fieldZone:
+			if t, err := time.Parse("-0700", s); err == nil {
+				loc = t.Location()
+			}
+		}
+	}
+	if century >= 0 {
+		year = century*100 + year%100
+	}
+	if pm && hour12 {
+		hour += 12
+	}
+	if yearDay > 0 {
+		month, day = time.January, yearDay
+	}
+	return time.Date(year, month, day, hour, min, sec, nsec, loc)
+}
@@ -0,0 +1,36 @@
+package logrotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		filename string
+		ts       time.Time
+		seq      int
+		ok       bool
+	}{
+		{"/var/log/app.%Y%m%d.log", "/var/log/app.20240102.log", time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local), 0, true},
+		{"/var/log/app.%Y%m%d.log", "/var/log/app.20240102.log.3", time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local), 3, true},
+		{"/var/log/app.%Y%m%d.log", "/var/log/app.20240102.log.gz", time.Time{}, 0, false},
+		{"/var/log/app.%Y%m%d.log", "/var/log/app.backup.log", time.Time{}, 0, false},
+		{"/var/log/app.%Y%m%d.log", "/var/log/other.20240102.log", time.Time{}, 0, false},
+		{"%Y/%m/app-%F_%T.%L.log", "2024/01/app-2024-01-02_15:04:05.123.log", time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.Local), 0, true},
+		{"app.%d-%b-%y.%I%p.log", "app.02-Feb-24.03PM.log", time.Date(2024, 2, 2, 15, 0, 0, 0, time.Local), 0, true},
+		{"app.%Y%j.log", "app.2024032.log", time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), 0, true},
+		{"app.100%%.log", "app.100%.log.1", time.Time{}, 1, true},
+		{"app.log", "app.log", time.Time{}, 0, true},
+		{"app.log", "app.log.", time.Time{}, 0, false},
+	}
+	for _, tt := range tests {
+		ts, seq, ok := MatchPattern(tt.pattern, tt.filename)
+		require.Equal(t, tt.ok, ok, "ok of %s should match", tt.filename)
+		require.True(t, tt.ts.Equal(ts), "ts of %s should match, got %v", tt.filename, ts)
+		require.Equal(t, tt.seq, seq, "seq of %s should match", tt.filename)
+	}
+}
@@ -0,0 +1,31 @@
+package logrotate
+
+// mirror duplicates the write of segments as a single log entry to the
+// secondary Logger if WithMirror, once the write is accepted, i.e.: *err
+// is nil. It's deferred by the write methods.
+func (l *Logger) mirror(err *error, segments ...[]byte) {
+	if secondary := l.opts.mirror; secondary != nil && *err == nil {
+		_, merr := secondary.WriteV(segments...)
+		l.mirrorFailed(merr)
+	}
+}
+
+// mirrorTry is like mirror, but for TryWrite, so it never blocks on the
+// secondary either.
+func (l *Logger) mirrorTry(err *error, b []byte) {
+	if secondary := l.opts.mirror; secondary != nil && *err == nil {
+		_, merr := secondary.TryWrite(b)
+		l.mirrorFailed(merr)
+	}
+}
+
+// mirrorFailed counts and reports the error of a duplicated write, if
+// any, to the secondary, without failing the write of the Logger.
+func (l *Logger) mirrorFailed(err error) {
+	if err == nil {
+		return
+	}
+	l.metrics.MirrorErrors.Add(1)
+	secondary := l.opts.mirror
+	secondary.handleError(&RotationError{Op: "mirror", Path: secondary.Name(), Err: err})
+}
@@ -0,0 +1,66 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Mirror(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Mirror")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "primary", "app.log"), WithMirror(nil))
+	require.Error(t, err, "nil mirror should be rejected")
+
+	var mu sync.Mutex
+	var errs []error
+	secondary, err := New(
+		filepath.Join(dir, "secondary", "app.log"),
+		WithMaxSize(10),
+		WithErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}),
+	)
+	require.NoError(t, err, "New secondary should succeed")
+	primaryName := filepath.Join(dir, "primary", "app.log")
+	l, err := New(primaryName, WithMirror(secondary))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.True(t, l.Options().HasMirror, "HasMirror should be set")
+
+	_, err = l.Write([]byte("abc\n"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = l.WriteV([]byte("de"), []byte("f\n"))
+	require.NoError(t, err, "WriteV should succeed")
+	_, err = l.TryWrite([]byte("ghi\n"))
+	require.NoError(t, err, "TryWrite should succeed")
+
+	readFile := func(name string) string {
+		b, err := os.ReadFile(name)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(b)
+	}
+	require.Equal(t, "abc\ndef\nghi\n", readFile(primaryName), "primary content should match")
+	// the secondary rotates on its own.
+	require.Equal(t, "abc\ndef\n", readFile(filepath.Join(dir, "secondary", "app.log")), "secondary content should match")
+	require.Equal(t, "ghi\n", readFile(filepath.Join(dir, "secondary", "app.log.1")), "secondary content should match")
+
+	// the secondary fails independently.
+	require.NoError(t, secondary.Close(), "Close secondary should succeed")
+	_, err = l.Write([]byte("jkl\n"))
+	require.NoError(t, err, "Write should succeed even if the mirror fails")
+	require.Equal(t, "abc\ndef\nghi\njkl\n", readFile(primaryName), "primary content should match")
+	require.Equal(t, uint64(1), l.Metrics().MirrorErrors, "MirrorErrors should be counted")
+	require.NoError(t, l.Healthy(), "primary should be healthy")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, errs, 1, "secondary should be reported")
+	require.ErrorIs(t, errs[0], ErrClosed, "error should be ErrClosed")
+}
@@ -0,0 +1,38 @@
+package logrotate
+
+import "time"
+
+// WriteInfo describes a physical write to a log file, which is passed to
+// the WriteObserver.
+type WriteInfo struct {
+	Filename string        // path of the file written to
+	Bytes    int           // count of bytes written
+	Duration time.Duration // duration of the physical write
+	Rotated  bool          // whether the file was rotated right before the write
+	Err      error         // error of the write, if any
+}
+
+// writeHandle writes b to the file handle h, records it for the group
+// commit, forwards it to syslog and calls the WriteObserver if any, where
+// rotated reports whether h was rotated to for the write.
+func (l *Logger) writeHandle(h *fileHandle, b []byte, rotated bool) (int, error) {
+	observer := l.opts.writeObserver
+	if observer == nil {
+		n, err := h.Write(b)
+		l.commit.wrote(h, n)
+		l.forward.send(b[:n])
+		return n, err
+	}
+	start := time.Now()
+	n, err := h.Write(b)
+	l.commit.wrote(h, n)
+	l.forward.send(b[:n])
+	observer(WriteInfo{
+		Filename: h.name,
+		Bytes:    n,
+		Duration: time.Since(start),
+		Rotated:  rotated,
+		Err:      err,
+	})
+	return n, err
+}
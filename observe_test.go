@@ -0,0 +1,54 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteObserver(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WriteObserver")
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	var infos []WriteInfo
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(
+		filename,
+		WithMaxSize(4),
+		WithWriteObserver(func(info WriteInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			infos = append(infos, info)
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.True(t, l.Options().HasWriteObserver, "HasWriteObserver should be set")
+
+	for _, line := range []string{"aaa", "b", "ccc"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, infos, 3, "observer should be called on each write")
+	for i, want := range []struct {
+		filename string
+		bytes    int
+		rotated  bool
+	}{
+		{filename, 3, false},
+		{filename, 1, false},
+		{filename + ".1", 3, true},
+	} {
+		require.Equal(t, want.filename, infos[i].Filename, "Filename should match")
+		require.Equal(t, want.bytes, infos[i].Bytes, "Bytes should match")
+		require.Equal(t, want.rotated, infos[i].Rotated, "Rotated should match")
+		require.NoError(t, infos[i].Err, "Err should be nil")
+	}
+}
@@ -1,23 +1,340 @@
 package logrotate
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"time"
+
+	"github.com/lestrrat-go/strftime"
 )
 
 // Options is supplied as the optional arguments for New.
 type Options struct {
-	clock       Clock         // used to determine the current time
-	symlink     string        // linked to the current file
-	maxInterval time.Duration // max interval between file rotation
-	maxSequence int           // max count of log files in the same interval
-	maxSize     int           // max size of log file before rotation
-	maxAge      time.Duration // max age to retain old log files
-	maxBackups  int           // max number of old log files to retain
-	writeChSize int           // buffered write channel size
+	clock       Clock                  // used to determine the current time
+	utc         bool                   // use UTC regardless of the Location of clock
+	symlink     string                 // linked to the current file
+	symlinks    map[string]SymlinkRule // additional symlinks and their rules
+	maxInterval time.Duration          // max interval between file rotation
+	maxSequence int                    // max count of log files in the same interval
+	maxSize     int                    // max size of log file before rotation
+	maxAge      time.Duration          // max age to retain old log files
+	maxAgeDays  int                    // max calendar days to retain old log files
+	maxBackups  int                    // max number of old log files to retain
+	writeChSize int                    // buffered write channel size
+	autoTune    bool                   // tune the bound of the queue adaptively
+	queue       Queue                  // custom queue of buffered writes
+	spillDir    string                 // dir to spill overflowed buffered writes to
+	samples     int                    // max count of recent discarded writes sampled
+	sampleSize  int                    // max bytes of each sampled discarded write
+	exclusive   bool                   // take an exclusive lock on the current file
+	createOnNew bool                   // open the current file eagerly on New
+	preallocate int64                  // disk space to preallocate for new files
+	warmStandby time.Duration          // lead time to pre-open the file of the next interval
+	durability  Durability             // synchronous persistence mode of file writes
+	alignBlock  int                    // block size which buffered writes are aligned to
+	alignDelay  time.Duration          // max delay of buffered writes not yet aligned
+	shared      bool                   // share the Logger with the same pattern
+	name        string                 // name for metrics aggregation
+	labels      map[string]string      // labels for metrics aggregation
+	activeFile  string                 // fixed filename of the current file
+	recovery    RecoveryMode           // adoption of files left by a predecessor
+	finalMarker bool                   // append a finalization record on close
+	journal     string                 // path of the journal of pending rotations
+	stateFile   string                 // path of the state of the rotation schedule
+
+	symlinkTarget SymlinkTarget // file which Symlink is linked to
+
+	segmentSize  int    // size of chunks in segmented mode
+	segmentIndex string // path of the index of chunks in segmented mode
+
+	groupCommitDelay time.Duration // max delay of a group commit after a write
+	groupCommitBytes int64         // bytes written which trigger a group commit early
+
+	patternFuncs map[string]func() string // custom template functions in the pattern
+	seqSuffix    string                   // format of the sequence suffix of filenames
+
+	sizeReconcileInterval time.Duration // interval to reconcile size with the real file size
+	maxLines              int           // max line count of log file before rotation
+	rotateHeadroom        int           // headroom below MaxSize to rotate before
+
+	rotatePredicate   func(stats FileStats, b []byte) bool // custom rotation condition
+	scheduledRotation bool                                 // rotate at interval boundaries without writes
+	writeObserver     func(info WriteInfo)                 // called after each physical write
+
+	fallback     io.Writer       // written to when the filesystem is read-only
+	mirror       *Logger         // secondary Logger which writes are duplicated to
+	errorHandler func(err error) // called on errors in background
+	writeTimeout time.Duration   // max duration of a file write
+	partialWrite bool            // write the remainder of a failed write to the reopened file
+
+	syslogFacility Facility // facility of entries forwarded to syslog
+	syslogTag      string   // tag of entries forwarded to syslog, if any
+
+	inheritPerm bool                    // inherit permissions of the parent directory
+	createHook  func(path string) error // called on created files and directories
+
+	rotateHook func(path string) (string, error) // called on rotated files, may move them
+	timeRange  *strftime.Strftime                // layout of time range in rotated filenames
+
+	preRotateHook  func(reason RotateReason, current string) error // may veto rotations
+	maxRotateDefer time.Duration                                   // max duration rotations are vetoed
+
+	redactors []Redactor // applied to each write before persistence
+
+	maxBackupsPerInterval int                  // max number of log files to retain per interval
+	maxTotalSize          int64                // max total size of log files to retain
+	quotaGroup            *QuotaGroup          // combined max total size shared with other Loggers
+	quotaWeight           int                  // weight of eviction in quotaGroup
+	quotaPriority         int                  // priority of eviction in quotaGroup
+	policies              []RetentionPolicy    // custom retention policies
+	birthTime             bool                 // use birth time for retention
+	retentionGranularity  RetentionGranularity // unit which retention policies apply to
+	indexRescan           time.Duration        // full rescan interval of the file index
+	remoteStore           RemoteStore          // verified before deleting log files
+
+	minDeleteAge  time.Duration // min age since modification to delete log files
+	openFileCheck OpenFileCheck // scope of processes checked for holding log files
+
+	savelogCycle    int  // number of savelog-style numbered files
+	savelogCompress bool // compress savelog-style numbered files from 1
+
+	dayBundle BundleFormat // archive format to bundle the files of past days
+
+	partialSweep time.Duration // age of partial artifacts to sweep
+
+	processors     []Processor   // post-rotation processors
+	hashSuffix     int           // length of content hash suffix of rotated files
+	processRetries int           // max retries of a failed processor
+	processBackoff time.Duration // initial backoff between retries
+
+	millConcurrency int   // max rotated files processed in parallel
+	millRateLimit   int64 // max IO bandwidth of processors in bytes per second
+}
+
+// OptionsSnapshot is a read-only snapshot of the effective options of a
+// Logger, after normalization, for wrappers and admin endpoints to report
+// the configuration. Hooks like Clock and ErrorHandler are reported by
+// whether they are set, and processors and policies by their counts.
+type OptionsSnapshot struct {
+	Pattern     string
+	UTC         bool
+	Symlink     string
+	Symlinks    int // count of additional symlinks
+	MaxInterval time.Duration
+	MaxSequence int
+	MaxSize     int
+	MaxAge      time.Duration
+	MaxAgeDays  int
+	MaxBackups  int
+	WriteChan   int
+	AutoTune    bool
+	HasQueue    bool
+	SpillDir    string
+	Samples     int
+	SampleSize  int
+	Exclusive   bool
+	CreateOnNew bool
+	Preallocate int64
+	WarmStandby time.Duration
+	Durability  Durability
+	AlignBlock  int
+	AlignDelay  time.Duration
+	Shared      bool
+	Name        string
+	Labels      int // count of labels
+	ActiveFile  string
+	Recovery    RecoveryMode
+	FinalMarker bool
+	Journal     string
+	StateFile   string
+
+	SymlinkTarget SymlinkTarget
+
+	SegmentSize  int
+	SegmentIndex string
+
+	GroupCommitDelay time.Duration
+	GroupCommitBytes int64
+
+	PatternFuncs   int // count of custom template functions
+	SequenceSuffix string
+
+	SizeReconcileInterval time.Duration
+	MaxLines              int
+	RotateHeadroom        int
+	HasRotatePredicate    bool
+	HasWriteObserver      bool
+
+	HasFallbackWriter    bool
+	HasMirror            bool
+	HasErrorHandler      bool
+	WriteTimeout         time.Duration
+	PartialWriteRecovery bool
+
+	SyslogFacility Facility
+	SyslogTag      string
+
+	InheritPermissions bool
+	HasCreateHook      bool
+	HasRotateHook      bool
+	TimeRangeName      string
+
+	HasPreRotateHook bool
+	MaxRotateDefer   time.Duration
+
+	Redactors int // count of redactors
+
+	MaxBackupsPerInterval int
+	MaxTotalSize          int64
+	HasQuotaGroup         bool
+	QuotaWeight           int
+	QuotaPriority         int
+	RetentionPolicies     int // count of custom retention policies
+	BirthTime             bool
+	RetentionGranularity  RetentionGranularity
+	FileIndex             time.Duration
+	VerifyBeforeDelete    bool
+
+	MinAgeBeforeDelete time.Duration
+	OpenFileCheck      OpenFileCheck
+
+	SavelogCycle    int
+	SavelogCompress bool
+
+	DayBundle BundleFormat
+
+	PartialSweep time.Duration
+
+	PostRotateProcessors int // count of custom post-rotation processors
+	HashSuffix           int
+	ProcessRetries       int
+	ProcessBackoff       time.Duration
+
+	MillConcurrency int
+	MillRateLimit   int64
+}
+
+// snapshot returns the OptionsSnapshot of opts, with the values normalized
+// as they are used.
+func (opts *Options) snapshot() OptionsSnapshot {
+	hashSuffix := opts.hashSuffix
+	if hashSuffix > sha256.Size*2 {
+		hashSuffix = sha256.Size * 2
+	}
+	millConcurrency := opts.millConcurrency
+	if millConcurrency <= 0 {
+		millConcurrency = 1
+	}
+	var timeRangeName string
+	if opts.timeRange != nil {
+		timeRangeName = opts.timeRange.Pattern()
+	}
+	return OptionsSnapshot{
+		UTC:         opts.utc,
+		Symlink:     opts.symlink,
+		Symlinks:    len(opts.symlinks),
+		MaxInterval: opts.maxInterval,
+		MaxSequence: opts.maxSequence,
+		MaxSize:     opts.maxSize,
+		MaxAge:      opts.maxAge,
+		MaxAgeDays:  opts.maxAgeDays,
+		MaxBackups:  opts.maxBackups,
+		WriteChan:   opts.writeChSize,
+		AutoTune:    opts.autoTune,
+		HasQueue:    opts.queue != nil,
+		SpillDir:    opts.spillDir,
+		Samples:     opts.samples,
+		SampleSize:  opts.sampleSize,
+		Exclusive:   opts.exclusive,
+		CreateOnNew: opts.createOnNew,
+		Preallocate: opts.preallocate,
+		WarmStandby: opts.warmStandby,
+		Durability:  opts.durability,
+		AlignBlock:  opts.alignBlock,
+		AlignDelay:  opts.alignDelay,
+		Shared:      opts.shared,
+		Name:        opts.name,
+		Labels:      len(opts.labels),
+		ActiveFile:  opts.activeFile,
+		Recovery:    opts.recovery,
+		FinalMarker: opts.finalMarker,
+		Journal:     opts.journal,
+		StateFile:   opts.stateFile,
+
+		SymlinkTarget: opts.symlinkTarget,
+
+		SegmentSize:  opts.segmentSize,
+		SegmentIndex: opts.segmentIndex,
+
+		GroupCommitDelay: opts.groupCommitDelay,
+		GroupCommitBytes: opts.groupCommitBytes,
+
+		PatternFuncs:   len(opts.patternFuncs),
+		SequenceSuffix: opts.seqSuffix,
+
+		SizeReconcileInterval: opts.sizeReconcileInterval,
+		MaxLines:              opts.maxLines,
+		RotateHeadroom:        opts.rotateHeadroom,
+		HasRotatePredicate:    opts.rotatePredicate != nil,
+		HasWriteObserver:      opts.writeObserver != nil,
+
+		HasFallbackWriter:    opts.fallback != nil,
+		HasMirror:            opts.mirror != nil,
+		HasErrorHandler:      opts.errorHandler != nil,
+		WriteTimeout:         opts.writeTimeout,
+		PartialWriteRecovery: opts.partialWrite,
+
+		SyslogFacility: opts.syslogFacility,
+		SyslogTag:      opts.syslogTag,
+
+		InheritPermissions: opts.inheritPerm,
+		HasCreateHook:      opts.createHook != nil,
+		HasRotateHook:      opts.rotateHook != nil,
+		TimeRangeName:      timeRangeName,
+
+		HasPreRotateHook: opts.preRotateHook != nil,
+		MaxRotateDefer:   opts.maxRotateDefer,
+
+		Redactors: len(opts.redactors),
+
+		MaxBackupsPerInterval: opts.maxBackupsPerInterval,
+		MaxTotalSize:          opts.maxTotalSize,
+		HasQuotaGroup:         opts.quotaGroup != nil,
+		QuotaWeight:           opts.quotaWeight,
+		QuotaPriority:         opts.quotaPriority,
+		RetentionPolicies:     len(opts.policies),
+		BirthTime:             opts.birthTime,
+		RetentionGranularity:  opts.retentionGranularity,
+		FileIndex:             opts.indexRescan,
+		VerifyBeforeDelete:    opts.remoteStore != nil,
+
+		MinAgeBeforeDelete: opts.minDeleteAge,
+		OpenFileCheck:      opts.openFileCheck,
+
+		SavelogCycle:    opts.savelogCycle,
+		SavelogCompress: opts.savelogCompress,
+
+		DayBundle: opts.dayBundle,
+
+		PartialSweep: opts.partialSweep,
+
+		PostRotateProcessors: len(opts.processors),
+		HashSuffix:           hashSuffix,
+		ProcessRetries:       opts.processRetries,
+		ProcessBackoff:       opts.processBackoff,
+
+		MillConcurrency: millConcurrency,
+		MillRateLimit:   opts.millRateLimit,
+	}
 }
 
-// Option is the functional option type.
-type Option func(*Options)
+// Option is the functional option type. An option returns an error if its
+// argument is invalid, which is returned by New.
+type Option func(*Options) error
 
 func newDefaultOptions() *Options {
 	return &Options{
@@ -28,43 +345,178 @@ func newDefaultOptions() *Options {
 		maxAge:      0,                 // retain all old log files
 		maxBackups:  0,                 // retain all old log files
 		writeChSize: 0,                 // do not use buffered write.
+
+		seqSuffix: defaultSequenceSuffix, // e.g.: "app.log.1"
+
+		processBackoff: time.Second, // 1 second
+
+		millConcurrency: 1, // process rotated files one by one
+		millRateLimit:   0, // no IO bandwidth limit
 	}
 }
 
-func parseOptions(setters ...Option) *Options {
+// retentionPolicies returns the built-in retention policies configured by
+// options, followed by the custom ones.
+func (opts *Options) retentionPolicies() []RetentionPolicy {
+	var policies []RetentionPolicy
+	if opts.maxAge > 0 {
+		policies = append(policies, NewMaxAgePolicy(opts.maxAge))
+	}
+	if opts.maxAgeDays > 0 {
+		policies = append(policies, NewMaxAgeDaysPolicy(opts.maxAgeDays))
+	}
+	if opts.maxBackupsPerInterval > 0 {
+		policies = append(policies, NewMaxBackupsPerIntervalPolicy(opts.maxBackupsPerInterval, opts.maxInterval))
+	}
+	if opts.maxBackups > 0 {
+		policies = append(policies, NewMaxBackupsPolicy(opts.maxBackups))
+	}
+	if opts.maxTotalSize > 0 {
+		policies = append(policies, NewMaxTotalSizePolicy(opts.maxTotalSize))
+	}
+	return append(policies, opts.policies...)
+}
+
+// postRotateProcessors returns the built-in processors configured by options,
+// followed by the custom ones.
+func (opts *Options) postRotateProcessors() []Processor {
+	var processors []Processor
+	if opts.hashSuffix > 0 {
+		processors = append(processors, NewHashSuffixProcessor(opts.hashSuffix))
+	}
+	return append(processors, opts.processors...)
+}
+
+func parseOptions(setters ...Option) (*Options, error) {
 	// default Options
 	opts := newDefaultOptions()
 	for _, setter := range setters {
-		setter(opts)
+		if err := setter(opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.segmentSize > 0 {
+		if opts.maxSize != opts.segmentSize {
+			return nil, fmt.Errorf("logrotate: max size %d conflicts with segment size %d", opts.maxSize, opts.segmentSize)
+		}
+		if opts.activeFile != "" || opts.savelogCycle > 0 {
+			return nil, errors.New("logrotate: segments can't be combined with active file or savelog")
+		}
+	}
+	if opts.warmStandby > 0 && (opts.activeFile != "" || opts.savelogCycle > 0) {
+		return nil, errors.New("logrotate: warm standby can't be combined with active file or savelog")
 	}
-	return opts
+	if opts.dayBundle != BundleNone && opts.savelogCycle > 0 {
+		return nil, errors.New("logrotate: day bundle can't be combined with savelog")
+	}
+	if opts.maxSize > 0 && opts.rotateHeadroom >= opts.maxSize {
+		return nil, fmt.Errorf("logrotate: rotate headroom %d must be less than max size %d", opts.rotateHeadroom, opts.maxSize)
+	}
+	if opts.groupCommitDelay > 0 && opts.durability != DurabilityDefault {
+		return nil, fmt.Errorf("logrotate: group commit is redundant with durability %v", opts.durability)
+	}
+	if opts.utc {
+		opts.clock = utcClock{opts.clock}
+	}
+	return opts, nil
+}
+
+// sizeLimit returns the size which a write must not put the current file
+// over, i.e.: MaxSize less RotateHeadroom, or 0 if MaxSize is disabled.
+func (opts *Options) sizeLimit() int64 {
+	if opts.maxSize <= 0 {
+		return 0
+	}
+	return int64(opts.maxSize - opts.rotateHeadroom)
 }
 
 // WithClock specifies the clock used by Logger to determine the current
 // time. It defaults to the system clock with time.Now.
 func WithClock(clock Clock) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.clock = clock
+		return nil
+	}
+}
+
+// WithUTC forces all filename timestamps and rotation boundaries to UTC,
+// regardless of the timezone of the host or the Location of the Clock, so
+// that multi-region fleets name files consistently. Patterns may also use
+// %z and %Z for the timezone offset and name, e.g.: "app.%Y%m%d%z.log".
+//
+// Default: false
+func WithUTC(enable bool) Option {
+	return func(opts *Options) error {
+		opts.utc = enable
+		return nil
 	}
 }
 
 // WithSymlink sets the symbolic link name that gets linked to
-// the current filename being used.
+// the current filename being used. The name can contain strftime tokens,
+// e.g.: "logs/%Y/%m/current", evaluated at the rotation time of the current
+// file, so the symlink lives alongside the directory of the period.
 //
 // Default: ""
 func WithSymlink(name string) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
+		if err := validateSymlink(name); err != nil {
+			return err
+		}
 		opts.symlink = name
+		return nil
 	}
 }
 
-// WithMaxInterval sets the maximum interval between file rotation.
-// In particular, the minimal interval unit is in time.Second level.
+// WithSymlinks sets additional symbolic links, each tracking the target
+// selected by its rule, e.g.: "latest" by SymlinkCurrent, and "today" by
+// SymlinkNewestOfDay, for dashboards and scripts with different
+// expectations. It can be passed multiple times, and a rule for the name of
+// Symlink overrides it. Targets are never removed by retention.
+//
+// Default: none
+func WithSymlinks(rules map[string]SymlinkRule) Option {
+	return func(opts *Options) error {
+		if opts.symlinks == nil {
+			opts.symlinks = make(map[string]SymlinkRule)
+		}
+		for name, rule := range rules {
+			if err := validateSymlink(name); err != nil {
+				return err
+			}
+			opts.symlinks[name] = rule
+		}
+		return nil
+	}
+}
+
+// WithSymlinkTarget sets the file which Symlink is linked to:
+// SymlinkActiveFile links the file being written by the Logger, e.g.: the
+// highest sequence file created on MaxSize within the current interval, or
+// the newest log file if the current file is not open. SymlinkNewestByTime
+// links the newest log file found by scanning the directory, e.g.: when
+// several processes write the files of the same pattern.
+//
+// Default: SymlinkActiveFile
+func WithSymlinkTarget(t SymlinkTarget) Option {
+	return func(opts *Options) error {
+		if t < SymlinkActiveFile || t > SymlinkNewestByTime {
+			return fmt.Errorf("logrotate: invalid symlink target %v", t)
+		}
+		opts.symlinkTarget = t
+		return nil
+	}
+}
+
+// WithMaxInterval sets the maximum interval between file rotation. Sub-second
+// intervals are supported, e.g.: with the %L or %N directive in the pattern.
+// If d <= 0, rotation based on interval is disabled.
 //
 // Default: 24 hours
 func WithMaxInterval(d time.Duration) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.maxInterval = d
+		return nil
 	}
 }
 
@@ -77,8 +529,9 @@ func WithMaxInterval(d time.Duration) Option {
 //
 // Default: 0
 func WithMaxSequence(n int) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.maxSequence = n
+		return nil
 	}
 }
 
@@ -88,8 +541,104 @@ func WithMaxSequence(n int) Option {
 //
 // Default: 100 MiB
 func WithMaxSize(s int) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.maxSize = s
+		return nil
+	}
+}
+
+// WithMaxSizeString is like WithMaxSize, but the size is parsed from s with
+// an optional unit, e.g.: "100MiB", "10MB", "512K" or "1024". K, M and G are
+// the same as KiB, MiB and GiB. An invalid s makes New fail.
+func WithMaxSizeString(s string) Option {
+	return func(opts *Options) error {
+		size, err := ParseSize(s)
+		if err != nil {
+			return fmt.Errorf("logrotate: invalid max size %q: %w", s, err)
+		}
+		opts.maxSize = int(size)
+		return nil
+	}
+}
+
+// WithRotateHeadroom rotates the current file before a write puts it within
+// the headroom bytes of MaxSize, instead of over MaxSize, so that the file
+// stays under MaxSize by that margin even if the tracked size lags behind,
+// e.g.: bursts appended by other processes until the size is reconciled.
+// It's useful when downstream systems hard-reject files larger than a
+// strict limit. It must be less than MaxSize if MaxSize > 0.
+//
+// Default: 0
+func WithRotateHeadroom(bytes int) Option {
+	return func(opts *Options) error {
+		if bytes < 0 {
+			return fmt.Errorf("logrotate: invalid rotate headroom %d", bytes)
+		}
+		opts.rotateHeadroom = bytes
+		return nil
+	}
+}
+
+// WithMaxLines sets the max line count of log file before rotation, as
+// some pipelines cap files by entry count rather than bytes. Lines are
+// counted by '\n' per write, and rotation is triggered like MaxSize, so a
+// write is never split across files. Lines of the existing file are
+// counted when resumed.
+//
+// Default: 0 (no limit)
+func WithMaxLines(n int) Option {
+	return func(opts *Options) error {
+		opts.maxLines = n
+		return nil
+	}
+}
+
+// WithScheduledRotation rotates the current file at each MaxInterval
+// boundary by a timer, even if there are no writes. Otherwise, rotation
+// boundaries are only evaluated at write time. If the Clock implements
+// TimerClock, the timer is taken from it, so a fake clock can drive the
+// schedule in tests.
+//
+// Default: false
+func WithScheduledRotation(enable bool) Option {
+	return func(opts *Options) error {
+		opts.scheduledRotation = enable
+		return nil
+	}
+}
+
+// WithWriteObserver sets the observer called after each physical write to
+// a log file, with the bytes written, the duration, the filename, and
+// whether the file was rotated right before, so that APM tools can record
+// fine-grained latency histograms without wrapping the Logger.
+//
+// NOTE: the observer is called synchronously on the write path, possibly
+// concurrently and with the Logger locked, so it should be cheap and must
+// not call the Logger.
+//
+// Default: nil
+func WithWriteObserver(observer func(info WriteInfo)) Option {
+	return func(opts *Options) error {
+		opts.writeObserver = observer
+		return nil
+	}
+}
+
+// WithRotatePredicate sets the predicate consulted before each write with
+// the stats of the current file and the bytes to be written. If it returns
+// true, the current file is rotated and b is written to the new file, so
+// advanced users can rotate on custom conditions, e.g.: specific marker
+// lines or upstream epoch changes.
+//
+// NOTE: the predicate may be called concurrently, and more than once for a
+// write, so it should be cheap and deterministic. A write is never rotated
+// twice.
+//
+// Default: nil
+func WithRotatePredicate(predicate func(stats FileStats, b []byte) bool) Option {
+	return func(opts *Options) error {
+		opts.rotatePredicate = predicate
+		return nil
 	}
 }
 
@@ -99,8 +648,23 @@ func WithMaxSize(s int) Option {
 //
 // Default: 0
 func WithMaxAge(d time.Duration) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.maxAge = d
+		return nil
+	}
+}
+
+// WithMaxAgeDays sets the max calendar days to retain old log files,
+// relative to the local midnight, instead of a raw duration as MaxAge,
+// e.g.: with n 30, the files of the last 30 days and today are kept, even
+// around DST transitions. If n <= 0, old log files are not removed based on
+// calendar days.
+//
+// Default: 0
+func WithMaxAgeDays(n int) Option {
+	return func(opts *Options) error {
+		opts.maxAgeDays = n
+		return nil
 	}
 }
 
@@ -110,8 +674,790 @@ func WithMaxAge(d time.Duration) Option {
 //
 // Default: 0
 func WithMaxBackups(n int) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.maxBackups = n
+		return nil
+	}
+}
+
+// WithMaxBackupsPerInterval sets the maximum number of log files to
+// retain for each MaxInterval bucket (e.g.: per hour), independent of
+// MaxBackups. So a single bursty interval which rotates many times based on
+// MaxSize can't evict log files of all other intervals. The bucket of a log
+// file is evaluated based on FileInfo.Time. If n <= 0, that means no limit
+// per interval.
+//
+// Default: 0
+func WithMaxBackupsPerInterval(n int) Option {
+	return func(opts *Options) error {
+		opts.maxBackupsPerInterval = n
+		return nil
+	}
+}
+
+// WithMaxTotalSize sets the maximum total size in bytes of log files to
+// retain. The oldest log files are removed until the total size is not over
+// it, but the newest log file is always kept. If MaxTotalSize <= 0, that
+// means not remove old log files based on total size.
+//
+// Default: 0
+func WithMaxTotalSize(size int64) Option {
+	return func(opts *Options) error {
+		opts.maxTotalSize = size
+		return nil
+	}
+}
+
+// WithQuotaGroup makes the Logger share the QuotaGroup g, which enforces a
+// combined max total size of the log files of all its Loggers. When over
+// the quota, the files of Loggers with lower priority are removed first, and
+// among Loggers with the same priority, of the one using the most relative
+// to its weight. A weight <= 0 returns an error.
+//
+// Default: nil
+func WithQuotaGroup(g *QuotaGroup, weight, priority int) Option {
+	return func(opts *Options) error {
+		if weight <= 0 {
+			return fmt.Errorf("logrotate: invalid quota weight: %d", weight)
+		}
+		opts.quotaGroup = g
+		opts.quotaWeight = weight
+		opts.quotaPriority = priority
+		return nil
+	}
+}
+
+// WithRetentionPolicy adds custom retention policies, which select old log
+// files to be removed (e.g.: keep one file per day for 30 days, and one per
+// month for a year). The built-in policies configured by MaxAge,
+// MaxBackupsPerInterval, MaxBackups and MaxTotalSize are applied first in
+// that order, and then the custom ones in order, each one selecting from the
+// files remaining after the previous ones.
+//
+// Default: no custom policies
+func WithRetentionPolicy(p ...RetentionPolicy) Option {
+	return func(opts *Options) error {
+		opts.policies = append(opts.policies, p...)
+		return nil
+	}
+}
+
+// WithExclusive takes an exclusive advisory lock (flock) on the current log
+// file, so that another Logger instance in the same process or another
+// process with the same pattern can't interleave or truncate it. If enabled,
+// the current log file is opened eagerly by New, and ErrFileLocked is
+// returned by New or Write when another holder exists.
+//
+// NOTE: the lock is a no-op on platforms without flock support, e.g.:
+// Windows.
+//
+// Default: false
+func WithExclusive(exclusive bool) Option {
+	return func(opts *Options) error {
+		opts.exclusive = exclusive
+		return nil
+	}
+}
+
+// WithCreateOnNew sets whether to open (or create) the current file and set
+// up the symlink eagerly on New, instead of on the first Write, so that
+// health checks and tailers started at boot find the file.
+//
+// Default: false
+func WithCreateOnNew(create bool) Option {
+	return func(opts *Options) error {
+		opts.createOnNew = create
+		return nil
+	}
+}
+
+// WithPreallocate sets the disk space in bytes to preallocate for new log
+// files, capped by MaxSize, which reduces fragmentation and ENOSPC surprises
+// mid-file for high-throughput appenders. The file size is unchanged, so
+// appends still start at the end of data.
+//
+// NOTE: it uses fallocate on Linux, and is a no-op on other platforms or
+// filesystems without fallocate support.
+//
+// Default: 0 (no preallocation)
+func WithPreallocate(size int64) Option {
+	return func(opts *Options) error {
+		opts.preallocate = size
+		return nil
+	}
+}
+
+// WithWarmStandby makes the directory of, and pre-opens the file of the
+// next interval lead before each MaxInterval boundary, so the first write in
+// the new interval doesn't pay the MkdirAll and OpenFile latency, which is
+// significant for the tail latency at exactly the top of the hour under
+// load. The pre-opened file is created empty, and removed on Close if not
+// taken. It's skipped if the file exists already. It can't be combined with
+// WithActiveFile or WithSavelog.
+//
+// Default: 0 (disabled)
+func WithWarmStandby(lead time.Duration) Option {
+	return func(opts *Options) error {
+		if lead <= 0 {
+			return fmt.Errorf("logrotate: invalid warm standby lead %v", lead)
+		}
+		opts.warmStandby = lead
+		return nil
+	}
+}
+
+// Durability is the synchronous persistence mode of file writes.
+type Durability int
+
+const (
+	// DurabilityDefault leaves persistence to the OS page cache.
+	DurabilityDefault Durability = iota
+	// DurabilityDSync opens files with O_DSYNC, so each write returns after
+	// the data (and the metadata needed to read it) is persisted. It falls
+	// back to O_SYNC on platforms without O_DSYNC.
+	DurabilityDSync
+	// DurabilitySync opens files with O_SYNC, so each write returns after
+	// the data and all metadata are persisted.
+	DurabilitySync
+)
+
+// flag returns the open flag of d.
+func (d Durability) flag() int {
+	switch d {
+	case DurabilityDSync:
+		return oDSync
+	case DurabilitySync:
+		return os.O_SYNC
+	default:
+		return 0
+	}
+}
+
+// String returns the name of d.
+func (d Durability) String() string {
+	switch d {
+	case DurabilityDefault:
+		return "default"
+	case DurabilityDSync:
+		return "dsync"
+	case DurabilitySync:
+		return "sync"
+	default:
+		return fmt.Sprintf("Durability(%d)", int(d))
+	}
+}
+
+// WithDurability sets the synchronous persistence mode of file writes, so
+// that audit loggers can request persistence per write, accepting the
+// throughput cost, without wrapping the file handle themselves. An invalid
+// mode returns an error.
+//
+// NOTE: O_DIRECT is not supported, as it requires aligned write buffers.
+//
+// Default: DurabilityDefault
+func WithDurability(d Durability) Option {
+	return func(opts *Options) error {
+		if d < DurabilityDefault || d > DurabilitySync {
+			return fmt.Errorf("logrotate: invalid durability: %v", d)
+		}
+		opts.durability = d
+		return nil
+	}
+}
+
+// WithAlignedWrites coalesces writes in memory, and writes them out to the
+// file in whole blocks of blockSize bytes, e.g.: 4096, so that each
+// physical write ends at a block boundary, which reduces read-modify-write
+// cycles on some storage. The remainder is written out at most maxDelay
+// after it's buffered, and on rotation, Close and Sync. Both must be
+// positive.
+//
+// NOTE: the buffered writes are lost if the process crashes, and readers
+// of the current file, e.g.: tail -f, only see them once written out.
+//
+// Default: disabled
+func WithAlignedWrites(blockSize int, maxDelay time.Duration) Option {
+	return func(opts *Options) error {
+		if blockSize <= 0 {
+			return fmt.Errorf("logrotate: invalid aligned block size %d", blockSize)
+		}
+		if maxDelay <= 0 {
+			return fmt.Errorf("logrotate: invalid aligned write delay %v", maxDelay)
+		}
+		opts.alignBlock = blockSize
+		opts.alignDelay = maxDelay
+		return nil
+	}
+}
+
+// WithGroupCommit makes writes durable without an fsync each: the written
+// files are fsynced in batched group commits, at most maxDelay after a
+// write, or as soon as maxBytes are written since the last commit, and
+// each write returns once a commit covers it. So concurrent writers share
+// the fsync cost, at the latency of up to maxDelay per write. maxDelay must
+// be positive, and maxBytes <= 0 means no limit of bytes.
+//
+// The durability watermark, i.e.: the count of bytes committed so far, is
+// reported by Metrics.CommittedBytes, and Sync waits until it passes all
+// the data written before. It can't be combined with WithDurability.
+//
+// NOTE: in buffered mode, writes still return once queued, and TryWrite
+// never waits for the commit, so call Sync to wait for durability.
+//
+// Default: disabled
+func WithGroupCommit(maxDelay time.Duration, maxBytes int64) Option {
+	return func(opts *Options) error {
+		if maxDelay <= 0 {
+			return fmt.Errorf("logrotate: invalid group commit delay %v", maxDelay)
+		}
+		opts.groupCommitDelay = maxDelay
+		opts.groupCommitBytes = maxBytes
+		return nil
+	}
+}
+
+// WithShared sets whether the Logger can be shared in the process. Loggers
+// are registered process-wide by their cleaned absolute patterns, so that
+// two components can't accidentally construct competing Loggers rotating the
+// same files: New returns ErrPatternInUse if another open Logger uses the
+// same pattern. If both are shared, New returns the existing Logger instead,
+// and the options passed to the later New are ignored. A shared Logger is
+// only closed when Close is called for each New returned it.
+//
+// Default: false
+func WithShared(shared bool) Option {
+	return func(opts *Options) error {
+		opts.shared = shared
+		return nil
+	}
+}
+
+// WithName sets the name of the Logger, e.g.: its destination, which is
+// reported by CollectAll, so multi-logger services can export per-logger
+// metrics without manual bookkeeping.
+//
+// Default: "" (the pattern is used instead)
+func WithName(name string) Option {
+	return func(opts *Options) error {
+		opts.name = name
+		return nil
+	}
+}
+
+// WithLabels sets the labels of the Logger, e.g.: service or destination,
+// which are reported with its Metrics by MetricsWithLabels and CollectAll,
+// so that the series of Loggers in the same process are distinguishable
+// when exported to metric systems like Prometheus. The labels are copied.
+//
+// Default: nil
+func WithLabels(labels map[string]string) Option {
+	return func(opts *Options) error {
+		opts.labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			if k == "" {
+				return fmt.Errorf("logrotate: empty label name with value %q", v)
+			}
+			opts.labels[k] = v
+		}
+		return nil
+	}
+}
+
+// WithActiveFile enables the hybrid mode of traditional logrotate: the
+// current file is always written at the fixed filename name, e.g.:
+// "app.log", and on rotation it's renamed to the filename generated by the
+// pattern for the period just ended, e.g.: "app.20240101.log", so that the
+// timestamp reflects the time range of the content. A sequence suffix is
+// appended if the filename already exists, e.g.: on MaxSize rotations. On
+// New, a file left at name from a previous period is renamed on the first
+// write.
+//
+// NOTE: name should not match the pattern, so that the current file is
+// never removed by retention. It's ignored in savelog mode.
+//
+// Default: "" (disabled)
+func WithActiveFile(name string) Option {
+	return func(opts *Options) error {
+		opts.activeFile = name
+		return nil
+	}
+}
+
+// WithPatternFuncs adds custom template functions to the pattern, in
+// addition to the built-in "hostname", "pid" and "env:NAME". A token like
+// "%{name}" in the pattern is replaced by the value of the function name,
+// evaluated at rotation time, e.g.: "/var/log/%{hostname}/app.%Y%m%d.log".
+// The tokens are wildcards when matching the files for retention. A token
+// without a function makes New return an error.
+//
+// Default: nil
+func WithPatternFuncs(funcs map[string]func() string) Option {
+	return func(opts *Options) error {
+		if opts.patternFuncs == nil {
+			opts.patternFuncs = make(map[string]func() string)
+		}
+		for name, fn := range funcs {
+			opts.patternFuncs[name] = fn
+		}
+		return nil
+	}
+}
+
+// WithSequenceSuffix sets the format of the sequence suffix appended to the
+// filenames rotated in the same interval, e.g.: "_part%02d" for
+// "app.log_part01", so rotated names fit existing ingestion regexes. The
+// format must have exactly one integer verb, e.g.: "%d" or "%03d", and no
+// other verbs, path separators or glob metacharacters. It's ignored in
+// savelog mode.
+//
+// Default: ".%d"
+func WithSequenceSuffix(format string) Option {
+	return func(opts *Options) error {
+		if err := validateSequenceSuffix(format); err != nil {
+			return err
+		}
+		opts.seqSuffix = format
+		return nil
+	}
+}
+
+// WithRecoveryMode sets how files left by a crashed predecessor are adopted
+// on the first open, e.g.: resuming the sequence of the current filename
+// instead of overwriting it. An invalid mode returns an error. It's ignored
+// in savelog mode and with an active file.
+//
+// Default: RecoveryOff
+func WithRecoveryMode(m RecoveryMode) Option {
+	return func(opts *Options) error {
+		if m < RecoveryOff || m > RecoveryPermissive {
+			return fmt.Errorf("logrotate: invalid recovery mode: %v", m)
+		}
+		opts.recovery = m
+		return nil
+	}
+}
+
+// WithFinalMarker appends a finalization record with the entry count and
+// checksum as the last line of a file closed by rotation or Close, so
+// consumers can distinguish cleanly finished files from ones truncated by a
+// crash with ReadFinalMarker. A file reopened for appending, e.g.: after a
+// restart, is finalized again with a record covering the whole content.
+//
+// Default: false
+func WithFinalMarker(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.finalMarker = enabled
+		return nil
+	}
+}
+
+// WithJournal journals rotations to the sidecar file at path, as a small
+// state machine from intent, to closed, and then removed after
+// post-processed, so that a crash mid-rotation never leaves the rotated file
+// ambiguous: on New, the rotations pending at a crash are completed, by
+// calling the RotateHook if not yet and queueing the rotated file for
+// post-rotation processing, or rolled back if the rotated file no longer
+// exists. The path should not match the pattern. It's ignored in savelog
+// mode, where the numbered files are not post-processed.
+//
+// Default: "" (disabled)
+func WithJournal(path string) Option {
+	return func(opts *Options) error {
+		opts.journal = path
+		return nil
+	}
+}
+
+// WithStateFile persists the rotation time and the sequence of the current
+// file to the sidecar file at path, replaced atomically when they change,
+// so that a restarted Logger continues the schedule of its predecessor:
+// e.g.: with a stable pattern like "app.log" and MaxInterval, the file
+// written before a restart mid-interval is still rotated at its interval
+// boundary, neither early nor late. The path should not match the pattern.
+//
+// Default: "" (disabled)
+func WithStateFile(path string) Option {
+	return func(opts *Options) error {
+		opts.stateFile = path
+		return nil
+	}
+}
+
+// WithSegments enables the segmented mode: the log of each interval is
+// written in numbered chunks of chunkSize bytes, i.e.: rotated by size as
+// WithMaxSize(chunkSize), and the offset of each chunk in the log of its
+// interval is recorded to the index file at path, so that downstream
+// readers can access the log at random offsets with ReadSegmentIndex and
+// LocateSegment, e.g.: the bytes from 10 GB into today's log. Retention
+// operates on whole chunks, which are dropped from the index once removed
+// or moved, e.g.: compressed. The path should not match the pattern.
+//
+// NOTE: a write is never split across chunks, so a chunk may end short of
+// chunkSize. It can't be combined with WithActiveFile nor WithSavelog.
+//
+// Default: disabled
+func WithSegments(chunkSize int, index string) Option {
+	return func(opts *Options) error {
+		if chunkSize <= 0 {
+			return fmt.Errorf("logrotate: invalid segment size %d", chunkSize)
+		}
+		if index == "" {
+			return errors.New("logrotate: empty segment index path")
+		}
+		opts.maxSize = chunkSize
+		opts.segmentSize = chunkSize
+		opts.segmentIndex = index
+		return nil
+	}
+}
+
+// WithInheritPermissions makes created log files and directories copy the
+// mode and ownership of their parent directory, instead of the hard-coded
+// 0644 and 0755 masked by umask. Regular files never get the executable
+// bits. The owner is copied only if the process runs as root, otherwise
+// only the group, e.g.: a service user in the group of a "root:adm"
+// directory. If changing ownership is not permitted, the file is still
+// used, and a RotationError is reported to the ErrorHandler.
+//
+// Default: false
+func WithInheritPermissions(inherit bool) Option {
+	return func(opts *Options) error {
+		opts.inheritPerm = inherit
+		return nil
+	}
+}
+
+// WithCreateHook sets the hook called on each created log file and
+// directory, after permissions are set. It can be used to set
+// platform-specific attributes, e.g.: SELinux labels or xattrs. The log file
+// is not used if the hook returns an error.
+//
+// Default: nil
+func WithCreateHook(hook func(path string) error) Option {
+	return func(opts *Options) error {
+		opts.createHook = hook
+		return nil
+	}
+}
+
+// WithRotateHook sets the hook called on each rotated log file once it's
+// closed, with the path of the file. It can move the file, e.g.: into a
+// dated subdirectory or renamed with a job ID, and returns the new path,
+// or path itself if not moved. The returned path is passed to post-rotation
+// processors, returned by RotateAndGet, and honored by retention policies
+// and Symlink even if it doesn't match the pattern. If the hook returns an
+// error, it's reported to the ErrorHandler, and the file is regarded as not
+// moved.
+//
+// NOTE: it's called in the write path after the internal lock is released,
+// so it should be fast (e.g.: a rename). Moved files not matching the
+// pattern are only tracked in memory, so they are no longer managed after
+// the process restarts.
+//
+// Default: nil
+func WithRotateHook(hook func(path string) (newPath string, err error)) Option {
+	return func(opts *Options) error {
+		opts.rotateHook = hook
+		return nil
+	}
+}
+
+// WithPreRotateHook sets the hook called before the current file is
+// rotated, with the reason and the path of the current file. If it returns
+// an error, the rotation is vetoed, e.g.: while a long export is reading the
+// current file: a rotation on write is evaluated again on the next write,
+// and Rotate returns an error wrapping ErrRotationVetoed. Once rotations have
+// been vetoed for maxDefer, the hook is bypassed until the next rotation, so
+// rotation can't be blocked forever. maxDefer must be positive.
+//
+// NOTE: the hook is called with the Logger locked, so it should be cheap
+// and must not call the Logger.
+//
+// Default: nil
+func WithPreRotateHook(hook func(reason RotateReason, current string) error, maxDefer time.Duration) Option {
+	return func(opts *Options) error {
+		if hook != nil && maxDefer <= 0 {
+			return fmt.Errorf("logrotate: invalid max rotate defer %v", maxDefer)
+		}
+		opts.preRotateHook = hook
+		opts.maxRotateDefer = maxDefer
+		return nil
+	}
+}
+
+// WithRedactors sets the redactors which are applied in order to each
+// write before it's persisted, e.g.: to mask tokens or PII. Redacted
+// matches are counted in Metrics.Redactions.
+//
+// NOTE: redaction applies per write, so a secret split across two writes
+// is not matched. Writes report the length of the original log line.
+//
+// Default: no redactors
+func WithRedactors(r ...Redactor) Option {
+	return func(opts *Options) error {
+		opts.redactors = append(opts.redactors, r...)
+		return nil
+	}
+}
+
+// WithTimeRangeName renames each rotated log file once it's closed to
+// include the time range of its writes, formatted by the strftime layout,
+// e.g.: "%Y%m%dT%H" renames "app.log.1" to "app.20240101T00-20240101T06.log",
+// so that operators can find the file for an incident time window at a
+// glance. The sequence suffix is removed, and appended again only if the
+// filename already exists. Files with no writes are not renamed. The file
+// is renamed before the RotateHook is called.
+//
+// NOTE: the time range of a file resumed on New starts from its first write
+// after New. Renamed files are only tracked in memory for retention, as
+// RotateHook moved files.
+//
+// Default: "" (disabled)
+func WithTimeRangeName(layout string) Option {
+	return func(opts *Options) error {
+		if layout == "" {
+			opts.timeRange = nil
+			return nil
+		}
+		p, err := newStrftime(layout)
+		if err != nil {
+			return fmt.Errorf("logrotate: invalid time range layout: %w", err)
+		}
+		opts.timeRange = p
+		return nil
+	}
+}
+
+// WithBirthTime makes retention decisions and ordering (e.g.: MaxAge and
+// MaxBackups) use the birth time of log files when available, instead of
+// the modification time, to be robust against tools that modify it (e.g.:
+// rsync, backup restores). See FileInfo.Time.
+//
+// The birth time is available on macOS, FreeBSD and NetBSD, and on Linux
+// 4.11+ (amd64, arm64, riscv64 and loong64) with a filesystem recording it.
+// Otherwise, it falls back to the modification time.
+//
+// Default: false
+func WithBirthTime(enable bool) Option {
+	return func(opts *Options) error {
+		opts.birthTime = enable
+		return nil
+	}
+}
+
+// WithRetentionGranularity sets the unit which retention policies apply
+// to. With RetentionDirectory, the policies select from the per-interval
+// directories of the pattern, e.g.: "logs/20240101" of
+// "logs/%Y%m%d/app.log", which are removed entirely in one pass, instead of
+// globbing the files across all of them. New returns an error if the
+// parent directory of the pattern has no strftime token. An invalid
+// granularity returns an error.
+//
+// Default: RetentionFile
+func WithRetentionGranularity(g RetentionGranularity) Option {
+	return func(opts *Options) error {
+		if g < RetentionFile || g > RetentionDirectory {
+			return fmt.Errorf("logrotate: invalid retention granularity: %v", g)
+		}
+		opts.retentionGranularity = g
+		return nil
+	}
+}
+
+// WithFileIndex maintains an in-memory index of the log files, updated on
+// rotation and post-processing, so that a retention pass only lstats the
+// files changed since the previous pass, instead of globbing and lstating
+// all of them, e.g.: in huge log directories. The index is fully rescanned
+// at most every rescan interval, to pick up files created, modified or
+// removed by others. If rescan <= 0, the index is disabled.
+//
+// Default: 0 (disabled)
+func WithFileIndex(rescan time.Duration) Option {
+	return func(opts *Options) error {
+		opts.indexRescan = rescan
+		return nil
+	}
+}
+
+// WithVerifyBeforeDelete verifies each log file against the RemoteStore
+// which an archiver uploads it to, e.g.: an S3-compatible object store,
+// before retention policies, MaxAge or MaxBackups, or the QuotaGroup delete
+// it locally: the remote object must exist and match its size, and also its
+// SHA-256 checksum if known. Otherwise, the deletion is deferred to a later
+// pass, and a PurgeError wrapping ErrNotArchived is reported. With
+// RetentionDirectory, a directory is removed only if every file in it is
+// verified.
+//
+// Default: nil (not verified)
+func WithVerifyBeforeDelete(store RemoteStore) Option {
+	return func(opts *Options) error {
+		opts.remoteStore = store
+		return nil
+	}
+}
+
+// WithMinAgeBeforeDelete defers the deletion of log files modified within
+// d by retention policies, MaxAge or MaxBackups, or the QuotaGroup, e.g.: a
+// file rotated out a moment ago which a log shipper is still reading. The
+// deletion is retried on a later pass, and a PurgeError wrapping
+// ErrFileInUse is reported. It also applies to the files removed with
+// RetentionDirectory or DayBundle, and by PurgeDir. If d <= 0, files are
+// deleted regardless.
+//
+// Default: 0 (disabled)
+func WithMinAgeBeforeDelete(d time.Duration) Option {
+	return func(opts *Options) error {
+		opts.minDeleteAge = d
+		return nil
+	}
+}
+
+// WithOpenFileCheck defers the deletion of log files held open by the
+// processes in scope c, as fuser does, so a file is never yanked from under
+// a reader. The deletion is retried on a later pass, and a PurgeError
+// wrapping ErrFileInUse is reported. It also applies to the files removed
+// with RetentionDirectory or DayBundle, and by PurgeDir. Only supported on
+// Linux, where the file descriptors are read from /proc; it's a no-op on
+// other platforms.
+//
+// NOTE: with OpenCheckAll, each deletion scans the file descriptors of all
+// the processes, which may be slow on hosts running many of them.
+//
+// Default: OpenCheckNone
+func WithOpenFileCheck(c OpenFileCheck) Option {
+	return func(opts *Options) error {
+		if c < OpenCheckNone || c > OpenCheckAll {
+			return fmt.Errorf("logrotate: invalid open file check %v", c)
+		}
+		opts.openFileCheck = c
+		return nil
+	}
+}
+
+// WithFallbackWriter sets the writer to write to when the filesystem turns
+// read-only (e.g.: on filesystem corruption), such as os.Stderr. In the
+// read-only mode, the logger probes for recovery on a backoff schedule
+// instead of reopening files on every write. If not set, Write returns
+// ErrReadOnly in the read-only mode.
+//
+// Default: nil
+func WithFallbackWriter(w io.Writer) Option {
+	return func(opts *Options) error {
+		opts.fallback = w
+		return nil
+	}
+}
+
+// WithMirror duplicates every accepted write to the secondary Logger, e.g.:
+// rotating on a different disk, so that critical audit logs survive a
+// single-disk failure without an external tee. The secondary fails
+// independently: its failures never fail the writes of the Logger, but are
+// reported to the ErrorHandler of the secondary, and counted in
+// Metrics.MirrorErrors. The secondary is owned by the caller, which should
+// close it after the Logger.
+//
+// NOTE: unbuffered writes also wait for the secondary, so make the
+// secondary buffered to decouple them from its disk.
+//
+// Default: nil
+func WithMirror(secondary *Logger) Option {
+	return func(opts *Options) error {
+		if secondary == nil {
+			return errors.New("logrotate: nil mirror")
+		}
+		opts.mirror = secondary
+		return nil
+	}
+}
+
+// WithSyslog forwards each line written to the files to the local syslog
+// or journald via its datagram socket, e.g.: /dev/log, with facility and
+// tag, so hosts relying on system log collection get a copy without
+// double-writing in the application. If tag is empty, it's the program
+// name.
+//
+// Lines are forwarded in the background as informational messages,
+// decoupled from the writes: they are dropped if the daemon falls behind,
+// or is unavailable, and counted in Metrics.ForwardErrors. The remaining
+// lines are forwarded on Close.
+//
+// Default: disabled
+func WithSyslog(facility Facility, tag string) Option {
+	return func(opts *Options) error {
+		if facility < FacilityKern || facility > FacilityLocal7 {
+			return fmt.Errorf("logrotate: invalid syslog facility %d", facility)
+		}
+		if tag == "" {
+			tag = filepath.Base(os.Args[0])
+		}
+		opts.syslogFacility = facility
+		opts.syslogTag = tag
+		return nil
+	}
+}
+
+// WithErrorHandler sets the handler called on errors which can't be
+// returned to the caller of Write, e.g.: ErrReadOnly when the filesystem
+// turns read-only. The handler is never called with the internal lock held,
+// so it's safe to write to the Logger itself. If not set, errors are traced
+// to os.Stderr.
+//
+// Default: nil
+func WithErrorHandler(handler func(err error)) Option {
+	return func(opts *Options) error {
+		opts.errorHandler = handler
+		return nil
+	}
+}
+
+// WithWriteTimeout sets the max duration of a file write. If > 0, file
+// writes are performed in a supervised goroutine, so that a write hanging on
+// a hung filesystem (e.g.: NFS or FUSE mounts) fails with ErrWriteTimeout
+// instead of blocking the caller, and the logger turns degraded. While
+// degraded, writes fail fast with ErrWriteTimeout without piling up
+// goroutines, until the hung write completes.
+//
+// NOTE: a timed out write is indeterminate: it's not canceled, and may still
+// land in the file after Write returns, so callers which retry on errors,
+// e.g.: bufio.Writer wrappers, may duplicate the entry. Each write costs a
+// copy of the buffer, as the hung write may still read it after Write
+// returns, a goroutine and a timer.
+//
+// Default: 0 (no timeout)
+func WithWriteTimeout(d time.Duration) Option {
+	return func(opts *Options) error {
+		opts.writeTimeout = d
+		return nil
+	}
+}
+
+// WithPartialWriteRecovery sets whether the remainder of a write which
+// failed part way, e.g.: on ENOSPC, is written to the log file reopened
+// after the failure. If the remainder is written entirely, Write returns
+// the full length and no error, and ErrPartialWrite is reported to the
+// ErrorHandler instead. Otherwise, Write returns the bytes written to both
+// files, so callers like bufio.Writer neither write the head again nor
+// lose the tail. It doesn't apply to read-only filesystems and write
+// timeouts, after which the file may still be written.
+//
+// Default: false
+func WithPartialWriteRecovery(enable bool) Option {
+	return func(opts *Options) error {
+		opts.partialWrite = enable
+		return nil
+	}
+}
+
+// WithSizeReconcileInterval sets the interval to reconcile the tracked size
+// of the current file with its real size by stat, so that MaxSize is still
+// enforced in time when other processes append to the same file. The file is
+// already stat-ed on each write, so reconciliation costs no extra syscalls.
+//
+// Default: 0 (never reconcile)
+func WithSizeReconcileInterval(d time.Duration) Option {
+	return func(opts *Options) error {
+		opts.sizeReconcileInterval = d
+		return nil
 	}
 }
 
@@ -127,7 +1473,214 @@ func WithMaxBackups(n int) Option {
 //
 // Default: 0
 func WithWriteChan(size int) Option {
-	return func(opts *Options) {
+	return func(opts *Options) error {
 		opts.writeChSize = size
+		return nil
+	}
+}
+
+// WithAutoTune tunes the effective bound of the queue in buffered mode
+// adaptively between 1/16 of writeChSize and writeChSize, see WithWriteChan.
+// Every second, the bound is doubled if writes were rejected by the full
+// queue, to reduce loss during bursts, or halved if the queue is mostly
+// empty and drained promptly, to reduce the memory held by queued entries
+// during quiet periods. The bound starts at writeChSize, and is reported
+// by Metrics.QueueBound.
+//
+// It only takes effect with the default queue, see WithQueue.
+//
+// Default: false
+func WithAutoTune(enable bool) Option {
+	return func(opts *Options) error {
+		opts.autoTune = enable
+		return nil
+	}
+}
+
+// WithQueue sets the queue of buffered writes, e.g.: a lock-free MPSC ring
+// or a disk-backed queue, instead of the default one backed by a channel of
+// size writeChSize.
+//
+// It only takes effect in buffered mode, see WithWriteChan. writeChSize
+// still bounds the lane of PriorityHigh writes, and the shedding of
+// PriorityLow writes by the Len of q.
+//
+// Default: nil (a channel of size writeChSize)
+func WithQueue(q Queue) Option {
+	return func(opts *Options) error {
+		opts.queue = q
+		return nil
+	}
+}
+
+// WithSpillDir sets the directory to spill buffered writes to when the
+// queue is full, instead of discarding them. The spilled writes are persisted
+// to a temporary spill file in dir, and replayed into the log in order when
+// the queue drains, so the data loss under pressure turns into delayed
+// delivery for audit-grade logs. The spill file is removed on Close, after
+// the remaining spilled writes are replayed.
+//
+// NOTE: spill files are not recovered on restart, so the spilled writes not
+// yet replayed are lost if the process crashes, and the orphan
+// "logrotate-*.spill" files are left in dir, which are safe to remove once
+// no Logger is using dir.
+//
+// It only takes effect in buffered mode, see WithWriteChan. TryWrite never
+// spills.
+//
+// Default: "" (discard on overflow)
+func WithSpillDir(dir string) Option {
+	return func(opts *Options) error {
+		opts.spillDir = dir
+		return nil
+	}
+}
+
+// WithDroppedSamples keeps the first size bytes of up to n most recent
+// discarded writes, retrievable by Logger.DroppedSamples, so that developers
+// can see what kind of traffic is being lost. If size <= 0, 256 bytes are
+// kept.
+//
+// Default: 0 (disabled)
+func WithDroppedSamples(n, size int) Option {
+	return func(opts *Options) error {
+		if size <= 0 {
+			size = 256
+		}
+		opts.samples = n
+		opts.sampleSize = size
+		return nil
+	}
+}
+
+// WithSavelog enables the Debian savelog compatibility mode: on rotation, the
+// current file is renamed to "<name>.0", and the older ones are shifted to
+// "<name>.1", "<name>.2", ..., up to "<name>.<cycle-1>", with the oldest one
+// removed. If compress is true, the files numbered from 1 are compressed as
+// "<name>.1.gz" and so on by the mill goroutine. So existing log collection
+// scripts expecting that layout keep working.
+//
+// NOTE: it's intended for patterns without time format (e.g.: "app.log"),
+// as files are numbered per current filename. Post-rotation processors
+// don't apply to the numbered files. Retention policies, e.g.: MaxAge, still
+// apply to them, and the gaps left are closed by the next shift, so the
+// files stay numbered in order.
+//
+// Default: 0 (disabled)
+func WithSavelog(cycle int, compress bool) Option {
+	return func(opts *Options) error {
+		opts.savelogCycle = cycle
+		opts.savelogCompress = compress
+		return nil
+	}
+}
+
+// WithDayBundle bundles the log files of each past day into one archive in
+// format, e.g.: BundleZip, named after the first file of the day without
+// the sequence suffix, e.g.: "app.log.20240101.zip", or with the day
+// appended if the pattern has no time verbs, e.g.: "app.log.2024-01-01.zip",
+// and removes the originals, keeping directories tidy when MaxSize causes
+// many small files per day. It's done in the mill goroutine on the first
+// pass after the day rolls over, after the post-rotation processors, and
+// retention applies to the bundles as to log files. A day is bundled only
+// if all the originals can be deleted, see WithMinAgeBeforeDelete,
+// WithOpenFileCheck and WithVerifyBeforeDelete. It can't be combined with
+// WithSavelog.
+//
+// Default: BundleNone
+func WithDayBundle(format BundleFormat) Option {
+	return func(opts *Options) error {
+		if format < BundleNone || format > BundleTarGz {
+			return fmt.Errorf("logrotate: invalid bundle format %v", format)
+		}
+		opts.dayBundle = format
+		return nil
+	}
+}
+
+// WithPartialSweep sweeps the partial artifacts left by operations killed
+// midway, e.g.: "app.log.1.gz.tmp" of compression, "app.log.symlink#1.2" of
+// symlink creation, or the ".tmp" files of the journal, state file and
+// segment index, once they are older than maxAge, so directories don't
+// accumulate junk. A partial compression whose source still exists is
+// resumed, by queueing the source for post-rotation processing again. The
+// sweep runs in the mill goroutine on startup and every maxAge thereafter.
+// Younger artifacts are left alone, as they may still be in progress, e.g.:
+// by another process.
+//
+// Default: 0 (disabled)
+func WithPartialSweep(maxAge time.Duration) Option {
+	return func(opts *Options) error {
+		if maxAge <= 0 {
+			return fmt.Errorf("logrotate: invalid partial sweep age %v", maxAge)
+		}
+		opts.partialSweep = maxAge
+		return nil
+	}
+}
+
+// WithHashSuffix appends a short content hash of n hex digits to the name of
+// each rotated log file, e.g.: app.20240101.log → app.20240101.log.abc123,
+// making rotated files immutable by name for ingestion systems which dedupe
+// by filename. It runs before other post-rotation processors, and retention
+// policies still apply to the renamed files.
+//
+// Default: 0 (disabled)
+func WithHashSuffix(n int) Option {
+	return func(opts *Options) error {
+		opts.hashSuffix = n
+		return nil
+	}
+}
+
+// WithPostRotateProcessors sets the processors which are applied in order
+// to each rotated log file by the mill goroutine, e.g.: compression →
+// checksum → upload → delete. Each processor receives the path returned by
+// the previous one.
+//
+// Default: no processors
+func WithPostRotateProcessors(p ...Processor) Option {
+	return func(opts *Options) error {
+		opts.processors = append(opts.processors, p...)
+		return nil
+	}
+}
+
+// WithProcessorRetry sets the max retries of a failed post-rotation
+// processor, and the initial backoff between retries. The backoff is
+// doubled after each retry. If maxRetries <= 0, a failed processor is
+// not retried.
+//
+// Default: 0 retries, 1 second backoff
+func WithProcessorRetry(maxRetries int, backoff time.Duration) Option {
+	return func(opts *Options) error {
+		opts.processRetries = maxRetries
+		opts.processBackoff = backoff
+		return nil
+	}
+}
+
+// WithMillConcurrency sets the max number of rotated log files processed
+// in parallel by post-rotation processors. If n <= 0, it is treated as 1.
+//
+// Default: 1
+func WithMillConcurrency(n int) Option {
+	return func(opts *Options) error {
+		opts.millConcurrency = n
+		return nil
+	}
+}
+
+// WithMillRateLimit sets the max IO bandwidth in bytes per second shared
+// by all post-rotation processors, so compression or upload of large
+// rotated files won't starve the disk. Processors should read the rotated
+// files through ThrottleReader to be limited. If bytesPerSecond <= 0, that
+// means no limit.
+//
+// Default: 0
+func WithMillRateLimit(bytesPerSecond int64) Option {
+	return func(opts *Options) error {
+		opts.millRateLimit = bytesPerSecond
+		return nil
 	}
 }
@@ -0,0 +1,111 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lestrrat-go/strftime"
+)
+
+// patternFuncRegexp matches the template function tokens in a pattern,
+// e.g.: "%{hostname}" or "%{env:POD_NAME}".
+var patternFuncRegexp = regexp.MustCompile(`%\{([^}]*)\}`)
+
+// builtinPatternFunc returns the built-in template function named name:
+// "hostname", "pid", and "env:NAME" for the environment variable NAME.
+func builtinPatternFunc(name string) (func() string, bool) {
+	switch {
+	case name == "hostname":
+		return func() string {
+			hostname, _ := os.Hostname()
+			return hostname
+		}, true
+	case name == "pid":
+		return func() string { return strconv.Itoa(os.Getpid()) }, true
+	case strings.HasPrefix(name, "env:"):
+		key := strings.TrimPrefix(name, "env:")
+		return func() string { return os.Getenv(key) }, true
+	}
+	return nil, false
+}
+
+// funcPattern is a strftime pattern with template function tokens, which
+// are evaluated at rotation time.
+type funcPattern struct {
+	raw   string
+	funcs map[string]func() string // by token name
+
+	mu       sync.Mutex // guards following
+	expanded string     // raw with the tokens expanded last time
+	compiled *strftime.Strftime
+}
+
+// newFuncPattern returns the funcPattern of the pattern, with the custom
+// template functions funcs in addition to the built-in ones, or nil if the
+// pattern has no tokens. It returns an error if a token has no function.
+func newFuncPattern(pattern string, funcs map[string]func() string) (*funcPattern, error) {
+	matches := patternFuncRegexp.FindAllStringSubmatch(pattern, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	p := &funcPattern{raw: pattern, funcs: make(map[string]func() string)}
+	for _, m := range matches {
+		name := m[1]
+		if fn, ok := funcs[name]; ok {
+			p.funcs[name] = fn
+		} else if fn, ok := builtinPatternFunc(name); ok {
+			p.funcs[name] = fn
+		} else {
+			return nil, fmt.Errorf("unknown pattern func %q", name)
+		}
+	}
+	if _, err := p.compile(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compile evaluates the template functions, and returns the strftime
+// pattern with their values, which is compiled again only if changed.
+func (p *funcPattern) compile() (*strftime.Strftime, error) {
+	expanded := patternFuncRegexp.ReplaceAllStringFunc(p.raw, func(token string) string {
+		name := token[2 : len(token)-1]
+		// the value is literal, even if it contains '%'.
+		return strings.ReplaceAll(p.funcs[name](), "%", "%%")
+	})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.compiled != nil && expanded == p.expanded {
+		return p.compiled, nil
+	}
+	compiled, err := newStrftime(expanded)
+	if err != nil {
+		return nil, err
+	}
+	p.expanded, p.compiled = expanded, compiled
+	return compiled, nil
+}
+
+// genBaseFilename generates the base filename of the pattern at
+// rotationTime, evaluating the template functions if any.
+func (l *Logger) genBaseFilename(rotationTime int64) string {
+	pattern := l.pattern
+	if l.funcPattern != nil {
+		if compiled, err := l.funcPattern.compile(); err == nil {
+			pattern = compiled
+		}
+	}
+	return genBaseFilename(pattern, l.opts.clock, rotationTime)
+}
+
+// patternString returns the pattern of l as provided to New.
+func (l *Logger) patternString() string {
+	if l.funcPattern != nil {
+		return l.funcPattern.raw
+	}
+	return l.pattern.Pattern()
+}
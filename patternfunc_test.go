@@ -0,0 +1,52 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PatternFuncs(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PatternFuncs")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "%{unknown}.log"))
+	require.Error(t, err, "New should fail with an unknown pattern func")
+
+	t.Setenv("LOGROTATE_POD", "pod-0")
+	shard := "a"
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "%{shard}.%{env:LOGROTATE_POD}.%{pid}.%Y%m%d.log"),
+		WithClock(clock),
+		WithMaxInterval(24*time.Hour),
+		WithPatternFuncs(map[string]func() string{"shard": func() string { return shard }}),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, filepath.Join(dir, "*.*.*.*.log*"), l.globPattern, "tokens should be wildcards in glob pattern")
+	require.Equal(t, 1, l.Options().PatternFuncs, "snapshot should count the custom funcs")
+
+	pid := strconv.Itoa(os.Getpid())
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, filepath.Join(dir, "a.pod-0."+pid+".20240101.log"), l.currFilename, "tokens should be evaluated")
+
+	shard = "b%d"
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, filepath.Join(dir, "a.pod-0."+pid+".20240101.log"), l.currFilename, "tokens should not be evaluated before rotation")
+
+	clock.Advance(24 * time.Hour)
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, filepath.Join(dir, "b%d.pod-0."+pid+".20240102.log"), l.currFilename, "tokens should be evaluated at rotation time literally")
+
+	_, _, ok := MatchPattern(filepath.Join(dir, "%{shard}.%{pid}.%Y%m%d.log"), filepath.Join(dir, "c.1.20240103.log"))
+	require.True(t, ok, "tokens should match any value")
+}
@@ -0,0 +1,111 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultFileMode fs.FileMode = 0644
+	defaultDirMode  fs.FileMode = 0755
+)
+
+// mkdirAll creates the directory dir along with any necessary parents, the
+// same as os.MkdirAll. If InheritPermissions is enabled, each created
+// directory copies the mode and ownership of its parent directory. The
+// CreateHook is called on each created directory.
+func (l *Logger) mkdirAll(dir string) error {
+	if !l.opts.inheritPerm && l.opts.createHook == nil {
+		return os.MkdirAll(dir, defaultDirMode)
+	}
+
+	fi, err := os.Stat(dir)
+	if err == nil {
+		if !fi.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	parent := filepath.Dir(dir)
+	if parent == dir {
+		return err // root
+	}
+	if err := l.mkdirAll(parent); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, defaultDirMode); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil // created by others in the meantime
+		}
+		return err
+	}
+	return l.applyPermissions(dir, true)
+}
+
+// applyPermissions copies the mode and ownership of the parent directory to
+// the created file or directory at path if InheritPermissions is enabled,
+// and then calls the CreateHook.
+func (l *Logger) applyPermissions(path string, isDir bool) error {
+	if l.opts.inheritPerm {
+		parent, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("stat parent directory: %w", err)
+		}
+		mode := parent.Mode().Perm()
+		if !isDir {
+			// regular files should not be executable
+			mode &^= 0111
+		}
+		// chmod explicitly, as the mode passed on creation is masked by umask.
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+		if err := inheritOwner(path, parent); errors.Is(err, fs.ErrPermission) {
+			// keep logging with the owner of the process, e.g.: a service
+			// user in the group of a "root:adm" directory.
+			l.warn(&RotationError{Op: "chown", Path: path, Err: err})
+		} else if err != nil {
+			return err
+		}
+	}
+	if l.opts.createHook != nil {
+		if err := l.opts.createHook(path); err != nil {
+			return fmt.Errorf("create hook: %w", err)
+		}
+	}
+	return nil
+}
+
+// lchown and geteuid are replaced in tests.
+var (
+	lchown  = os.Lchown
+	geteuid = os.Geteuid
+)
+
+// inheritOwner changes the owner of the created file or directory at path
+// to the one of parent if they differ. Only the group is changed unless
+// the process runs as root, as others can't give files away.
+func inheritOwner(path string, parent fs.FileInfo) error {
+	puid, pgid, ok := fileOwner(parent)
+	if !ok {
+		return nil
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	uid, gid, _ := fileOwner(fi)
+	if geteuid() != 0 || uid == puid {
+		puid = -1
+	}
+	if gid == pgid {
+		pgid = -1
+	}
+	if puid == -1 && pgid == -1 {
+		return nil
+	}
+	return lchown(path, puid, pgid)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package logrotate
+
+import (
+	"io/fs"
+)
+
+// fileOwner is not supported on this platform.
+func fileOwner(fi fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,105 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InheritPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file mode bits are not supported on windows")
+	}
+	dir := filepath.Join(baseLogDir, "Test_InheritPermissions")
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	require.NoError(t, os.Chmod(dir, 0770), "Chmod should succeed")
+
+	var mu sync.Mutex
+	var created []string
+	l, err := New(
+		filepath.Join(dir, "nested", "app.log"),
+		WithInheritPermissions(true),
+		WithCreateHook(func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			created = append(created, path)
+			return nil
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	_, err = l.Write([]byte("Hello, World"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	fi, err := os.Stat(filepath.Join(dir, "nested"))
+	require.NoError(t, err, "Stat should succeed")
+	require.Equal(t, os.FileMode(0770), fi.Mode().Perm(), "directory mode should be inherited")
+
+	fi, err = os.Stat(filepath.Join(dir, "nested", "app.log"))
+	require.NoError(t, err, "Stat should succeed")
+	require.Equal(t, os.FileMode(0660), fi.Mode().Perm(), "file mode should be inherited without executable bits")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{
+		filepath.Join(dir, "nested"),
+		filepath.Join(dir, "nested", "app.log"),
+	}, created, "create hook should be called on created directory and file")
+}
+
+func Test_InheritPermissions_ForeignOwner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on windows")
+	}
+	dir := filepath.Join(baseLogDir, "Test_InheritPermissions_ForeignOwner")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	// e.g.: a "root:adm" directory, with the service user in "adm".
+	if err := os.Chown(dir, 12345, 23456); err != nil {
+		t.Skipf("chown requires privileges: %v", err)
+	}
+
+	type chown struct{ uid, gid int }
+	var chowns []chown
+	defer func(f func(string, int, int) error, g func() int) { lchown, geteuid = f, g }(lchown, geteuid)
+	geteuid = func() int { return 1000 }
+	lchown = func(path string, uid, gid int) error {
+		chowns = append(chowns, chown{uid, gid})
+		return &os.PathError{Op: "lchown", Path: path, Err: os.ErrPermission}
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithInheritPermissions(true),
+		WithErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	_, err = l.Write([]byte("Hello, World"))
+	require.NoError(t, err, "Write should succeed despite the failed chown")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "Hello, World", string(b), "file should be written")
+	require.Equal(t, []chown{{-1, 23456}}, chowns, "only the group should be changed by non-root")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, errs, 1, "failed chown should be reported")
+	var rerr *RotationError
+	require.ErrorAs(t, errs[0], &rerr, "error should be a RotationError")
+	require.Equal(t, "chown", rerr.Op, "RotationError op should match")
+	require.ErrorIs(t, rerr, os.ErrPermission, "error should wrap the permission error")
+}
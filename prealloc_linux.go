@@ -0,0 +1,21 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE, which allocates space without
+// changing the file size, so appends still start at the end of data.
+const fallocKeepSize = 0x1
+
+// preallocate allocates size bytes of disk space for f by fallocate. It's a
+// no-op if the filesystem doesn't support it.
+func preallocate(f *os.File, size int64) error {
+	err := syscall.Fallocate(int(f.Fd()), fallocKeepSize, 0, size)
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Preallocate(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Preallocate")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithMaxSize(64*1024), WithPreallocate(1024*1024))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, int64(64*1024), l.preallocateSize(), "preallocate size should be capped by MaxSize")
+
+	_, err = l.Write([]byte("Hello"))
+	require.NoError(t, err, "Write should succeed")
+	fi, err := os.Stat(filename)
+	require.NoError(t, err, "Stat should succeed")
+	require.Equal(t, int64(5), fi.Size(), "file size should not include preallocated space")
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Blocks > 8 {
+		// fallocate supported by the filesystem
+		require.GreaterOrEqual(t, st.Blocks*512, int64(64*1024), "space should be preallocated")
+	}
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "Hello", string(data), "content should match")
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package logrotate
+
+import (
+	"os"
+)
+
+// preallocate is a no-op, as fallocate is not supported on this platform.
+func preallocate(f *os.File, size int64) error {
+	return nil
+}
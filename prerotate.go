@@ -0,0 +1,89 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RotateReason is the reason of a rotation, which is passed to the
+// PreRotateHook.
+type RotateReason int
+
+const (
+	// RotateBySize rotates as the write would put the file over MaxSize.
+	RotateBySize RotateReason = iota + 1
+	// RotateByLines rotates as the write would put the file over MaxLines.
+	RotateByLines
+	// RotateByInterval rotates at the MaxInterval boundary.
+	RotateByInterval
+	// RotateByPredicate rotates as the RotatePredicate returned true.
+	RotateByPredicate
+	// RotateByRequest rotates as requested by Rotate, RotateAsync or
+	// RotateAndGet.
+	RotateByRequest
+	// RotateByRecovery rotates the existing file which can't be resumed on
+	// open, e.g.: a stale active file, or an unreadable one with a
+	// RecoveryMode set. It's never passed to the PreRotateHook.
+	RotateByRecovery
+
+	rotateReasons // upper bound of the reasons
+)
+
+// String returns the name of the reason.
+func (r RotateReason) String() string {
+	switch r {
+	case RotateBySize:
+		return "size"
+	case RotateByLines:
+		return "lines"
+	case RotateByInterval:
+		return "interval"
+	case RotateByPredicate:
+		return "predicate"
+	case RotateByRequest:
+		return "request"
+	case RotateByRecovery:
+		return "recovery"
+	default:
+		return fmt.Sprintf("RotateReason(%d)", int(r))
+	}
+}
+
+// parseRotateReason returns the reason named s by String, or 0 if unknown.
+func parseRotateReason(s string) RotateReason {
+	for r := RotateReason(1); r < rotateReasons; r++ {
+		if r.String() == s {
+			return r
+		}
+	}
+	return 0
+}
+
+// rotateFor rotates the current file for reason, unless the PreRotateHook
+// vetoes it, in which case an error wrapping ErrRotationVetoed is returned.
+// Once rotations have been vetoed for MaxRotateDefer, the hook is bypassed
+// so that rotation is never blocked forever. It returns whether rotated.
+// l.mu must be held by the caller.
+func (l *Logger) rotateFor(reason RotateReason) (bool, error) {
+	hook := l.opts.preRotateHook
+	if h := l.file.Load(); hook != nil && h != nil {
+		now := l.opts.clock.Now()
+		if l.vetoedSince.IsZero() || now.Sub(l.vetoedSince) < l.opts.maxRotateDefer {
+			if err := hook(reason, h.name); err != nil {
+				if l.vetoedSince.IsZero() {
+					l.vetoedSince = now
+				}
+				return false, fmt.Errorf("%w: %s: %w", ErrRotationVetoed, reason, err)
+			}
+		}
+	}
+	l.vetoedSince = time.Time{}
+	return true, l.rotate(reason)
+}
+
+// isVetoed reports whether err is a rotation vetoed by the PreRotateHook,
+// which is deferred rather than failed.
+func isVetoed(err error) bool {
+	return errors.Is(err, ErrRotationVetoed)
+}
@@ -0,0 +1,73 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PreRotateHook(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PreRotateHook")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	_, err := New(filename, WithPreRotateHook(func(RotateReason, string) error { return nil }, 0))
+	require.Error(t, err, "New should fail without max defer")
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	exporting := false
+	var reasons []RotateReason
+	var currents []string
+	l, err := New(
+		filename,
+		WithClock(clock),
+		WithMaxSize(4),
+		WithPreRotateHook(func(reason RotateReason, current string) error {
+			reasons = append(reasons, reason)
+			currents = append(currents, current)
+			if exporting {
+				return errors.New("exporting")
+			}
+			return nil
+		}, time.Minute),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.True(t, l.Options().HasPreRotateHook, "HasPreRotateHook should be set")
+	require.Equal(t, time.Minute, l.Options().MaxRotateDefer, "MaxRotateDefer should match")
+	write := func(s string) {
+		_, err := l.Write([]byte(s))
+		require.NoError(t, err, "Write should succeed")
+	}
+	readFile := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+
+	write("aaa")
+	exporting = true
+	write("bbb")
+	require.ErrorIs(t, l.Rotate(), ErrRotationVetoed, "Rotate should be vetoed")
+	require.Equal(t, "aaabbb", readFile(filename), "vetoed rotation should be deferred")
+	require.Equal(t, []RotateReason{RotateBySize, RotateByRequest}, reasons, "reasons should match")
+	require.Equal(t, []string{filename, filename}, currents, "current should match")
+
+	// bypassed after vetoed for max defer.
+	clock.Advance(2 * time.Minute)
+	write("ccc")
+	require.Equal(t, "ccc", readFile(filename+".1"), "rotation should be forced after max defer")
+
+	// vetoed again after the forced rotation.
+	write("dd")
+	require.Equal(t, "cccdd", readFile(filename+".1"), "rotation should be vetoed again")
+	exporting = false
+	write("e")
+	require.Equal(t, "e", readFile(filename+".2"), "rotation should not be vetoed")
+	require.Equal(t, "size", RotateBySize.String(), "String should match")
+}
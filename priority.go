@@ -0,0 +1,113 @@
+package logrotate
+
+import (
+	"fmt"
+	"io"
+)
+
+// Priority is the priority of log lines in buffered mode, so that important
+// lines are kept while less important ones are shed first under pressure.
+// In unbuffered mode, all log lines are written directly regardless of
+// their priorities.
+type Priority int
+
+const (
+	// PriorityLow log lines are shed first: discarded once the queue is
+	// half full, so that the remaining space is kept for more important
+	// lines, e.g.: DEBUG lines.
+	PriorityLow Priority = iota
+	// PriorityNormal log lines are discarded if the queue is full. It's the
+	// priority of Write.
+	PriorityNormal
+	// PriorityHigh log lines are never discarded, e.g.: ERROR lines. They
+	// are queued in a separate lane drained before the queue, and block if
+	// the lane is full.
+	PriorityHigh
+	numPriority
+)
+
+var priorityNames = [numPriority]string{"low", "normal", "high"}
+
+// String returns the name of p, e.g.: "high".
+func (p Priority) String() string {
+	if p < 0 || p >= numPriority {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// WritePriority is like Write, but writes b with priority prio in buffered
+// mode. It returns an error for an invalid priority.
+//
+// NOTE: PriorityHigh log lines are drained with strict priority, so they
+// may be written before the previously written lines of lower priorities,
+// and before the previously requested rotations take effect.
+func (l *Logger) WritePriority(b []byte, prio Priority) (n int, err error) {
+	switch prio {
+	case PriorityNormal:
+		return l.Write(b)
+	case PriorityLow, PriorityHigh:
+	default:
+		return 0, fmt.Errorf("invalid priority: %v", prio)
+	}
+	if len(l.opts.redactors) > 0 {
+		size := len(b)
+		b = l.redact(b)
+		defer func() { n = redactedLen(n, err, size) }()
+	}
+	if l.closed.Load() {
+		l.discard(&l.metrics.DiscardsClosed, len(b), b)
+		return 0, ErrClosed
+	}
+	if l.opts.mirror != nil {
+		defer l.mirror(&err, b)
+	}
+	if l.opts.writeChSize <= 0 {
+		return l.write(b)
+	}
+	if prio == PriorityHigh {
+		if !l.enqueueHigh(b) {
+			l.discard(&l.metrics.DiscardsClosed, len(b), b)
+			return 0, ErrClosed
+		}
+		return len(b), nil
+	}
+	if 2*l.queue.Len() >= l.queueBound() {
+		l.discard(&l.metrics.DiscardsQueueFull, len(b), b)
+		return 0, ErrDiscarded
+	}
+	if !l.enqueue(len(b), b) {
+		return 0, ErrDiscarded
+	}
+	return len(b), nil
+}
+
+// enqueueHigh copies b, and writes it to highCh, blocking until highCh has
+// space. It returns false if the Logger was closed.
+func (l *Logger) enqueueHigh(b []byte) bool {
+	l.opMu.RLock()
+	defer l.opMu.RUnlock()
+	if l.closed.Load() {
+		return false
+	}
+	copied := make([]byte, len(b))
+	copy(copied, b)
+	l.highCh <- writeOp{b: copied, t: l.opts.clock.Now().UnixNano()}
+	l.notifyWrite()
+	return true
+}
+
+// PriorityWriter returns an io.Writer which writes to l with priority prio,
+// so that each level of a leveled logger can be wired to its own lane.
+func (l *Logger) PriorityWriter(prio Priority) io.Writer {
+	return priorityWriter{l: l, prio: prio}
+}
+
+type priorityWriter struct {
+	l    *Logger
+	prio Priority
+}
+
+func (w priorityWriter) Write(b []byte) (int, error) {
+	return w.l.WritePriority(b, w.prio)
+}
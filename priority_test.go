@@ -0,0 +1,72 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WritePriority(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WritePriority")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(4))
+	require.NoError(t, err, "New should succeed")
+
+	_, err = l.WritePriority([]byte("1"), Priority(100))
+	require.Error(t, err, "WritePriority should fail with invalid priority")
+
+	// block writeLoop, so the lanes fill up.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+
+	_, err = l.WritePriority([]byte("n\n"), PriorityNormal)
+	require.NoError(t, err, "normal line should be queued")
+	_, err = l.WritePriority([]byte("l\n"), PriorityLow)
+	require.NoError(t, err, "low line should be queued below half full")
+	_, err = l.WritePriority([]byte("l\n"), PriorityLow)
+	require.ErrorIs(t, err, ErrDiscarded, "low line should be shed at half full")
+	_, err = l.Write([]byte("n\n"))
+	require.NoError(t, err, "normal line should be queued until full")
+	_, err = l.Write([]byte("n\n"))
+	require.NoError(t, err, "normal line should be queued until full")
+	_, err = l.Write([]byte("n\n"))
+	require.ErrorIs(t, err, ErrDiscarded, "normal line should be discarded when full")
+
+	w := l.PriorityWriter(PriorityHigh)
+	for i := 0; i < 4; i++ {
+		_, err = w.Write([]byte("h\n"))
+		require.NoError(t, err, "high line should be queued in its own lane")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := w.Write([]byte("h\n"))
+		require.NoError(t, err, "high line should block instead of being discarded")
+	}()
+	select {
+	case <-done:
+		t.Fatal("high line should block when its lane is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.True(t, strings.HasPrefix(string(data), "h\nh\nh\nh\nh\n"), "high lines should be written first, got %q", data)
+	require.Equal(t, 5, strings.Count(string(data), "h\n"), "high lines should never be discarded")
+	require.Equal(t, 4, strings.Count(string(data), "\n")-5, "queued lines should be written")
+	require.Equal(t, uint64(2), l.Metrics().DiscardedQueueFull, "shed lines should be counted")
+}
@@ -0,0 +1,291 @@
+package logrotate
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Processor processes a rotated log file after rotation. Processors are
+// chained in the mill goroutine: each one receives the path returned by the
+// previous one, so a pipeline like compression → checksum → upload → delete
+// can be built from small steps.
+type Processor interface {
+	// Process processes the file at path, and returns the path of the
+	// resulting file. An empty newPath means that the file no longer
+	// exists (e.g.: it was deleted), and the remaining processors are
+	// skipped.
+	Process(ctx context.Context, path string) (newPath string, err error)
+}
+
+// ProcessorFunc is an adapter to allow the use of ordinary functions as
+// Processor.
+type ProcessorFunc func(ctx context.Context, path string) (string, error)
+
+// Process calls f(ctx, path).
+func (f ProcessorFunc) Process(ctx context.Context, path string) (string, error) {
+	return f(ctx, path)
+}
+
+// NewGzipProcessor returns a Processor which compresses the rotated log
+// file to path+".gz", and then removes the original file.
+func NewGzipProcessor() Processor {
+	return ProcessorFunc(gzipFile)
+}
+
+// gzipFile compresses the file at path to path+".gz", and removes the
+// original file on success.
+func gzipFile(ctx context.Context, path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open logfile: %w", err)
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return "", fmt.Errorf("stat logfile: %w", err)
+	}
+
+	// compressed to a partial file first, so a crash never leaves a
+	// truncated .gz behind, see WithPartialSweep.
+	dstPath := path + ".gz"
+	tmpPath := dstPath + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return "", fmt.Errorf("open compressed logfile: %w", err)
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, ThrottleReader(ctx, src)); err == nil {
+		err = gz.Close()
+	}
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, dstPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("compress logfile: %w", err)
+	}
+	// keep the modification time, so retention still works as expected.
+	_ = os.Chtimes(dstPath, fi.ModTime(), fi.ModTime())
+
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("remove logfile: %w", err)
+	}
+	return dstPath, nil
+}
+
+// NewHashSuffixProcessor returns a Processor which appends a short content
+// hash (the first n hex digits of SHA-256) of the rotated log file to its
+// name, e.g.: app.20240101.log → app.20240101.log.abc123, making rotated
+// files immutable by name. The hash is appended after the full name, so the
+// renamed file still matches the glob of the pattern, and retention
+// policies still apply to it. If n <= 0 or n > 64, n is 64.
+func NewHashSuffixProcessor(n int) Processor {
+	if n <= 0 || n > sha256.Size*2 {
+		n = sha256.Size * 2
+	}
+	return ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("open logfile: %w", err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, ThrottleReader(ctx, f))
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("hash logfile: %w", err)
+		}
+		sum := hex.EncodeToString(h.Sum(nil))[:n]
+
+		newPath := path + "." + sum
+		if err := os.Rename(path, newPath); err != nil {
+			return "", fmt.Errorf("rename logfile: %w", err)
+		}
+		return newPath, nil
+	})
+}
+
+// processAllRotated processes all queued rotated files, with at most
+// MillConcurrency files processed in parallel.
+func (l *Logger) processAllRotated() {
+	paths := l.takeRotated()
+	if len(paths) == 0 {
+		return
+	}
+	concurrency := l.opts.millConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, path := range paths {
+		path := path
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := l.processRotated(path)
+			if err != nil {
+				l.handleError(&RotationError{Op: "process", Path: path, Err: err})
+			}
+			if err != nil && l.ctx.Err() != nil {
+				return // canceled by Close, so resumed on restart
+			}
+			// done even if failed, as the error is reported.
+			if err := l.journal.done(path); err != nil {
+				l.handleError(&RotationError{Op: "journal", Path: l.opts.journal, Err: err})
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// processRotated runs all post-rotation processors in order on the
+// rotated file at path. Each processor is retried with exponential backoff
+// as configured by WithProcessorRetry.
+func (l *Logger) processRotated(path string) error {
+	for _, p := range l.processors {
+		newPath, err := l.processWithRetry(p, path)
+		l.index.touch(path, newPath)
+		if err != nil {
+			l.metrics.ProcessErrors.Add(1)
+			return err
+		}
+		l.retrackMoved(path, newPath)
+		if newPath == "" {
+			break // file no longer exists
+		}
+		path = newPath
+	}
+	l.metrics.Processed.Add(1)
+	return nil
+}
+
+func (l *Logger) processWithRetry(p Processor, path string) (string, error) {
+	backoff := l.opts.processBackoff
+	for i := 0; ; i++ {
+		newPath, err := p.Process(l.ctx, path)
+		if err == nil || i >= l.opts.processRetries {
+			return newPath, err
+		}
+		l.metrics.ProcessRetries.Add(1)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-l.life.quit:
+			timer.Stop()
+			return "", err
+		}
+		backoff *= 2
+	}
+}
+
+// queueRotated queues the rotated file at path for post-rotation
+// processing in the mill goroutine.
+func (l *Logger) queueRotated(path string) {
+	if len(l.processors) == 0 {
+		return
+	}
+	l.rotatedMu.Lock()
+	l.rotated = append(l.rotated, path)
+	l.rotatedMu.Unlock()
+}
+
+// takeRotated takes all queued rotated files.
+func (l *Logger) takeRotated() []string {
+	l.rotatedMu.Lock()
+	defer l.rotatedMu.Unlock()
+	paths := l.rotated
+	l.rotated = nil
+	return paths
+}
+
+type rateLimiterKey struct{}
+
+// rateLimiter limits the IO bandwidth (in bytes per second) shared by all
+// post-rotation processors of a Logger.
+type rateLimiter struct {
+	rate int64 // bytes per second
+
+	mu   sync.Mutex // guards following
+	next time.Time  // time at which the next bytes can be consumed
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	return &rateLimiter{rate: rate}
+}
+
+// wait blocks until n bytes can be consumed, or ctx is done.
+func (r *rateLimiter) wait(ctx context.Context, n int) error {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	delay := r.next.Sub(now)
+	r.next = r.next.Add(time.Duration(int64(n) * int64(time.Second) / r.rate))
+	r.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// maxThrottleChunk is the max bytes read at once by a throttled reader, so
+// the bandwidth is smoothed even with a large read buffer.
+const maxThrottleChunk = 32 * 1024
+
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > maxThrottleChunk {
+		p = p[:maxThrottleChunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := t.limiter.wait(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// ThrottleReader returns a reader which reads from r with the IO bandwidth
+// limited by MillRateLimit, if ctx is the one passed to Processor.Process.
+// Otherwise, r is returned as is. Custom processors should wrap their
+// readers with it to share the IO bandwidth of the mill.
+func ThrottleReader(ctx context.Context, r io.Reader) io.Reader {
+	limiter, ok := ctx.Value(rateLimiterKey{}).(*rateLimiter)
+	if !ok || limiter == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, limiter: limiter}
+}
@@ -0,0 +1,179 @@
+package logrotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PostRotateProcessors(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PostRotateProcessors")
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	var processed []string
+	record := ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, path)
+		return path, nil
+	})
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithMaxSize(8),
+		WithPostRotateProcessors(NewGzipProcessor(), record),
+	)
+	require.NoError(t, err, "New should succeed")
+
+	l.Write([]byte("logfile1"))
+	l.Write([]byte("logfile2"))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	rotated := filepath.Join(dir, "app.log")
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{rotated + ".gz"}, processed, "processors should be chained in order")
+	require.NoFileExists(t, rotated, "rotated file should be removed after compressed")
+
+	f, err := os.Open(rotated + ".gz")
+	require.NoError(t, err, "Open should succeed")
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err, "gzip.NewReader should succeed")
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err, "ReadAll should succeed")
+	require.Equal(t, "logfile1", string(content), "compressed content should match")
+
+	metrics := l.Metrics()
+	require.Equal(t, uint64(1), metrics.Processed, "one rotated file should be processed")
+	require.Equal(t, uint64(0), metrics.ProcessErrors, "no process errors")
+}
+
+func Test_PostRotateProcessors_Retry(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PostRotateProcessors_Retry")
+	defer os.RemoveAll(dir)
+
+	var attempts atomic.Int32
+	failing := ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+		attempts.Add(1)
+		return "", errors.New("upload failed")
+	})
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithMaxSize(8),
+		WithPostRotateProcessors(failing),
+		WithProcessorRetry(2, time.Millisecond),
+	)
+	require.NoError(t, err, "New should succeed")
+
+	l.Write([]byte("logfile1"))
+	l.Write([]byte("logfile2"))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	require.Equal(t, int32(3), attempts.Load(), "processor should be retried twice")
+	metrics := l.Metrics()
+	require.Equal(t, uint64(2), metrics.ProcessRetries, "retries should be counted")
+	require.Equal(t, uint64(1), metrics.ProcessErrors, "process error should be counted")
+	require.Equal(t, uint64(0), metrics.Processed, "no rotated file should be processed")
+}
+
+func Test_MillConcurrency(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MillConcurrency")
+	defer os.RemoveAll(dir)
+
+	var running, maxRunning atomic.Int32
+	slow := ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return path, nil
+	})
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithPostRotateProcessors(slow),
+		WithMillConcurrency(2),
+	)
+	require.NoError(t, err, "New should succeed")
+
+	l.queueRotated("a")
+	l.queueRotated("b")
+	l.queueRotated("c")
+	l.queueRotated("d")
+	l.processAllRotated()
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	require.Equal(t, int32(2), maxRunning.Load(), "at most 2 files should be processed in parallel")
+	require.Equal(t, uint64(4), l.Metrics().Processed, "all rotated files should be processed")
+}
+
+func Test_ThrottleReader(t *testing.T) {
+	data := make([]byte, 1000)
+	ctx := context.WithValue(context.Background(), rateLimiterKey{}, newRateLimiter(10000))
+
+	start := time.Now()
+	r := ThrottleReader(ctx, bytes.NewReader(data))
+	for i := 0; i < 3; i++ {
+		_, err := io.Copy(io.Discard, r)
+		require.NoError(t, err, "Copy should succeed")
+		r = ThrottleReader(ctx, bytes.NewReader(data))
+	}
+	// 3000 bytes at 10000 bytes per second, the last 1000 bytes are not waited.
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond, "read should be throttled")
+
+	r = ThrottleReader(context.Background(), bytes.NewReader(data))
+	_, ok := r.(*bytes.Reader)
+	require.True(t, ok, "reader should not be throttled without rate limiter")
+}
+
+func Test_HashSuffix(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_HashSuffix")
+	defer os.RemoveAll(dir)
+
+	var processed atomic.Value
+	record := ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+		processed.Store(path)
+		return path, nil
+	})
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithMaxSize(8),
+		WithHashSuffix(8),
+		WithPostRotateProcessors(record),
+	)
+	require.NoError(t, err, "New should succeed")
+
+	l.Write([]byte("logfile1"))
+	l.Write([]byte("logfile2"))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	sum := sha256.Sum256([]byte("logfile1"))
+	want := filepath.Join(dir, "app.log."+hex.EncodeToString(sum[:])[:8])
+	require.Equal(t, want, processed.Load(), "hash suffix should be appended before other processors")
+	data, err := os.ReadFile(want)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "logfile1", string(data), "content should match")
+	matched, err := filepath.Match(l.globPattern, want)
+	require.NoError(t, err, "Match should succeed")
+	require.True(t, matched, "renamed file should match the glob, so retention applies")
+}
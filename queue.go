@@ -0,0 +1,67 @@
+package logrotate
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// QueueEntry is a log line queued in buffered mode.
+type QueueEntry struct {
+	Data []byte    // copy of the log line, owned by the queue
+	Time time.Time // when the log line was enqueued
+}
+
+// Queue is the queue of log lines between writers and the writeLoop
+// goroutine in buffered mode. Enqueue is called by writers concurrently,
+// while Dequeue is only called by the writeLoop goroutine, so it can be a
+// multi-producer single-consumer queue. Both must never block.
+type Queue interface {
+	// Enqueue adds e to the tail of the queue. It returns false if the
+	// queue is full, then the log line is spilled or discarded.
+	Enqueue(e QueueEntry) bool
+	// Dequeue removes and returns the entry at the head of the queue. It
+	// returns false if the queue is empty.
+	Dequeue() (QueueEntry, bool)
+	// Len returns the count of entries in the queue.
+	Len() int
+	// Bytes returns the total length of entries' Data in the queue.
+	Bytes() int64
+}
+
+// chanQueue is the default Queue backed by a buffered channel.
+type chanQueue struct {
+	ch    chan QueueEntry
+	bytes atomic.Int64
+}
+
+func newChanQueue(size int) *chanQueue {
+	return &chanQueue{ch: make(chan QueueEntry, size)}
+}
+
+func (q *chanQueue) Enqueue(e QueueEntry) bool {
+	select {
+	case q.ch <- e:
+		q.bytes.Add(int64(len(e.Data)))
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *chanQueue) Dequeue() (QueueEntry, bool) {
+	select {
+	case e := <-q.ch:
+		q.bytes.Add(-int64(len(e.Data)))
+		return e, true
+	default:
+		return QueueEntry{}, false
+	}
+}
+
+func (q *chanQueue) Len() int {
+	return len(q.ch)
+}
+
+func (q *chanQueue) Bytes() int64 {
+	return q.bytes.Load()
+}
@@ -0,0 +1,94 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sliceQueue is an unbounded Queue backed by a slice.
+type sliceQueue struct {
+	mu      sync.Mutex
+	entries []QueueEntry
+	bytes   int64
+}
+
+func (q *sliceQueue) Enqueue(e QueueEntry) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entries = append(q.entries, e)
+	q.bytes += int64(len(e.Data))
+	return true
+}
+
+func (q *sliceQueue) Dequeue() (QueueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return QueueEntry{}, false
+	}
+	e := q.entries[0]
+	q.entries = q.entries[1:]
+	q.bytes -= int64(len(e.Data))
+	return e, true
+}
+
+func (q *sliceQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+func (q *sliceQueue) Bytes() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bytes
+}
+
+func Test_WithQueue(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WithQueue")
+	defer os.RemoveAll(dir)
+
+	q := &sliceQueue{}
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithWriteChan(1),
+		WithQueue(q),
+	)
+	require.NoError(t, err, "New should succeed")
+	require.True(t, l.Options().HasQueue, "HasQueue should be set")
+
+	// block writeLoop, so the writes are queued.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+
+	for i := 0; i < 10; i++ {
+		_, err = l.Write([]byte("1"))
+		require.NoError(t, err, "Write should not be discarded by the unbounded queue")
+	}
+	require.Equal(t, 10, q.Len(), "writes should be queued in the custom queue")
+	require.Equal(t, int64(10), l.Metrics().QueuedBytes, "QueuedBytes should match")
+
+	// Rotate should take effect after the queued writes.
+	done := l.RotateAsync()
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+	close(release)
+	require.NoError(t, <-done, "Rotate should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "1111111111", string(data), "writes before Rotate should be in the rotated file")
+	data, err = os.ReadFile(filepath.Join(dir, "app.log.1"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "2", string(data), "writes after Rotate should be in the new file")
+	require.Zero(t, l.Metrics().QueuedBytes, "queue should be drained")
+}
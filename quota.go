@@ -0,0 +1,135 @@
+package logrotate
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// QuotaGroup enforces a combined max total size of the log files of the
+// Loggers sharing it, e.g.: error.log, access.log and audit.log writing to
+// the same directory. When the combined total size is over the quota, the
+// oldest files of the Loggers with the lowest priority are removed first,
+// and among Loggers with the same priority, of the one using the most
+// relative to its weight. The current file and the Symlink targets of each
+// Logger are never removed.
+//
+// The quota is enforced by the mill goroutine of each Logger after its own
+// retention policies are applied.
+type QuotaGroup struct {
+	maxTotalSize int64
+
+	mu      sync.Mutex // guards following, and serializes enforcements
+	members map[*Logger]*quotaMember
+}
+
+// quotaMember is a Logger sharing a QuotaGroup.
+type quotaMember struct {
+	weight    int
+	priority  int
+	protected map[string]bool // files never removed, as of the last mill
+}
+
+// NewQuotaGroup returns a QuotaGroup with the combined max total size of
+// log files in bytes.
+func NewQuotaGroup(maxTotalSize int64) *QuotaGroup {
+	return &QuotaGroup{maxTotalSize: maxTotalSize, members: make(map[*Logger]*quotaMember)}
+}
+
+// TotalSize returns the combined total size of the log files of the
+// Loggers sharing g.
+func (g *QuotaGroup) TotalSize() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var total int64
+	for l := range g.members {
+		files, _ := l.getLogFiles()
+		for _, f := range files {
+			total += f.Size()
+		}
+	}
+	return total
+}
+
+// join adds l to g with weight and priority.
+func (g *QuotaGroup) join(l *Logger, weight, priority int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.members[l] = &quotaMember{weight: weight, priority: priority}
+}
+
+// leave removes l from g.
+func (g *QuotaGroup) leave(l *Logger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.members, l)
+}
+
+// enforce records the files of l never removed, and then removes the files
+// of the members of g until the combined total size is not over the quota.
+func (g *QuotaGroup) enforce(l *Logger, protected map[string]bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if m, ok := g.members[l]; ok {
+		m.protected = protected
+	}
+
+	type usage struct {
+		*quotaMember
+		l         *Logger
+		size      int64
+		removable []FileInfo // oldest first
+	}
+	var total int64
+	usages := make([]*usage, 0, len(g.members))
+	for ml, m := range g.members {
+		files, err := ml.getLogFiles()
+		if err != nil {
+			return err
+		}
+		current := ml.currentFilename()
+		u := &usage{quotaMember: m, l: ml}
+		// NOTE: files already sorted newest first, see byModTime.
+		for i := len(files) - 1; i >= 0; i-- {
+			f := files[i]
+			u.size += f.Size()
+			if f.Path != current && !m.protected[f.Path] {
+				u.removable = append(u.removable, f)
+			}
+		}
+		total += u.size
+		usages = append(usages, u)
+	}
+
+	var errs []error
+	for total > g.maxTotalSize {
+		var victim *usage
+		for _, u := range usages {
+			if len(u.removable) == 0 {
+				continue
+			}
+			if victim == nil || u.priority < victim.priority ||
+				(u.priority == victim.priority && u.size*int64(victim.weight) > victim.size*int64(u.weight)) {
+				victim = u
+			}
+		}
+		if victim == nil {
+			break // only protected files left
+		}
+		f := victim.removable[0]
+		victim.removable = victim.removable[1:]
+		if err := victim.l.deferDeletion(f); err != nil {
+			errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+			continue
+		}
+		if err := os.Remove(f.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+			continue
+		}
+		victim.l.index.touch(f.Path)
+		victim.size -= f.Size()
+		total -= f.Size()
+	}
+	return errors.Join(errs...)
+}
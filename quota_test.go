@@ -0,0 +1,57 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_QuotaGroup(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_QuotaGroup")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	g := NewQuotaGroup(30)
+	_, err := New(filepath.Join(dir, "other.log"), WithQuotaGroup(g, 0, 0))
+	require.Error(t, err, "New should fail with invalid quota weight")
+
+	now := time.Now()
+	newLogger := func(name string, weight, priority int) *Logger {
+		for i, age := range []time.Duration{2 * time.Hour, time.Hour} {
+			path := filepath.Join(dir, name+".log."+strconv.Itoa(i+1))
+			require.NoError(t, os.WriteFile(path, make([]byte, 10), 0644), "WriteFile should succeed")
+			require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)), "Chtimes should succeed")
+		}
+		l, err := New(filepath.Join(dir, name+".log"), WithQuotaGroup(g, weight, priority))
+		require.NoError(t, err, "New should succeed")
+		require.True(t, l.Options().HasQuotaGroup, "HasQuotaGroup should match")
+		_, err = l.Write([]byte("x"))
+		require.NoError(t, err, "Write should succeed")
+		return l
+	}
+	access := newLogger("access", 1, 0)
+	defer access.Close()
+	errorLog := newLogger("error", 1, 1)
+	defer errorLog.Close()
+	audit := newLogger("audit", 3, 1)
+	defer audit.Close()
+
+	require.NoError(t, access.millRunOnce(), "millRunOnce should succeed")
+	for name, exists := range map[string]bool{
+		"access.log.1": false, // lowest priority evicted first
+		"access.log.2": false,
+		"access.log":   true,  // current file never removed
+		"error.log.1":  false, // using the most relative to its weight
+		"error.log.2":  false,
+		"audit.log.1":  true,
+		"audit.log.2":  true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		require.Equal(t, exists, err == nil, "existence of %s should match", name)
+	}
+	require.Equal(t, int64(23), g.TotalSize(), "TotalSize should not be over the quota")
+}
@@ -0,0 +1,91 @@
+package logrotate
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RecoveryMode is how files left by a crashed predecessor, e.g.: after a
+// crash during rotation or with a skewed clock, are adopted on the first
+// open of a Logger.
+type RecoveryMode int
+
+const (
+	// RecoveryOff adopts nothing: the current file is evaluated from the
+	// pattern only, and an existing file which fails to open is truncated.
+	RecoveryOff RecoveryMode = iota
+	// RecoveryStrict adopts the files of the current filename only: the
+	// sequence resumes at the highest existing sequence suffix, and an
+	// existing file is never truncated, but rotated away instead.
+	RecoveryStrict
+	// RecoveryPermissive also adopts the newest file matching the pattern
+	// if it's modified after the current rotation time, e.g.: named by a
+	// predecessor with a clock ahead, and writes to it until the next
+	// rotation.
+	RecoveryPermissive
+)
+
+// String returns the name of m.
+func (m RecoveryMode) String() string {
+	switch m {
+	case RecoveryOff:
+		return "off"
+	case RecoveryStrict:
+		return "strict"
+	case RecoveryPermissive:
+		return "permissive"
+	default:
+		return fmt.Sprintf("RecoveryMode(%d)", int(m))
+	}
+}
+
+// adoptOrphan returns the filename to open on the first open, adopting the
+// files left by a predecessor by l.opts.recovery, given the filename
+// evaluated from the pattern. It assumes l.mu is held.
+func (l *Logger) adoptOrphan(filename string) string {
+	if l.opts.recovery == RecoveryOff || l.opts.savelogCycle > 0 || l.opts.activeFile != "" {
+		return filename // fixed filenames
+	}
+	files, err := l.getLogFiles()
+	if err != nil {
+		return filename
+	}
+	base := l.currBaseFilename
+	for _, f := range files {
+		seq, ok := sequenceOf(f.Path, base, l.opts.seqSuffix)
+		if !ok || seq <= l.currSequence {
+			continue
+		}
+		if l.opts.maxSequence > 0 && seq > uint(l.opts.maxSequence) {
+			continue
+		}
+		l.currSequence = seq
+		filename = sequenceFilename(base, l.opts.seqSuffix, seq)
+	}
+	if l.opts.recovery == RecoveryPermissive && len(files) > 0 {
+		// NOTE: files already sorted newest first, see byModTime.
+		newest := files[0]
+		start := time.Unix(0, l.currRotationTime-l.tzOffset)
+		if _, ok := sequenceOf(newest.Path, base, l.opts.seqSuffix); !ok && newest.ModTime().After(start) && l.isGenerated(newest.Path) {
+			filename = newest.Path
+		}
+	}
+	l.currFilename = filename
+	return filename
+}
+
+// isGenerated reports whether path is generated by the pattern, with an
+// optional sequence suffix, e.g.: not compressed by a processor.
+func (l *Logger) isGenerated(path string) bool {
+	glob := strings.TrimSuffix(l.globPattern, suffixGlob)
+	if matched, _ := filepath.Match(glob, path); matched {
+		return true
+	}
+	if trimmed, _ := trimSequence(path, l.opts.seqSuffix); trimmed != path {
+		matched, _ := filepath.Match(glob, trimmed)
+		return matched
+	}
+	return false
+}
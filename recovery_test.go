@@ -0,0 +1,66 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RecoveryMode(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RecoveryMode")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithRecoveryMode(RecoveryMode(3)))
+	require.Error(t, err, "New should fail with invalid recovery mode")
+
+	writeFile := func(name, data string, mtime time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644), "WriteFile should succeed")
+		require.NoError(t, os.Chtimes(path, mtime, mtime), "Chtimes should succeed")
+	}
+	readFile := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		mode  RecoveryMode
+		files map[string]string
+	}{
+		{RecoveryOff, map[string]string{"app.20240101.log": "a1", "app.20240101.log.1": "b", "app.20240102.log": "c"}},
+		{RecoveryStrict, map[string]string{"app.20240101.log": "a", "app.20240101.log.1": "b1", "app.20240102.log": "c"}},
+		{RecoveryPermissive, map[string]string{"app.20240101.log": "a", "app.20240101.log.1": "b", "app.20240102.log": "c1"}},
+	} {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			require.NoError(t, os.RemoveAll(dir), "RemoveAll should succeed")
+			writeFile("app.20240101.log", "a", day1.Add(-2*time.Hour))
+			writeFile("app.20240101.log.1", "b", day1.Add(-time.Hour))
+			// left by a predecessor with a clock ahead.
+			writeFile("app.20240102.log", "c", day1.Add(time.Hour))
+
+			l, err := New(
+				filepath.Join(dir, "app.%Y%m%d.log"),
+				WithClock(clockwork.NewFakeClockAt(day1)),
+				WithMaxInterval(24*time.Hour),
+				WithMaxSize(10),
+				WithRecoveryMode(tc.mode),
+			)
+			require.NoError(t, err, "New should succeed")
+			defer l.Close()
+			require.Equal(t, tc.mode, l.Options().Recovery, "Recovery should match")
+
+			_, err = l.Write([]byte("1"))
+			require.NoError(t, err, "Write should succeed")
+			for name, data := range tc.files {
+				require.Equal(t, data, readFile(name), "file %s should match", name)
+			}
+		})
+	}
+}
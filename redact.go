@@ -0,0 +1,58 @@
+package logrotate
+
+import "regexp"
+
+// Redactor redacts sensitive data, e.g.: tokens or PII, from a log line
+// before it's persisted.
+type Redactor interface {
+	// Redact returns the redacted log line, and the count of redacted
+	// matches. It must not modify b, which is owned by the caller, and
+	// should return b itself if nothing is redacted.
+	Redact(b []byte) (redacted []byte, matches int)
+}
+
+// RedactorFunc is an adapter to allow the use of ordinary functions as
+// Redactors.
+type RedactorFunc func(b []byte) ([]byte, int)
+
+// Redact calls f(b).
+func (f RedactorFunc) Redact(b []byte) ([]byte, int) {
+	return f(b)
+}
+
+// NewRegexpRedactor returns a Redactor which replaces the matches of re
+// with repl, where $ signs are expanded as in regexp.Regexp.ReplaceAll,
+// e.g.: `token=$1***`.
+func NewRegexpRedactor(re *regexp.Regexp, repl string) Redactor {
+	replBytes := []byte(repl)
+	return RedactorFunc(func(b []byte) ([]byte, int) {
+		matches := len(re.FindAllIndex(b, -1))
+		if matches == 0 {
+			return b, 0
+		}
+		return re.ReplaceAll(b, replBytes), matches
+	})
+}
+
+// redact applies the redactors to b in order, and counts the redacted
+// matches in metrics.
+func (l *Logger) redact(b []byte) []byte {
+	for _, r := range l.opts.redactors {
+		var matches int
+		b, matches = r.Redact(b)
+		if matches > 0 {
+			l.metrics.Redactions.Add(uint64(matches))
+		}
+	}
+	return b
+}
+
+// redactedLen returns the length reported to the caller for a write of
+// the redacted log line, which returned n and err, given the length of the
+// original log line size.
+func redactedLen(n int, err error, size int) int {
+	if err == nil || n > size {
+		return size
+	}
+	return n
+}
@@ -0,0 +1,56 @@
+package logrotate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Redactors(t *testing.T) {
+	for _, writeChSize := range []int{0, 16} {
+		dir := filepath.Join(baseLogDir, "Test_Redactors")
+		l, err := New(
+			filepath.Join(dir, "app.log"),
+			WithWriteChan(writeChSize),
+			WithRedactors(
+				NewRegexpRedactor(regexp.MustCompile(`token=\w+`), "token=***"),
+				RedactorFunc(func(b []byte) ([]byte, int) {
+					n := bytes.Count(b, []byte("alice"))
+					if n == 0 {
+						return b, 0
+					}
+					return bytes.ReplaceAll(b, []byte("alice"), []byte("<user>")), n
+				}),
+			),
+		)
+		require.NoError(t, err, "New should succeed")
+		require.Equal(t, 2, l.Options().Redactors, "Redactors should match")
+
+		line := []byte("user=alice token=abc token=def\n")
+		n, err := l.Write(line)
+		require.NoError(t, err, "Write should succeed")
+		require.Equal(t, len(line), n, "Write should return the original length")
+		require.Equal(t, "user=alice token=abc token=def\n", string(line), "Write should not modify b")
+
+		n, err = l.WriteV([]byte("tok"), []byte("en=ghi\n"))
+		require.NoError(t, err, "WriteV should succeed")
+		require.Equal(t, 10, n, "WriteV should return the original length")
+
+		_, err = l.WritePriority([]byte("alice\n"), PriorityHigh)
+		require.NoError(t, err, "WritePriority should succeed")
+		require.NoError(t, l.Close(), "Close should succeed")
+
+		data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Contains(t, string(data), "user=<user> token=*** token=***\n", "Write should be redacted")
+		require.Contains(t, string(data), "token=***\n", "WriteV should be redacted across segments")
+		require.Contains(t, string(data), "<user>\n", "WritePriority should be redacted")
+		require.NotContains(t, string(data), "alice", "no match should be left")
+		require.Equal(t, uint64(5), l.Metrics().Redactions, "Redactions should match")
+		os.RemoveAll(dir)
+	}
+}
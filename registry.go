@@ -0,0 +1,181 @@
+package logrotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// registry is the process-wide registry of open Loggers keyed by their
+// cleaned absolute patterns, so that two components can't accidentally
+// construct competing Loggers rotating the same files.
+var registry = struct {
+	mu      sync.Mutex
+	loggers map[string]*Logger
+}{
+	loggers: make(map[string]*Logger),
+}
+
+// registryKey returns the registry key of pattern.
+func registryKey(pattern string) string {
+	if abs, err := filepath.Abs(pattern); err == nil {
+		return abs
+	}
+	return filepath.Clean(pattern)
+}
+
+// register registers l with key. If there is already a Logger registered
+// with key, and both are shared, the existing one is returned with its
+// reference count increased. Otherwise, ErrPatternInUse is returned.
+func register(key string, l *Logger) (*Logger, error) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if existing, ok := registry.loggers[key]; ok {
+		if existing.opts.shared && l.opts.shared {
+			existing.refs++
+			return existing, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPatternInUse, key)
+	}
+	l.key = key
+	l.refs = 1
+	registry.loggers[key] = l
+	return l, nil
+}
+
+// unregister decreases the reference count of l, and removes l from the
+// registry once no reference remains. It returns whether l should be
+// closed, i.e.: it's the last reference.
+func unregister(l *Logger) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.loggers[l.key] != l {
+		return true // not registered, e.g.: already closed
+	}
+	if l.refs > 1 {
+		l.refs--
+		return false
+	}
+	l.refs = 0
+	delete(registry.loggers, l.key)
+	return true
+}
+
+// Name returns the name set by WithName, or the pattern if not set.
+func (l *Logger) Name() string {
+	if l.opts.name != "" {
+		return l.opts.name
+	}
+	return l.patternString()
+}
+
+// Loggers returns the open Loggers in the process, sorted by Name.
+func Loggers() []*Logger {
+	registry.mu.Lock()
+	loggers := make([]*Logger, 0, len(registry.loggers))
+	for _, l := range registry.loggers {
+		loggers = append(loggers, l)
+	}
+	registry.mu.Unlock()
+	sort.Slice(loggers, func(i, j int) bool {
+		if ni, nj := loggers[i].Name(), loggers[j].Name(); ni != nj {
+			return ni < nj
+		}
+		return loggers[i].key < loggers[j].key
+	})
+	return loggers
+}
+
+// NamedMetrics is the Metrics of a Logger with its name and labels.
+type NamedMetrics struct {
+	Name    string            // see Logger.Name
+	Pattern string            // filename pattern of the Logger
+	Labels  map[string]string // see Logger.Labels
+	Metrics
+}
+
+// Labels returns a copy of the labels set by WithLabels.
+func (l *Logger) Labels() map[string]string {
+	labels := make(map[string]string, len(l.opts.labels))
+	for k, v := range l.opts.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// MetricsWithLabels returns a snapshot of the Metrics of the Logger with its
+// name and labels, so each Logger of a multi-logger process can be exported
+// as a distinct series.
+func (l *Logger) MetricsWithLabels() NamedMetrics {
+	return NamedMetrics{
+		Name:    l.Name(),
+		Pattern: l.patternString(),
+		Labels:  l.Labels(),
+		Metrics: l.Metrics(),
+	}
+}
+
+// CollectAll returns a snapshot of the Metrics of the open Loggers in the
+// process, sorted by Name, so multi-logger services can export per-logger
+// metrics, e.g.: labeled by Name and Labels.
+func CollectAll() []NamedMetrics {
+	loggers := Loggers()
+	all := make([]NamedMetrics, len(loggers))
+	for i, l := range loggers {
+		all[i] = l.MetricsWithLabels()
+	}
+	return all
+}
+
+// CloseAll closes all open Loggers in the process concurrently, flushing
+// their buffered writes, so that applications with multiple Loggers can
+// guarantee a single call flushes and closes everything before exit. Shared
+// Loggers are closed regardless of their reference counts. It returns the
+// joined close errors, or ctx.Err() if ctx is done before all are closed,
+// in which case the remaining Loggers keep closing in the background.
+func CloseAll(ctx context.Context) error {
+	registry.mu.Lock()
+	loggers := make([]*Logger, 0, len(registry.loggers))
+	for _, l := range registry.loggers {
+		l.refs = 1 // the last reference, so Close closes l
+		loggers = append(loggers, l)
+	}
+	registry.mu.Unlock()
+
+	errs := make([]error, len(loggers))
+	var wg sync.WaitGroup
+	for i, l := range loggers {
+		wg.Add(1)
+		go func(i int, l *Logger) {
+			defer wg.Done()
+			if err := l.Close(); err != nil && !errors.Is(err, ErrClosed) {
+				errs[i] = err
+			}
+		}(i, l)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Exit closes all open Loggers by CloseAll, and then exits the process by
+// os.Exit with code, as os.Exit skips deferred Close calls, so the buffered
+// writes would be lost. Close errors are traced to stderr.
+func Exit(ctx context.Context, code int) {
+	if err := CloseAll(ctx); err != nil {
+		tracef(os.Stderr, "failed to close loggers: %v", err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,123 @@
+package logrotate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Registry(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Registry")
+	defer os.RemoveAll(dir)
+
+	pattern := filepath.Join(dir, "app.log.%Y%m%d")
+	l1, err := New(pattern)
+	require.NoError(t, err, "New should succeed")
+
+	_, err = New(filepath.Join(dir, ".", "app.log.%Y%m%d"))
+	require.ErrorIs(t, err, ErrPatternInUse, "New should fail with the same cleaned pattern")
+	_, err = New(pattern, WithShared(true))
+	require.ErrorIs(t, err, ErrPatternInUse, "New should fail if the existing one is not shared")
+
+	require.NoError(t, l1.Close(), "Close should succeed")
+	l2, err := New(pattern)
+	require.NoError(t, err, "New should succeed after the existing one closed")
+	require.NoError(t, l2.Close(), "Close should succeed")
+}
+
+func Test_Registry_Shared(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Registry_Shared")
+	defer os.RemoveAll(dir)
+
+	pattern := filepath.Join(dir, "app.log")
+	l1, err := New(pattern, WithShared(true))
+	require.NoError(t, err, "New should succeed")
+	l2, err := New(pattern, WithShared(true))
+	require.NoError(t, err, "New should succeed")
+	require.Same(t, l1, l2, "New should return the existing shared Logger")
+	_, err = New(pattern)
+	require.ErrorIs(t, err, ErrPatternInUse, "New should fail if not shared")
+
+	require.NoError(t, l1.Close(), "Close should succeed")
+	_, err = l2.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed while still referenced")
+	require.NoError(t, l2.Close(), "Close should succeed")
+	_, err = l2.Write([]byte("2"))
+	require.ErrorIs(t, err, ErrClosed, "Write should fail after the last Close")
+	require.ErrorIs(t, l2.Close(), ErrClosed, "Close should fail after the last Close")
+}
+
+func Test_CloseAll(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_CloseAll")
+	defer os.RemoveAll(dir)
+
+	l1, err := New(filepath.Join(dir, "a.log"), WithWriteChan(16))
+	require.NoError(t, err, "New should succeed")
+	l2, err := New(filepath.Join(dir, "b.log"), WithShared(true))
+	require.NoError(t, err, "New should succeed")
+	_, err = New(filepath.Join(dir, "b.log"), WithShared(true))
+	require.NoError(t, err, "New should succeed")
+
+	_, err = l1.Write([]byte("a"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = l2.Write([]byte("b"))
+	require.NoError(t, err, "Write should succeed")
+
+	require.NoError(t, CloseAll(context.Background()), "CloseAll should succeed")
+	require.True(t, l1.closed.Load(), "Logger should be closed")
+	require.True(t, l2.closed.Load(), "shared Logger should be closed regardless of references")
+	require.ErrorIs(t, l2.Close(), ErrClosed, "Close should fail after CloseAll")
+
+	data, err := os.ReadFile(filepath.Join(dir, "a.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "a", string(data), "buffered writes should be flushed")
+}
+
+func Test_CollectAll(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_CollectAll")
+	defer os.RemoveAll(dir)
+
+	l1, err := New(filepath.Join(dir, "b.log"), WithName("access"), WithLabels(map[string]string{"service": "api"}))
+	require.NoError(t, err, "New should succeed")
+	defer l1.Close()
+	l2, err := New(filepath.Join(dir, "a.log"), WithWriteChan(1))
+	require.NoError(t, err, "New should succeed")
+	defer l2.Close()
+	require.Equal(t, "access", l1.Options().Name, "Name should match")
+	require.Equal(t, filepath.Join(dir, "a.log"), l2.Name(), "pattern should be the default name")
+
+	// block writeLoop, so the writes are discarded.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l2.submit(func() {
+		close(blocked)
+		<-release
+	})
+	defer close(release)
+	<-blocked
+	l2.Write([]byte("1"))
+	l2.Write([]byte("2"))
+
+	var collected []NamedMetrics
+	for _, m := range CollectAll() {
+		if m.Name == "access" || m.Pattern == l2.Name() {
+			collected = append(collected, m)
+		}
+	}
+	require.Len(t, collected, 2, "open Loggers should be collected")
+	require.Equal(t, l2.Name(), collected[0].Name, "metrics should be sorted by Name")
+	require.Equal(t, uint64(1), collected[0].Discards, "Metrics should match")
+	require.Equal(t, "access", collected[1].Name, "metrics should be sorted by Name")
+	require.Equal(t, filepath.Join(dir, "b.log"), collected[1].Pattern, "Pattern should match")
+	require.Equal(t, map[string]string{"service": "api"}, collected[1].Labels, "Labels should match")
+	require.Empty(t, collected[0].Labels, "Labels should be empty if not set")
+
+	l1.Labels()["service"] = "modified"
+	require.Equal(t, "api", l1.MetricsWithLabels().Labels["service"], "Labels should be copied")
+	require.Equal(t, 1, l1.Options().Labels, "Labels should be counted")
+
+	_, err = New(filepath.Join(dir, "c.log"), WithLabels(map[string]string{"": "x"}))
+	require.Error(t, err, "New should fail with an empty label name")
+}
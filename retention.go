@@ -0,0 +1,380 @@
+package logrotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy selects old log files to be removed by the mill
+// goroutine.
+type RetentionPolicy interface {
+	// Select returns the files to be removed. The files are sorted newest
+	// first by the time parsed from their filenames, falling back to
+	// FileInfo.Time for the files not matched by the pattern, except that the
+	// current file and the Symlink target come first, as they are never
+	// removed. now is the current time of the Logger's clock.
+	Select(files []FileInfo, now time.Time) (remove []FileInfo)
+}
+
+// RetentionPolicyFunc is an adapter to allow the use of ordinary functions
+// as RetentionPolicy.
+type RetentionPolicyFunc func(files []FileInfo, now time.Time) []FileInfo
+
+// Select calls f(files, now).
+func (f RetentionPolicyFunc) Select(files []FileInfo, now time.Time) []FileInfo {
+	return f(files, now)
+}
+
+// tailPolicy is a RetentionPolicy which always selects a tail of the sorted
+// files, and returns the index where the tail starts, so it's applied by
+// truncation, scanning the files only up to the cutoff.
+type tailPolicy func(files []FileInfo, now time.Time) int
+
+// Select returns the tail of files selected by p.
+func (p tailPolicy) Select(files []FileInfo, now time.Time) []FileInfo {
+	if i := p(files, now); i < len(files) {
+		return files[i:]
+	}
+	return nil
+}
+
+// olderThan returns the files older than cutoff by FileInfo.Time. All the
+// files are checked, as they're sorted by the time parsed from their
+// filenames, so a file touched recently never shields the older ones after
+// it.
+func olderThan(files []FileInfo, cutoff time.Time) []FileInfo {
+	var old []FileInfo
+	for _, f := range files {
+		if f.Time().Before(cutoff) {
+			old = append(old, f)
+		}
+	}
+	return old
+}
+
+// NewMaxAgePolicy returns a RetentionPolicy which removes files older
+// than maxAge, based on FileInfo.Time.
+func NewMaxAgePolicy(maxAge time.Duration) RetentionPolicy {
+	return RetentionPolicyFunc(func(files []FileInfo, now time.Time) []FileInfo {
+		return olderThan(files, now.Add(-1*maxAge))
+	})
+}
+
+// NewMaxAgeDaysPolicy returns a RetentionPolicy which removes files older
+// than days calendar days relative to the local midnight of now, based on
+// FileInfo.Time, e.g.: with days 30, files of the last 30 days and today are
+// kept. Days are counted on the calendar in the location of now, so the
+// cutoff never drifts by an hour around DST transitions.
+func NewMaxAgeDaysPolicy(days int) RetentionPolicy {
+	return RetentionPolicyFunc(func(files []FileInfo, now time.Time) []FileInfo {
+		y, m, d := now.Date()
+		return olderThan(files, time.Date(y, m, d-days, 0, 0, 0, 0, now.Location()))
+	})
+}
+
+// NewMaxBackupsPolicy returns a RetentionPolicy which keeps at most the
+// newest maxBackups files. If maxBackups < 0, it's regarded as 0.
+func NewMaxBackupsPolicy(maxBackups int) RetentionPolicy {
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+	return tailPolicy(func(files []FileInfo, now time.Time) int {
+		if len(files) <= maxBackups {
+			return len(files)
+		}
+		return maxBackups
+	})
+}
+
+// NewMaxBackupsPerIntervalPolicy returns a RetentionPolicy which keeps at
+// most the newest maxBackups files for each interval bucket (e.g.: per
+// hour). The bucket of a file is evaluated based on FileInfo.Time in its
+// location. If interval <= 0, all files fall in the same bucket.
+func NewMaxBackupsPerIntervalPolicy(maxBackups int, interval time.Duration) RetentionPolicy {
+	intervalNanos := int64(interval)
+	return RetentionPolicyFunc(func(files []FileInfo, now time.Time) []FileInfo {
+		var remove []FileInfo
+		counts := make(map[int64]int)
+		for _, f := range files {
+			bucket := int64(0)
+			if intervalNanos > 0 {
+				_, offset := f.Time().Zone()
+				bucket = evalRotationTime(f.Time().UnixNano(), int64(offset)*int64(time.Second), intervalNanos)
+			}
+			counts[bucket]++
+			if counts[bucket] > maxBackups {
+				remove = append(remove, f)
+			}
+		}
+		return remove
+	})
+}
+
+// NewMaxTotalSizePolicy returns a RetentionPolicy which keeps the newest
+// files as long as their total size is not over maxTotalSize in bytes. The
+// newest file is always kept.
+func NewMaxTotalSizePolicy(maxTotalSize int64) RetentionPolicy {
+	return tailPolicy(func(files []FileInfo, now time.Time) int {
+		var total int64
+		for i, f := range files {
+			total += f.Size()
+			if i > 0 && total > maxTotalSize {
+				return i
+			}
+		}
+		return len(files)
+	})
+}
+
+// NewGFSPolicy returns a grandfather-father-son RetentionPolicy, which
+// keeps the newest file of each of the latest dailies days, the newest file
+// of each of the latest weeklies ISO weeks, and the newest file of each of
+// the latest monthlies months. All other files are removed, except the
+// newest file which is always kept. Days, weeks and months are evaluated
+// based on FileInfo.Time in its location.
+//
+// NOTE: only one file is kept per period, so it works best with log files
+// rotated daily (MaxInterval of 24 hours).
+func NewGFSPolicy(dailies, weeklies, monthlies int) RetentionPolicy {
+	type tier struct {
+		limit  int
+		period func(t time.Time) [2]int
+		seen   map[[2]int]bool
+	}
+	day := func(t time.Time) [2]int { return [2]int{t.Year(), t.YearDay()} }
+	week := func(t time.Time) [2]int {
+		year, week := t.ISOWeek()
+		return [2]int{year, week}
+	}
+	month := func(t time.Time) [2]int { return [2]int{t.Year(), int(t.Month())} }
+
+	return RetentionPolicyFunc(func(files []FileInfo, now time.Time) []FileInfo {
+		tiers := []*tier{
+			{limit: dailies, period: day, seen: make(map[[2]int]bool)},
+			{limit: weeklies, period: week, seen: make(map[[2]int]bool)},
+			{limit: monthlies, period: month, seen: make(map[[2]int]bool)},
+		}
+		var remove []FileInfo
+		for i, f := range files {
+			keep := i == 0 // always keep the newest file
+			for _, t := range tiers {
+				p := t.period(f.Time())
+				if t.seen[p] || len(t.seen) >= t.limit {
+					continue
+				}
+				// files are sorted newest first, so f is the newest
+				// file of this period.
+				t.seen[p] = true
+				keep = true
+			}
+			if !keep {
+				remove = append(remove, f)
+			}
+		}
+		return remove
+	})
+}
+
+// applyRetentionPolicies applies the policies in order, each one selecting
+// from the files remaining after the previous ones, and returns all the
+// selected files to be removed. The built-in policies selecting a tail of
+// the files just truncate them.
+func applyRetentionPolicies(policies []RetentionPolicy, files []FileInfo, now time.Time) []FileInfo {
+	var removals []FileInfo
+	for _, p := range policies {
+		if tail, ok := p.(tailPolicy); ok {
+			i := tail(files, now)
+			removals = append(removals, files[i:]...)
+			files = files[:i:i]
+			continue
+		}
+		remove := p.Select(files, now)
+		if len(remove) == 0 {
+			continue
+		}
+		removed := make(map[string]bool, len(remove))
+		for _, f := range remove {
+			removed[f.Path] = true
+		}
+		remaining := make([]FileInfo, 0, len(files))
+		for _, f := range files {
+			if !removed[f.Path] {
+				remaining = append(remaining, f)
+			}
+		}
+		files = remaining
+		removals = append(removals, remove...)
+	}
+	return removals
+}
+
+// PurgeDir removes the old log files matching the pattern which are
+// selected by the policy, for out-of-process cleanup tools, e.g.: cron jobs
+// or init containers, with the same matching and retention logic as the mill
+// goroutine of Logger. Files matched by the glob of the pattern are further
+// matched by MatchPattern, with an optional extension added by processors,
+// e.g.: ".gz", so files of other patterns in the same directory are never
+// removed. The newest file is regarded as the current file of a running
+// Logger, so it's never removed. Only the following options of the Logger
+// are honored: WithClock for the current time, WithSequenceSuffix and
+// WithBirthTime for the matching and ordering, and WithMinAgeBeforeDelete,
+// WithOpenFileCheck and WithVerifyBeforeDelete for the deletion. It returns
+// the removed files, and the joined errors of the files failed to remove or
+// deferred.
+func PurgeDir(pattern string, policy RetentionPolicy, options ...Option) ([]string, error) {
+	if policy == nil {
+		return nil, errors.New("logrotate: nil retention policy")
+	}
+	if _, err := newStrftime(pattern); err != nil {
+		return nil, fmt.Errorf("invalid strftime pattern: %v", err)
+	}
+	opts, err := parseOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+	names := newTimeParser(pattern, opts.seqSuffix)
+	globbed, err := listLogFiles(parseGlobPattern(pattern), nil, opts.birthTime, opts.seqSuffix, names, nil)
+	if err != nil {
+		return nil, err
+	}
+	re, _ := compilePattern(pattern, opts.seqSuffix)
+	files := globbed[:0]
+	for _, f := range globbed {
+		if re.MatchString(f.Path) || re.MatchString(strings.TrimSuffix(f.Path, filepath.Ext(f.Path))) {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	var removed []string
+	var errs []error
+	for _, f := range applyRetentionPolicies([]RetentionPolicy{policy}, files, opts.clock.Now()) {
+		if f.Path == files[0].Path {
+			continue
+		}
+		if err := deferDeletion(context.Background(), opts, f); err != nil {
+			errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+			continue
+		}
+		if err := os.Remove(f.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, &PurgeError{Path: f.Path, Err: err})
+			continue
+		}
+		removed = append(removed, f.Path)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// RetentionGranularity is the unit which retention policies apply to.
+type RetentionGranularity int
+
+const (
+	// RetentionFile applies retention policies to each log file.
+	RetentionFile RetentionGranularity = iota
+	// RetentionDirectory applies retention policies to each per-interval
+	// directory, e.g.: "logs/20240101" of the pattern "logs/%Y%m%d/app.log",
+	// and removes the selected ones with all their entries.
+	RetentionDirectory
+)
+
+// String returns the name of g.
+func (g RetentionGranularity) String() string {
+	switch g {
+	case RetentionFile:
+		return "file"
+	case RetentionDirectory:
+		return "directory"
+	default:
+		return fmt.Sprintf("RetentionGranularity(%d)", int(g))
+	}
+}
+
+// parseDirGlobPattern returns the glob pattern of the per-interval
+// directories of the pattern, which is the parent directory of the files.
+func parseDirGlobPattern(pattern string) (string, error) {
+	dir := filepath.Dir(pattern)
+	if !strings.Contains(dir, "%") {
+		return "", fmt.Errorf("no per-interval directory in pattern: %s", pattern)
+	}
+	return strings.TrimSuffix(parseGlobPattern(dir), suffixGlob), nil
+}
+
+// purgeDirs applies the retention policies to the per-interval directories,
+// and removes the selected ones entirely, except the directory of the
+// current file and the ones containing a protected file. A directory is
+// kept until every file in it can be deleted, see deferDeletion.
+func (l *Logger) purgeDirs(protected []string, now time.Time) []error {
+	entries, err := listLogFiles(l.dirGlob, nil, l.opts.birthTime, l.opts.seqSuffix, l.dirNames, nil)
+	if err != nil {
+		return []error{err}
+	}
+	keep := map[string]bool{filepath.Dir(l.currentFilename()): true}
+	for _, path := range protected {
+		keep[filepath.Dir(path)] = true
+	}
+	dirs := entries[:0]
+	for _, d := range entries {
+		if d.IsDir() {
+			dirs = append(dirs, d)
+		}
+	}
+	// regard the kept directories as the newest ones, as the files.
+	dirs = moveToFront(dirs, func(d FileInfo) bool { return keep[d.Path] })
+
+	var errs []error
+	for _, d := range applyRetentionPolicies(l.policies, dirs, now) {
+		if keep[d.Path] {
+			continue
+		}
+		if err := walkFiles(d.Path, l.deferDeletion); err != nil {
+			// deferred to a later pass.
+			errs = append(errs, &PurgeError{Path: d.Path, Err: err})
+			continue
+		}
+		if err := os.RemoveAll(d.Path); err != nil {
+			errs = append(errs, &PurgeError{Path: d.Path, Err: err})
+		}
+		l.index.invalidate()
+	}
+	return errs
+}
+
+// walkFiles calls fn for each regular file in the directory tree rooted at
+// dir, and stops at the first error.
+func walkFiles(dir string, fn func(f FileInfo) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(FileInfo{Path: path, FileInfo: fi})
+	})
+}
+
+// moveToFront returns files with the ones reported by front moved to the
+// front, keeping the order of both, in linear time.
+func moveToFront(files []FileInfo, front func(f FileInfo) bool) []FileInfo {
+	moved := make([]FileInfo, 0, len(files))
+	for _, f := range files {
+		if front(f) {
+			moved = append(moved, f)
+		}
+	}
+	for _, f := range files {
+		if !front(f) {
+			moved = append(moved, f)
+		}
+	}
+	return moved
+}
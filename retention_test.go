@@ -0,0 +1,426 @@
+package logrotate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+// testFileInfo is a fake fs.FileInfo for testing retention policies.
+type testFileInfo struct {
+	fs.FileInfo
+	size    int64
+	modTime time.Time
+}
+
+func (fi testFileInfo) Size() int64        { return fi.size }
+func (fi testFileInfo) ModTime() time.Time { return fi.modTime }
+
+// genTestFiles generates n files sorted by modification time in descending
+// order, with the newest one at base and each one older by d.
+func genTestFiles(base time.Time, d time.Duration, n int, size int64) []FileInfo {
+	files := make([]FileInfo, 0, n)
+	for i := 0; i < n; i++ {
+		files = append(files, FileInfo{
+			Path:     fmt.Sprintf("log.%d", i),
+			FileInfo: testFileInfo{size: size, modTime: base.Add(-time.Duration(i) * d)},
+		})
+	}
+	return files
+}
+
+func paths(files []FileInfo) []string {
+	var ps []string
+	for _, f := range files {
+		ps = append(ps, f.Path)
+	}
+	return ps
+}
+
+func Test_RetentionPolicies(t *testing.T) {
+	now := time.Date(2024, 4, 1, 12, 30, 0, 0, time.UTC)
+	files := genTestFiles(now, 20*time.Minute, 6, 10)
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{
+			name:   "MaxAge",
+			policy: NewMaxAgePolicy(time.Hour),
+			want:   []string{"log.4", "log.5"},
+		},
+		{
+			name:   "MaxBackups",
+			policy: NewMaxBackupsPolicy(4),
+			want:   []string{"log.4", "log.5"},
+		},
+		{
+			name:   "MaxBackups over files",
+			policy: NewMaxBackupsPolicy(10),
+			want:   nil,
+		},
+		{
+			name:   "MaxBackups negative",
+			policy: NewMaxBackupsPolicy(-1),
+			want:   []string{"log.0", "log.1", "log.2", "log.3", "log.4", "log.5"},
+		},
+		{
+			name:   "MaxBackupsPerInterval",
+			policy: NewMaxBackupsPerIntervalPolicy(1, time.Hour),
+			// 12:30, 12:10 | 11:50, 11:30, 11:10 | 10:50
+			want: []string{"log.1", "log.3", "log.4"},
+		},
+		{
+			name:   "MaxTotalSize",
+			policy: NewMaxTotalSizePolicy(35),
+			want:   []string{"log.3", "log.4", "log.5"},
+		},
+		{
+			name:   "MaxTotalSize keeps newest",
+			policy: NewMaxTotalSizePolicy(1),
+			want:   []string{"log.1", "log.2", "log.3", "log.4", "log.5"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := paths(tt.policy.Select(files, now))
+			require.Equal(t, tt.want, got, "selected files should match")
+		})
+	}
+}
+
+func Test_MaxAgeDaysPolicy(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// DST started on 2024-03-10, so 48 hours before now is 23:30 on
+	// 2024-03-08, not 00:30 on 2024-03-09.
+	now := time.Date(2024, 3, 11, 0, 30, 0, 0, loc)
+	at := func(day, hour, min int) FileInfo {
+		return FileInfo{
+			Path:     fmt.Sprintf("log.%02d%02d%02d", day, hour, min),
+			FileInfo: testFileInfo{modTime: time.Date(2024, 3, day, hour, min, 0, 0, loc)},
+		}
+	}
+	files := []FileInfo{at(11, 0, 10), at(10, 23, 0), at(9, 0, 30), at(9, 0, 0), at(8, 23, 59)}
+
+	got := paths(NewMaxAgeDaysPolicy(2).Select(files, now))
+	require.Equal(t, []string{"log.082359"}, got, "files before the local midnight 2 days ago should be removed")
+	got = paths(NewMaxAgePolicy(48*time.Hour).Select(files, now))
+	require.Empty(t, got, "raw duration should be off by an hour around DST")
+}
+
+func Test_GFSPolicy(t *testing.T) {
+	// 2024-03-31 is Sunday, 2 files per day for 70 days.
+	now := time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC)
+	files := genTestFiles(now, 12*time.Hour, 140, 10)
+
+	remove := NewGFSPolicy(7, 4, 3).Select(files, now)
+	removed := make(map[string]bool)
+	for _, f := range remove {
+		removed[f.Path] = true
+	}
+	var kept []string
+	for _, f := range files {
+		if !removed[f.Path] {
+			kept = append(kept, f.ModTime().Format("2006-01-02T15"))
+		}
+	}
+	want := []string{
+		// 7 dailies, and the latest one is also weekly and monthly
+		"2024-03-31T18", "2024-03-30T18", "2024-03-29T18", "2024-03-28T18",
+		"2024-03-27T18", "2024-03-26T18", "2024-03-25T18",
+		// 3 more weeklies (sundays)
+		"2024-03-24T18", "2024-03-17T18", "2024-03-10T18",
+		// 2 more monthlies
+		"2024-02-29T18", "2024-01-31T18",
+	}
+	require.Equal(t, want, kept, "kept files should match")
+}
+
+func Test_applyRetentionPolicies(t *testing.T) {
+	now := time.Date(2024, 4, 1, 12, 30, 0, 0, time.UTC)
+	files := genTestFiles(now, 20*time.Minute, 6, 10)
+
+	var seen []string
+	custom := RetentionPolicyFunc(func(files []FileInfo, now time.Time) []FileInfo {
+		seen = paths(files)
+		return files[:1]
+	})
+	removals := applyRetentionPolicies([]RetentionPolicy{NewMaxBackupsPolicy(3), custom}, files, now)
+	require.Equal(t, []string{"log.0", "log.1", "log.2"}, seen, "custom policy should select from remaining files")
+	require.Equal(t, []string{"log.3", "log.4", "log.5", "log.0"}, paths(removals), "removals should match")
+}
+
+func Test_WithRetentionPolicy(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WithRetentionPolicy")
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("log.%d", i))
+		require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0644), "WriteFile should succeed")
+	}
+
+	// keep files with even sequence only
+	evenOnly := RetentionPolicyFunc(func(files []FileInfo, now time.Time) []FileInfo {
+		var remove []FileInfo
+		for _, f := range files {
+			if seq := filepath.Ext(f.Path); seq == ".1" || seq == ".3" {
+				remove = append(remove, f)
+			}
+		}
+		return remove
+	})
+	l, err := New(
+		filepath.Join(dir, "log"),
+		WithMaxTotalSize(40),
+		WithRetentionPolicy(evenOnly),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "log*"))
+	require.Equal(t, 2, len(files), "4 files are kept by MaxTotalSize, and then 2 files by custom policy")
+}
+
+func Test_BirthTime(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_BirthTime")
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	path := filepath.Join(dir, "log.1")
+	require.NoError(t, os.WriteFile(path, []byte("restored by rsync\n"), 0644), "WriteFile should succeed")
+	fi, err := os.Lstat(path)
+	require.NoError(t, err, "Lstat should succeed")
+	if _, ok := birthTime(path, fi); !ok {
+		t.Skip("birth time is not available on this platform or filesystem")
+	}
+	// modification time is changed to a week ago by some tool.
+	old := time.Now().Add(-7 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old), "Chtimes should succeed")
+
+	l, err := New(
+		filepath.Join(dir, "log"),
+		WithMaxAge(24*time.Hour),
+		WithBirthTime(true),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.FileExists(t, path, "file should be retained based on its birth time")
+}
+
+func Test_RetentionOrderByFilename(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RetentionOrderByFilename")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Date(2024, 1, 4, 12, 0, 0, 0, time.Local)
+	var names []string
+	for i := 0; i < 3; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+		mtime := time.Date(2024, 1, i+1, 23, 0, 0, 0, time.Local)
+		if i == 0 {
+			// touched by some tool, or written after a clock change.
+			mtime = now.Add(time.Hour)
+		}
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d.log"),
+		WithClock(clockwork.NewFakeClockAt(now)),
+		WithMaxBackups(2),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	files, err := l.getLogFiles()
+	require.NoError(t, err, "getLogFiles should succeed")
+	require.Equal(t, []string{names[2], names[1], names[0]}, paths(files), "files should be ordered by filename time")
+
+	_, err = l.Write([]byte("current"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.FileExists(t, names[2], "latest file by filename should be kept")
+	require.NoFileExists(t, names[1], "old file should be removed")
+	require.NoFileExists(t, names[0], "touched old file should be removed")
+}
+
+func Test_MaxAgeWithTouchedFile(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_MaxAgeWithTouchedFile")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local)
+	var names []string
+	for i := 0; i < 4; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+		mtime := time.Date(2024, 1, i+1, 23, 0, 0, 0, time.Local)
+		if i == 0 {
+			// the oldest one by filename touched just now.
+			mtime = now
+		}
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+
+	clock := clockwork.NewFakeClockAt(now)
+	for _, policy := range []RetentionPolicy{NewMaxAgePolicy(24 * time.Hour), NewMaxAgeDaysPolicy(1)} {
+		removed, err := PurgeDir(filepath.Join(dir, "app.%Y%m%d.log"), policy, WithClock(clock))
+		require.NoError(t, err, "PurgeDir should succeed")
+		require.ElementsMatch(t, names[1:3], removed, "old files after the touched one should be removed")
+		require.FileExists(t, names[0], "touched file should be kept by MaxAge")
+		require.FileExists(t, names[3], "newest file should be kept")
+		for _, name := range names[1:3] {
+			require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+			mtime := now.Add(-48 * time.Hour)
+			require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		}
+	}
+}
+
+func Test_PurgeDir(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PurgeDir")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Now()
+	var names []string
+	for i := 0; i < 4; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("x"), 0644), "WriteFile should succeed")
+		mtime := now.Add(time.Duration(i-4) * time.Hour)
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+	other := filepath.Join(dir, "other.log")
+	require.NoError(t, os.WriteFile(other, []byte("x"), 0644), "WriteFile should succeed")
+	// matched by the glob "app.*.log*", but not by the pattern.
+	backup := filepath.Join(dir, "app.backup.log")
+	require.NoError(t, os.WriteFile(backup, []byte("x"), 0644), "WriteFile should succeed")
+	old := now.Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(backup, old, old), "Chtimes should succeed")
+
+	_, err := PurgeDir(filepath.Join(dir, "%Q"), NewMaxBackupsPolicy(1))
+	require.Error(t, err, "PurgeDir should fail with invalid pattern")
+	_, err = PurgeDir(filepath.Join(dir, "app.%Y%m%d.log"), nil)
+	require.Error(t, err, "PurgeDir should fail with nil policy")
+
+	removed, err := PurgeDir(filepath.Join(dir, "app.%Y%m%d.log"), NewMaxBackupsPolicy(2))
+	require.NoError(t, err, "PurgeDir should succeed")
+	require.ElementsMatch(t, names[:2], removed, "oldest files should be removed")
+	require.FileExists(t, names[2], "file within MaxBackups should be kept")
+	require.FileExists(t, names[3], "newest file should be kept")
+	require.FileExists(t, other, "file not matching the pattern should be kept")
+	require.FileExists(t, backup, "file not matching the pattern should be kept")
+
+	// the current time is from the clock of the options.
+	clock := clockwork.NewFakeClockAt(now.Add(-2 * time.Hour))
+	removed, err = PurgeDir(filepath.Join(dir, "app.%Y%m%d.log"), NewMaxAgePolicy(time.Hour), WithClock(clock))
+	require.NoError(t, err, "PurgeDir should succeed")
+	require.Empty(t, removed, "no file should be older than MaxAge by the clock")
+
+	removed, err = PurgeDir(filepath.Join(dir, "app.%Y%m%d.log"), NewMaxAgePolicy(0))
+	require.NoError(t, err, "PurgeDir should succeed")
+	require.Equal(t, names[2:3], removed, "newest file should never be removed")
+}
+
+func Test_RetentionDirectory(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RetentionDirectory")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.%Y%m%d.log"), WithRetentionGranularity(RetentionDirectory))
+	require.Error(t, err, "New should fail without per-interval directory")
+
+	now := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+	var days []string
+	for i := 1; i <= 3; i++ {
+		day := filepath.Join(dir, fmt.Sprintf("2024010%d", i))
+		require.NoError(t, os.MkdirAll(day, 0755), "MkdirAll should succeed")
+		for j := 0; j < 3; j++ {
+			name := filepath.Join(day, fmt.Sprintf("app.log.%d", j))
+			require.NoError(t, os.WriteFile(name, []byte("x"), 0644), "WriteFile should succeed")
+		}
+		mtime := now.Add(time.Duration(i-4) * 24 * time.Hour)
+		require.NoError(t, os.Chtimes(day, mtime, mtime), "Chtimes should succeed")
+		days = append(days, day)
+	}
+
+	clock := clockwork.NewFakeClockAt(now)
+	l, err := New(
+		filepath.Join(dir, "%Y%m%d", "app.log"),
+		WithClock(clock),
+		WithMaxInterval(24*time.Hour),
+		WithMaxBackups(2),
+		WithRetentionGranularity(RetentionDirectory),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, RetentionDirectory, l.Options().RetentionGranularity, "snapshot should show the granularity")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoDirExists(t, days[0], "oldest directory should be removed entirely")
+	require.NoDirExists(t, days[1], "older directory should be removed entirely")
+	require.FileExists(t, filepath.Join(days[2], "app.log.2"), "directory within MaxBackups should be kept")
+	require.FileExists(t, filepath.Join(dir, "20240104", "app.log"), "current file should be kept")
+}
+
+// go test -bench ^Benchmark_applyRetentionPolicies10k$ -benchmem
+func Benchmark_applyRetentionPolicies10k(b *testing.B) {
+	now := time.Date(2024, 4, 1, 12, 30, 0, 0, time.UTC)
+	files := genTestFiles(now, time.Minute, 10000, 10)
+	policies := []RetentionPolicy{
+		NewMaxAgePolicy(7 * 24 * time.Hour),
+		NewMaxBackupsPolicy(9990),
+		NewMaxTotalSizePolicy(1 << 20),
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		applyRetentionPolicies(policies, files, now)
+	}
+}
+
+// go test -bench ^Benchmark_MillRunOnce10k$ -benchmem
+func Benchmark_MillRunOnce10k(b *testing.B) {
+	dir := filepath.Join(baseLogDir, "Benchmark_MillRunOnce10k")
+	defer os.RemoveAll(dir)
+	require.NoError(b, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	for i := 0; i < 10000; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("log.%d", i))
+		require.NoError(b, os.WriteFile(path, []byte("x"), 0644), "WriteFile should succeed")
+	}
+
+	for _, rescan := range []time.Duration{0, time.Hour} {
+		b.Run(fmt.Sprintf("FileIndex=%v", rescan), func(b *testing.B) {
+			l, err := New(
+				filepath.Join(dir, "log"),
+				WithMaxAge(time.Hour),
+				WithMaxBackups(20000),
+				WithFileIndex(rescan),
+			)
+			require.NoError(b, err, "New should succeed")
+			defer l.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, l.millRunOnce(), "millRunOnce should succeed")
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package logrotate
+
+// finishRotated calls the RotateHook on the closed rotated file at path,
+// and returns the new path of the file, which is tracked if moved. If the
+// hook fails, the error is reported to the ErrorHandler, and path is
+// returned.
+func (l *Logger) finishRotated(path string) string {
+	if l.opts.rotateHook == nil {
+		return path
+	}
+	newPath, err := l.opts.rotateHook(path)
+	if err != nil {
+		l.handleError(&RotationError{Op: "hook", Path: path, Err: err})
+		return path
+	}
+	l.index.touch(path, newPath)
+	if newPath != path && newPath != "" {
+		l.untrackMoved(path)
+		l.trackMoved(newPath)
+	}
+	return newPath
+}
+
+// trackMoved tracks path of a rotated file which was moved, and may not
+// match the globPattern.
+func (l *Logger) trackMoved(path string) {
+	l.movedMu.Lock()
+	defer l.movedMu.Unlock()
+	if l.moved == nil {
+		l.moved = make(map[string]struct{})
+	}
+	l.moved[path] = struct{}{}
+}
+
+// retrackMoved tracks newPath instead of path, if the file at path was
+// moved by the RotateHook, and then renamed by a post-rotation processor.
+func (l *Logger) retrackMoved(path, newPath string) {
+	if path == newPath {
+		return
+	}
+	l.movedMu.Lock()
+	defer l.movedMu.Unlock()
+	if _, ok := l.moved[path]; !ok {
+		return
+	}
+	delete(l.moved, path)
+	if newPath != "" {
+		l.moved[newPath] = struct{}{}
+	}
+}
+
+// movedPaths returns the paths of the moved rotated files, which may not
+// match the globPattern.
+func (l *Logger) movedPaths() []string {
+	l.movedMu.Lock()
+	defer l.movedMu.Unlock()
+	paths := make([]string, 0, len(l.moved))
+	for path := range l.moved {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// isMoved reports whether path is tracked as a moved rotated file.
+func (l *Logger) isMoved(path string) bool {
+	l.movedMu.Lock()
+	defer l.movedMu.Unlock()
+	_, ok := l.moved[path]
+	return ok
+}
+
+// untrackMoved stops tracking path, e.g.: if the file no longer exists.
+func (l *Logger) untrackMoved(path string) {
+	l.movedMu.Lock()
+	delete(l.moved, path)
+	l.movedMu.Unlock()
+}
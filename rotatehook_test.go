@@ -0,0 +1,102 @@
+package logrotate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RotateHook(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotateHook")
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "archive")
+	var mu sync.Mutex
+	var processed []string
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithMaxBackups(3), // including the current file
+		WithRotateHook(func(path string) (string, error) {
+			newPath := filepath.Join(archive, filepath.Base(path)+".job")
+			if err := os.MkdirAll(archive, 0755); err != nil {
+				return "", err
+			}
+			return newPath, os.Rename(path, newPath)
+		}),
+		WithPostRotateProcessors(ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, path)
+			return path, nil
+		})),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.True(t, l.Options().HasRotateHook, "HasRotateHook should be set")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	path, err := l.RotateAndGet()
+	require.NoError(t, err, "RotateAndGet should succeed")
+	require.Equal(t, filepath.Join(archive, "app.log.job"), path, "RotateAndGet should return the moved path")
+	require.FileExists(t, path, "rotated file should be moved")
+
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	moved := filepath.Join(archive, "app.log.1.job")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(processed) == 1 && processed[0] == moved
+	}, time.Second, time.Millisecond, "processors should receive the moved path")
+
+	_, err = l.Write([]byte("3"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return errors.Is(err, os.ErrNotExist)
+	}, time.Second, time.Millisecond, "retention should remove the oldest moved file")
+	require.FileExists(t, moved, "retention should keep the latest backup")
+}
+
+func Test_RotateHook_Error(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_RotateHook_Error")
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	var reported []error
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithRotateHook(func(path string) (string, error) {
+			return "", errors.New("hook failed")
+		}),
+		WithErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, err)
+		}),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	path, err := l.RotateAndGet()
+	require.NoError(t, err, "RotateAndGet should succeed")
+	require.Equal(t, filepath.Join(dir, "app.log"), path, "file should be regarded as not moved")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, reported, 1, "hook error should be reported")
+	var rerr *RotationError
+	require.ErrorAs(t, reported[0], &rerr, "RotationError should be reported")
+	require.Equal(t, "hook", rerr.Op, "Op should match")
+}
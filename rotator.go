@@ -0,0 +1,100 @@
+package logrotate
+
+import (
+	"os"
+)
+
+// Rotator manages naming, sequencing, retention and post-rotation
+// processing of files produced externally, e.g.: pcap captures or CSV
+// exports, with the same pattern and options as Logger. The caller opens
+// each file by OpenNext, writes and closes it, and then hands it over to the
+// post-rotation pipeline by Finish.
+//
+// Options about writing, e.g.: WriteChan, MaxSize and Exclusive, don't
+// apply, as the files are written by the caller.
+type Rotator struct {
+	l *Logger
+}
+
+// NewRotator creates a new concurrent safe Rotator object with the provided
+// filename pattern and options. It's registered process-wide by pattern as
+// Logger, see WithShared.
+func NewRotator(pattern string, options ...Option) (*Rotator, error) {
+	options = append(options[:len(options):len(options)], func(opts *Options) error {
+		// files are written by the caller.
+		opts.writeChSize = 0
+		opts.createOnNew = false
+		opts.exclusive = false
+		return nil
+	})
+	l, err := New(pattern, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Rotator{l: l}, nil
+}
+
+// OpenNext creates the next file based on the rotation rule: the filename
+// of the current interval, with a sequence suffix if it already exists,
+// e.g.: "capture.20240101.pcap.1". The returned file is owned by the
+// caller, which should close it and then call Finish with its name.
+func (r *Rotator) OpenNext() (*os.File, error) {
+	l := r.l
+	if l.closed.Load() {
+		return nil, ErrClosed
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	filename, _ := l.evalCurrentFilename(0, true)
+	return l.createFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|l.opts.durability.flag())
+}
+
+// Finish hands the file at path, which was closed by the caller, over to
+// the post-rotation pipeline as a rotated file of Logger: the RotateHook,
+// RotationEvents, post-rotation processors, retention and Symlink. It
+// returns the path of the file after the RotateHook, or ErrClosed after
+// Close called.
+func (r *Rotator) Finish(path string) (string, error) {
+	l := r.l
+	if l.closed.Load() {
+		return "", ErrClosed
+	}
+	finalName := l.finishRotated(path)
+	if finalName != "" {
+		l.queueRotated(finalName)
+	}
+	stats := FileStats{Path: finalName}
+	if fi, err := os.Stat(finalName); err == nil {
+		stats.Size = fi.Size()
+	}
+	l.sendRotationEvent(RotationEvent{
+		Time:  l.opts.clock.Now(),
+		Path:  finalName,
+		Stats: stats,
+	})
+	l.mill()
+	return finalName, nil
+}
+
+// Options returns a read-only snapshot of the effective options of this
+// Rotator.
+func (r *Rotator) Options() OptionsSnapshot {
+	return r.l.Options()
+}
+
+// RotationEvents returns the channel which receives a RotationEvent once a
+// file is finished, see Logger.RotationEvents.
+func (r *Rotator) RotationEvents() <-chan RotationEvent {
+	return r.l.RotationEvents()
+}
+
+// Metrics returns a snapshot of metrics of this Rotator.
+func (r *Rotator) Metrics() Metrics {
+	return r.l.Metrics()
+}
+
+// Close stops the mill goroutine and post-rotation processors. The files
+// already opened by OpenNext are not affected.
+func (r *Rotator) Close() error {
+	return r.l.Close()
+}
@@ -0,0 +1,74 @@
+package logrotate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Rotator(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Rotator")
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	var processed []string
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r, err := NewRotator(
+		filepath.Join(dir, "capture.%Y%m%d.pcap"),
+		WithClock(clock),
+		WithMaxBackups(2),
+		WithCreateOnNew(true),
+		WithPostRotateProcessors(ProcessorFunc(func(ctx context.Context, path string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, path)
+			return path, nil
+		})),
+	)
+	require.NoError(t, err, "NewRotator should succeed")
+	defer r.Close()
+	require.False(t, r.Options().CreateOnNew, "writing options should not apply")
+	entries, _ := os.ReadDir(dir)
+	require.Empty(t, entries, "no file should be created before OpenNext")
+
+	produce := func(content string) string {
+		f, err := r.OpenNext()
+		require.NoError(t, err, "OpenNext should succeed")
+		_, err = f.WriteString(content)
+		require.NoError(t, err, "WriteString should succeed")
+		require.NoError(t, f.Close(), "Close should succeed")
+		path, err := r.Finish(f.Name())
+		require.NoError(t, err, "Finish should succeed")
+		require.Equal(t, f.Name(), path, "path should not be moved")
+		return path
+	}
+
+	first := produce("1")
+	require.Equal(t, filepath.Join(dir, "capture.20240101.pcap"), first, "filename should match the pattern")
+	second := produce("2")
+	require.Equal(t, first+".1", second, "sequence suffix should be appended in the same interval")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(processed) == 2
+	}, time.Second, time.Millisecond, "finished files should be processed")
+
+	clock.Advance(24 * time.Hour)
+	third := produce("3")
+	require.Equal(t, filepath.Join(dir, "capture.20240102.pcap"), third, "filename should match the next interval")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(first)
+		return errors.Is(err, os.ErrNotExist)
+	}, time.Second, time.Millisecond, "retention should remove the oldest file")
+
+	require.NoError(t, r.Close(), "Close should succeed")
+	_, err = r.OpenNext()
+	require.ErrorIs(t, err, ErrClosed, "OpenNext should fail after Close")
+}
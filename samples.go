@@ -0,0 +1,87 @@
+package logrotate
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DroppedSample is a sample of a discarded write.
+type DroppedSample struct {
+	Time  time.Time // when the write was discarded
+	Cause string    // "queue_full", "closed" or "canceled"
+	Size  int       // length of the whole write
+	Data  []byte    // first bytes of the write, up to the sample size
+}
+
+// droppedSamples is a ring buffer of the most recent DroppedSamples.
+type droppedSamples struct {
+	mu      sync.Mutex
+	samples []DroppedSample // allocated on the first discard
+	next    int             // index of the next sample to overwrite
+	full    bool            // set when samples wrapped around
+}
+
+// discard counts a discarded write of segments with total length size, and
+// samples it if WithDroppedSamples.
+func (l *Logger) discard(cause *atomic.Uint64, size int, segments ...[]byte) {
+	l.metrics.discard(cause, size)
+	if l.opts.samples <= 0 {
+		return
+	}
+	name := "queue_full"
+	switch cause {
+	case &l.metrics.DiscardsClosed:
+		name = "closed"
+	case &l.metrics.DiscardsCanceled:
+		name = "canceled"
+	}
+	now := l.opts.clock.Now()
+
+	d := &l.dropped
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.samples == nil {
+		d.samples = make([]DroppedSample, l.opts.samples)
+	}
+	s := &d.samples[d.next]
+	s.Time, s.Cause, s.Size = now, name, size
+	s.Data = s.Data[:0] // reuse the buffer of the overwritten sample
+	for _, seg := range segments {
+		room := l.opts.sampleSize - len(s.Data)
+		if room <= 0 {
+			break
+		}
+		if len(seg) > room {
+			seg = seg[:room]
+		}
+		s.Data = append(s.Data, seg...)
+	}
+	d.next++
+	if d.next == len(d.samples) {
+		d.next, d.full = 0, true
+	}
+}
+
+// DroppedSamples returns copies of the sampled discarded writes, from the
+// oldest to the most recent. It returns nil if WithDroppedSamples is not
+// set or nothing was discarded.
+func (l *Logger) DroppedSamples() []DroppedSample {
+	d := &l.dropped
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.samples == nil {
+		return nil
+	}
+	var ordered []DroppedSample
+	if d.full {
+		ordered = append(ordered, d.samples[d.next:]...)
+	}
+	ordered = append(ordered, d.samples[:d.next]...)
+	result := make([]DroppedSample, len(ordered))
+	for i, s := range ordered {
+		s.Data = append([]byte(nil), s.Data...)
+		result[i] = s
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DroppedSamples(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_DroppedSamples")
+	defer os.RemoveAll(dir)
+
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithWriteChan(1),
+		WithDroppedSamples(2, 4),
+	)
+	require.NoError(t, err, "New should succeed")
+	require.Nil(t, l.DroppedSamples(), "nothing should be sampled yet")
+
+	// block writeLoop, so the queue is full.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+	_, err = l.Write([]byte("kept"))
+	require.NoError(t, err, "Write should be queued")
+	for _, line := range []string{"first", "second", "third"} {
+		_, err = l.Write([]byte(line))
+		require.ErrorIs(t, err, ErrDiscarded, "Write should be discarded")
+	}
+
+	samples := l.DroppedSamples()
+	require.Len(t, samples, 2, "only the most recent samples should be kept")
+	require.Equal(t, "seco", string(samples[0].Data), "Data should be truncated")
+	require.Equal(t, 6, samples[0].Size, "Size should be the whole length")
+	require.Equal(t, "thir", string(samples[1].Data), "samples should be ordered")
+	require.Equal(t, "queue_full", samples[1].Cause, "Cause should match")
+	samples[1].Data[0] = 'x'
+	require.Equal(t, "thir", string(l.DroppedSamples()[1].Data), "samples should be copied")
+
+	close(release)
+	require.NoError(t, l.Close(), "Close should succeed")
+	_, err = l.WriteV([]byte("a"), []byte("fterwards"))
+	require.ErrorIs(t, err, ErrClosed, "WriteV should fail after Close")
+	samples = l.DroppedSamples()
+	require.Equal(t, "afte", string(samples[1].Data), "segments should be sampled")
+	require.Equal(t, "closed", samples[1].Cause, "Cause should match")
+}
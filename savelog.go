@@ -0,0 +1,112 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// savelogFilename returns the filename of the n-th old log file of base in
+// Debian savelog-style numbering, e.g.: "app.log.0", "app.log.1.gz".
+func savelogFilename(base string, n int, compressed bool) string {
+	if compressed {
+		return fmt.Sprintf("%s.%d.gz", base, n)
+	}
+	return fmt.Sprintf("%s.%d", base, n)
+}
+
+// shiftSavelog shifts the old log files of base by one: base.N(.gz) is
+// renamed to base.N+1(.gz), and the oldest ones beyond base.CYCLE-1(.gz)
+// are removed. The gaps left by retention policies, e.g.: MaxAge, are
+// closed, so the files stay numbered from 1 in order. At last, base itself
+// is renamed to base.0. l.mu must be held by the caller.
+func (l *Logger) shiftSavelog(base string) error {
+	l.savelogMu.Lock()
+	defer l.savelogMu.Unlock()
+
+	cycle := l.opts.savelogCycle
+	var nums []int // numbers of the existing old files in order
+	for n := 0; n < cycle; n++ {
+		for _, compressed := range []bool{false, true} {
+			if _, err := os.Lstat(savelogFilename(base, n, compressed)); err == nil {
+				nums = append(nums, n)
+				break
+			}
+		}
+	}
+	for ; len(nums) > 0 && len(nums) >= cycle; nums = nums[:len(nums)-1] {
+		for _, compressed := range []bool{false, true} {
+			src := savelogFilename(base, nums[len(nums)-1], compressed)
+			l.index.touch(src)
+			if err := os.Remove(src); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return &PurgeError{Path: src, Err: err}
+			}
+		}
+	}
+	// the i-th file is renumbered to i+1: the ones after a gap move down
+	// first in order, and then the ones before any gap move up in reverse,
+	// so no file is overwritten.
+	for i := range nums {
+		if nums[i] > i+1 {
+			if err := l.renumberSavelog(base, nums[i], i+1); err != nil {
+				return err
+			}
+		}
+	}
+	for i := len(nums) - 1; i >= 0; i-- {
+		if nums[i] == i {
+			if err := l.renumberSavelog(base, i, i+1); err != nil {
+				return err
+			}
+		}
+	}
+	l.index.touch(base, savelogFilename(base, 0, false))
+	if err := os.Rename(base, savelogFilename(base, 0, false)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return &RotationError{Op: "rename", Path: base, Err: err}
+	}
+	return nil
+}
+
+// renumberSavelog renames the old log file base.from(.gz) to base.to(.gz),
+// and queues it for compression if enabled. l.savelogMu must be held by
+// the caller.
+func (l *Logger) renumberSavelog(base string, from, to int) error {
+	for _, compressed := range []bool{false, true} {
+		src, dst := savelogFilename(base, from, compressed), savelogFilename(base, to, compressed)
+		l.index.touch(src, dst)
+		if err := os.Rename(src, dst); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return &RotationError{Op: "rename", Path: src, Err: err}
+		}
+		if !compressed && l.opts.savelogCompress {
+			l.savelogQueue = append(l.savelogQueue, dst)
+		}
+	}
+	return nil
+}
+
+// compressSavelog compresses the old log files numbered from 1 which are
+// queued by shiftSavelog. A rotation waits for the file being compressed,
+// so it won't be renamed in the meantime.
+func (l *Logger) compressSavelog() error {
+	l.savelogMu.Lock()
+	queue := l.savelogQueue
+	l.savelogQueue = nil
+	l.savelogMu.Unlock()
+
+	var errs []error
+	for _, path := range queue {
+		l.savelogMu.Lock()
+		if _, err := os.Stat(path); err == nil {
+			l.index.touch(path, path+".gz")
+			if _, err := gzipFile(l.ctx, path); err != nil {
+				errs = append(errs, &RotationError{Op: "compress", Path: path, Err: err})
+			}
+		}
+		l.savelogMu.Unlock()
+	}
+	return errors.Join(errs...)
+}
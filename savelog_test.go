@@ -0,0 +1,112 @@
+package logrotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Savelog(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Savelog")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(
+		filename,
+		WithMaxSize(8),
+		WithSavelog(3, true),
+	)
+	require.NoError(t, err, "New should succeed")
+
+	for _, line := range []string{"logfile1", "logfile2", "logfile3", "logfile4"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+		require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	}
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "app.log*"))
+	require.ElementsMatch(t, []string{filename, filename + ".0", filename + ".1.gz", filename + ".2.gz"}, files, "files should be numbered as savelog")
+
+	readFile := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+	readGzip := func(path string) string {
+		f, err := os.Open(path)
+		require.NoError(t, err, "Open should succeed")
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err, "gzip.NewReader should succeed")
+		data, err := io.ReadAll(gz)
+		require.NoError(t, err, "ReadAll should succeed")
+		return string(data)
+	}
+	require.Equal(t, "logfile4", readFile(filename), "current file content should match")
+	require.Equal(t, "logfile3", readFile(filename+".0"), "file 0 content should match")
+	require.Equal(t, "logfile2", readGzip(filename+".1.gz"), "file 1 content should match")
+	require.Equal(t, "logfile1", readGzip(filename+".2.gz"), "file 2 content should match")
+}
+
+func Test_Savelog_Gaps(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Savelog_Gaps")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	filename := filepath.Join(dir, "app.log")
+	now := time.Now()
+	for n, content := range []string{"file0", "file1", "file2"} {
+		name := fmt.Sprintf("%s.%d", filename, n)
+		require.NoError(t, os.WriteFile(name, []byte(content), 0644), "WriteFile should succeed")
+		mtime := now.Add(-time.Duration(n) * time.Minute)
+		if n == 1 {
+			mtime = now.Add(-2 * time.Hour)
+		}
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+	}
+	l, err := New(
+		filename,
+		WithMaxSize(8),
+		WithMaxAge(time.Hour),
+		WithSavelog(3, false),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	// the aged file in the middle is removed by MaxAge.
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, filename+".1", "aged file should be removed")
+
+	for _, line := range []string{"logfile1", "logfile2"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "app.log*"))
+	require.ElementsMatch(t, []string{filename, filename + ".0", filename + ".1", filename + ".2"}, files, "gap should be closed by the shift")
+	for name, want := range map[string]string{
+		filename:        "logfile2",
+		filename + ".0": "logfile1",
+		filename + ".1": "file0",
+		filename + ".2": "file2",
+	} {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Equal(t, want, string(data), "content of %s should match", name)
+	}
+
+	// the oldest one beyond the cycle is removed.
+	_, err = l.Write([]byte("logfile3"))
+	require.NoError(t, err, "Write should succeed")
+	files, _ = filepath.Glob(filepath.Join(dir, "app.log*"))
+	require.ElementsMatch(t, []string{filename, filename + ".0", filename + ".1", filename + ".2"}, files, "files should be numbered up to the cycle")
+	data, err := os.ReadFile(filename + ".2")
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "file0", string(data), "oldest file should be removed")
+}
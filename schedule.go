@@ -0,0 +1,99 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// after waits for the duration d to elapse on the Logger's clock if it
+// implements TimerClock, or on the system clock otherwise.
+func (l *Logger) after(d time.Duration) <-chan time.Time {
+	if c, ok := l.opts.clock.(TimerClock); ok {
+		return c.After(d)
+	}
+	return time.After(d)
+}
+
+// NextRotation returns the time of the next MaxInterval boundary, at which
+// the current file will be rotated, so that shippers can schedule work
+// right after boundaries. It returns the zero time if rotation based on
+// MaxInterval is disabled.
+//
+// NOTE: the file may also be rotated earlier, e.g.: by MaxSize.
+func (l *Logger) NextRotation() time.Time {
+	if l.maxInterval <= 0 {
+		return time.Time{}
+	}
+	return l.nextRotationTime().In(l.opts.clock.Now().Location())
+}
+
+// nextRotationTime returns the time of the next MaxInterval boundary.
+func (l *Logger) nextRotationTime() time.Time {
+	curr := evalCurrRotationTime(l.opts.clock, l.tzOffset, l.maxInterval)
+	return time.Unix(0, curr+l.maxInterval-l.tzOffset)
+}
+
+// scheduleLoop runs in a goroutine to rotate the current file at each
+// MaxInterval boundary until Close is called.
+func (l *Logger) scheduleLoop() {
+	for {
+		d := l.nextRotationTime().Sub(l.opts.clock.Now())
+		select {
+		case <-l.life.quit:
+			return
+		case <-l.after(d):
+			// ordered with buffered writes, and skipped if closed.
+			l.submit(func() {
+				if err := l.rotateIfDue(); err != nil && !errors.Is(err, ErrClosed) && !isVetoed(err) {
+					l.handleError(err)
+				}
+			})
+		}
+	}
+}
+
+// rotateIfDue rotates the current file if it's open and its rotation time
+// has passed.
+func (l *Logger) rotateIfDue() error {
+	l.mu.Lock()
+	var err error
+	if h := l.file.Load(); h != nil && l.rotationDueLocked(h.rotationTime) {
+		err = l.rotateOrDefer(RotateByInterval)
+	}
+	if cerr := l.unlock(); cerr != nil {
+		tracef(os.Stderr, "failed to close rotated file: %v", cerr)
+	}
+	return err
+}
+
+// rotationDue reports whether the rotation time evaluated by the clock is
+// past rotationTime. If the clock jumps backwards before rotationTime, e.g.:
+// on NTP step, it's not due, and ErrClockRegression is returned once until
+// the clock catches up.
+func (l *Logger) rotationDue(rotationTime int64) (bool, error) {
+	curr := evalCurrRotationTime(l.opts.clock, l.tzOffset, l.maxInterval)
+	if curr < rotationTime {
+		if !l.clockRegressed.CompareAndSwap(false, true) {
+			return false, nil
+		}
+		l.metrics.ClockRegressions.Add(1)
+		behind := time.Duration(rotationTime - curr)
+		return false, fmt.Errorf("%w: %v behind the current rotation time", ErrClockRegression, behind)
+	}
+	if l.clockRegressed.Load() {
+		l.clockRegressed.Store(false)
+	}
+	return curr > rotationTime, nil
+}
+
+// rotationDueLocked is rotationDue, but reports the error after l.mu is
+// released. l.mu must be held by the caller.
+func (l *Logger) rotationDueLocked(rotationTime int64) bool {
+	due, err := l.rotationDue(rotationTime)
+	if err != nil {
+		l.report(err)
+	}
+	return due
+}
@@ -0,0 +1,108 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ScheduledRotation(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_ScheduledRotation")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d%H.log"),
+		WithClock(clock),
+		WithMaxInterval(time.Hour),
+		WithScheduledRotation(true),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("a"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, filepath.Join(dir, "app.2024010110.log"), l.currentFilename(), "filename should match")
+
+	// rotate at the next boundary without writes.
+	for _, want := range []string{"app.2024010111.log", "app.2024010112.log"} {
+		clock.BlockUntil(1) // wait until the schedule goroutine waits on clock
+		clock.Advance(time.Hour)
+		require.Eventually(t, func() bool {
+			return l.currentFilename() == filepath.Join(dir, want)
+		}, time.Second, time.Millisecond, "file should be rotated to %s", want)
+	}
+	require.FileExists(t, filepath.Join(dir, "app.2024010112.log"), "new file should be created")
+}
+
+func Test_NextRotation(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_NextRotation")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d%H.log"),
+		WithClock(clock),
+		WithMaxInterval(time.Hour),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), l.NextRotation(), "NextRotation should be the next boundary")
+
+	clock.Advance(time.Hour)
+	require.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), l.NextRotation(), "NextRotation should move with the clock")
+
+	l2, err := New(filepath.Join(dir, "app.log"), WithMaxInterval(0))
+	require.NoError(t, err, "New should succeed")
+	defer l2.Close()
+	require.True(t, l2.NextRotation().IsZero(), "NextRotation should be zero without MaxInterval")
+}
+
+func Test_ClockRegression(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_ClockRegression")
+	defer os.RemoveAll(dir)
+
+	var errs []error
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d%H.log"),
+		WithClock(clock),
+		WithMaxInterval(time.Hour),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	write := func(s string) {
+		_, err := l.Write([]byte(s))
+		require.NoError(t, err, "Write should succeed")
+	}
+	readFile := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+
+	write("a")
+	clock.Advance(40 * time.Minute) // 11:10
+	write("b")
+	clock.Advance(-20 * time.Minute) // 10:50, e.g.: NTP step
+	write("c")
+	write("d")
+	require.Equal(t, "a", readFile("app.2024010110.log"), "finished file should never be reopened")
+	require.Equal(t, "bcd", readFile("app.2024010111.log"), "current file should be kept on clock regression")
+	require.Len(t, errs, 1, "clock regression should be reported once")
+	require.ErrorIs(t, errs[0], ErrClockRegression, "error should match")
+	require.Equal(t, uint64(1), l.Metrics().ClockRegressions, "ClockRegressions should match")
+
+	clock.Advance(time.Hour) // 11:50, caught up
+	write("e")
+	clock.Advance(20 * time.Minute) // 12:10
+	write("f")
+	require.Equal(t, "bcde", readFile("app.2024010111.log"), "current file should be written after the clock caught up")
+	require.Equal(t, "f", readFile("app.2024010112.log"), "file should be rotated at the next interval")
+	require.Len(t, errs, 1, "no more clock regression should be reported")
+}
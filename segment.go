@@ -0,0 +1,153 @@
+package logrotate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Segment is a chunk of the log of an interval in segmented mode, see
+// WithSegments.
+type Segment struct {
+	Base   string // filename of the interval without the sequence suffix
+	Offset int64  // offset of the first byte of the chunk in the log of the interval
+	Path   string // path of the chunk
+}
+
+// ReadSegmentIndex reads the segment index written by WithSegments at
+// path, and returns the segments in the order they were written.
+func ReadSegmentIndex(path string) ([]Segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var segments []Segment
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s Segment
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %q %q", &s.Offset, &s.Base, &s.Path); err != nil {
+			// a torn line can't be written, as the file is replaced
+			// atomically, so it's corrupted by others.
+			return nil, fmt.Errorf("invalid segment index line %q: %v", scanner.Text(), err)
+		}
+		segments = append(segments, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// LocateSegment returns the segment of the log of the interval base which
+// contains offset, and the offset in the segment, e.g.: to read the bytes
+// from 10 GB into today's log. It returns false if no retained segment of
+// base starts at or before offset.
+func LocateSegment(segments []Segment, base string, offset int64) (Segment, int64, bool) {
+	var found Segment
+	ok := false
+	for _, s := range segments {
+		if s.Base == base && s.Offset <= offset && (!ok || s.Offset > found.Offset) {
+			found, ok = s, true
+		}
+	}
+	if !ok {
+		return Segment{}, 0, false
+	}
+	return found, offset - found.Offset, true
+}
+
+// segmentIndex records the offset of each chunk in the log of its interval
+// to a sidecar file, so that downstream readers can access the log of an
+// interval at random offsets. Chunks removed by retention, or moved, e.g.:
+// compressed, are dropped from the index when the next chunk is recorded.
+type segmentIndex struct {
+	path string
+
+	mu       sync.Mutex // guards following
+	segments []Segment
+}
+
+// loadSegmentIndex loads the segment index at path, if any.
+func loadSegmentIndex(path string) (*segmentIndex, error) {
+	segments, err := ReadSegmentIndex(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return &segmentIndex{path: path, segments: segments}, nil
+}
+
+// last returns the latest segment of base, and false if there is none.
+// x.mu must be held by the caller.
+func (x *segmentIndex) last(base string) (Segment, bool) {
+	for i := len(x.segments) - 1; i >= 0; i-- {
+		if x.segments[i].Base == base {
+			return x.segments[i], true
+		}
+	}
+	return Segment{}, false
+}
+
+// record records the chunk at path of the log of base, unless it's the
+// latest recorded already. size returns the final size of the previous
+// chunk of base, which the offset of the chunk follows. It's a no-op on a
+// nil index.
+func (x *segmentIndex) record(base, path string, size func(prev string) int64) error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var offset int64
+	if prev, ok := x.last(base); ok {
+		if prev.Path == path {
+			return nil
+		}
+		offset = prev.Offset + size(prev.Path)
+	}
+	retained := x.segments[:0]
+	for _, s := range x.segments {
+		if s.Path == path {
+			continue // reused, e.g.: after a restart
+		}
+		if _, err := os.Lstat(s.Path); err == nil {
+			retained = append(retained, s)
+		}
+	}
+	x.segments = append(retained, Segment{Base: base, Offset: offset, Path: path})
+
+	var buf bytes.Buffer
+	for _, s := range x.segments {
+		fmt.Fprintf(&buf, "%d %q %q\n", s.Offset, s.Base, s.Path)
+	}
+	return replaceFile(x.path, buf.Bytes())
+}
+
+// indexSegment records the current file in the segment index if
+// WithSegments, where prev is the file just rotated from, if any. l.mu must
+// be held by the caller.
+func (l *Logger) indexSegment(prev *fileHandle) {
+	h := l.file.Load()
+	if l.segments == nil || h == nil {
+		return
+	}
+	err := l.segments.record(l.currBaseFilename, h.name, func(path string) int64 {
+		if prev != nil && prev.name == path {
+			// wait for in-flight writes, so the size is final.
+			prev.retire()
+			return prev.size.Load()
+		}
+		if fi, err := os.Stat(path); err == nil {
+			return fi.Size()
+		}
+		return 0
+	})
+	if err != nil {
+		l.report(&RotationError{Op: "segment", Path: l.opts.segmentIndex, Err: err})
+	}
+}
@@ -0,0 +1,78 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Segments(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Segments")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	index := filepath.Join(dir, "segments.idx")
+	_, err := New(filename, WithSegments(0, index))
+	require.Error(t, err, "non-positive segment size should be rejected")
+	_, err = New(filename, WithSegments(10, index), WithMaxSize(20))
+	require.Error(t, err, "conflicting max size should be rejected")
+
+	l, err := New(filename, WithSegments(10, index))
+	require.NoError(t, err, "New should succeed")
+	require.Equal(t, 10, l.Options().MaxSize, "MaxSize should be the segment size")
+	require.Equal(t, index, l.Options().SegmentIndex, "SegmentIndex should match")
+
+	var content string
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dd\n", "eeeeee\n"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+		content += line
+	}
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	segments, err := ReadSegmentIndex(index)
+	require.NoError(t, err, "ReadSegmentIndex should succeed")
+	require.Equal(t, []Segment{
+		{Base: filename, Offset: 0, Path: filename},
+		{Base: filename, Offset: 10, Path: filename + ".1"},
+		{Base: filename, Offset: 18, Path: filename + ".2"},
+	}, segments, "segments should match")
+
+	// random access to the log of the interval.
+	readAt := func(offset int64, n int) string {
+		s, off, ok := LocateSegment(segments, filename, offset)
+		require.True(t, ok, "LocateSegment should find the segment")
+		f, err := os.Open(s.Path)
+		require.NoError(t, err, "Open should succeed")
+		defer f.Close()
+		b := make([]byte, n)
+		_, err = io.ReadFull(io.NewSectionReader(f, off, int64(n)), b)
+		require.NoError(t, err, "ReadFull should succeed")
+		return string(b)
+	}
+	require.Equal(t, content[12:16], readAt(12, 4), "content at offset should match")
+	require.Equal(t, content[18:25], readAt(18, 7), "content at offset should match")
+
+	// a restarted Logger continues the index from the last chunk, and
+	// removed chunks are dropped from it.
+	require.NoError(t, os.Remove(filename), "Remove should succeed")
+	l, err = New(filename, WithSegments(10, index))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	_, err = l.Write([]byte("ff\n"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = l.Write([]byte("gggggg\n"))
+	require.NoError(t, err, "Write should succeed")
+	segments, err = ReadSegmentIndex(index)
+	require.NoError(t, err, "ReadSegmentIndex should succeed")
+	require.Equal(t, []Segment{
+		{Base: filename, Offset: 10, Path: filename + ".1"},
+		{Base: filename, Offset: 18, Path: filename + ".2"},
+		{Base: filename, Offset: 25, Path: filename},
+	}, segments, "segments should match")
+	_, _, ok := LocateSegment(segments, filename, 5)
+	require.False(t, ok, "removed segment should not be found")
+}
@@ -0,0 +1,88 @@
+package logrotate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultSequenceSuffix is the default format of the sequence suffix of
+// filenames, e.g.: "app.log.1".
+const defaultSequenceSuffix = ".%d"
+
+// sequenceVerbRegexp matches the integer verb in a sequence suffix format,
+// with an optional zero padding and width, e.g.: "%d" or "%02d".
+var sequenceVerbRegexp = regexp.MustCompile(`%0?\d*d`)
+
+// sequenceRegexps caches the compiled regexps of sequence suffix formats,
+// as they're used to sort files.
+var sequenceRegexps sync.Map // format => *regexp.Regexp
+
+// validateSequenceSuffix returns an error if format doesn't have exactly
+// one integer verb, or has other verbs, path separators or glob
+// metacharacters.
+func validateSequenceSuffix(format string) error {
+	if len(sequenceVerbRegexp.FindAllString(format, -1)) != 1 {
+		return fmt.Errorf("sequence suffix must have exactly one integer verb: %q", format)
+	}
+	if strings.ContainsAny(sequenceVerbRegexp.ReplaceAllString(format, ""), `%/\*?[`) {
+		return fmt.Errorf("invalid sequence suffix: %q", format)
+	}
+	return nil
+}
+
+// sequenceExpr returns the regexp expression matching the sequence suffix
+// in format, which captures the sequence.
+func sequenceExpr(format string) string {
+	loc := sequenceVerbRegexp.FindStringIndex(format)
+	return regexp.QuoteMeta(format[:loc[0]]) + `(\d+)` + regexp.QuoteMeta(format[loc[1]:])
+}
+
+// sequenceRegexp returns the compiled regexp matching the sequence suffix
+// in format at the end of a filename.
+func sequenceRegexp(format string) *regexp.Regexp {
+	if re, ok := sequenceRegexps.Load(format); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(sequenceExpr(format) + `$`)
+	sequenceRegexps.Store(format, re)
+	return re
+}
+
+// sequenceFilename returns base with the sequence suffix seq in format, or
+// base itself if seq is 0.
+func sequenceFilename(base, format string, seq uint) string {
+	if seq == 0 {
+		return base
+	}
+	return base + fmt.Sprintf(format, seq)
+}
+
+// trimSequence returns path without the sequence suffix in format, and the
+// sequence, which is 0 if path has no sequence suffix.
+func trimSequence(path, format string) (string, int) {
+	m := sequenceRegexp(format).FindStringSubmatchIndex(path)
+	if m == nil {
+		return path, 0
+	}
+	seq, err := strconv.Atoi(path[m[2]:m[3]])
+	if err != nil {
+		return path, 0
+	}
+	return path[:m[0]], seq
+}
+
+// sequenceOf returns the sequence suffix in format of path if it's base or
+// a sequence of it, e.g.: "app.log.3".
+func sequenceOf(path, base, format string) (uint, bool) {
+	if path == base {
+		return 0, true
+	}
+	trimmed, seq := trimSequence(path, format)
+	if trimmed != base {
+		return 0, false
+	}
+	return uint(seq), true
+}
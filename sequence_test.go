@@ -0,0 +1,81 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SequenceSuffix(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SequenceSuffix")
+	defer os.RemoveAll(dir)
+
+	for _, format := range []string{"", "_part", "%d%d", "/%d", "_%s%d", "*%d"} {
+		_, err := New(filepath.Join(dir, "app.log"), WithSequenceSuffix(format))
+		require.Error(t, err, "New should fail with invalid sequence suffix %q", format)
+	}
+
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithMaxSize(2),
+		WithMaxBackups(2),
+		WithSequenceSuffix("_part%02d"),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, "_part%02d", l.Options().SequenceSuffix, "snapshot should show the sequence suffix")
+
+	for i := 0; i < 3; i++ {
+		_, err = l.Write([]byte("12"))
+		require.NoError(t, err, "Write should succeed")
+	}
+	require.Equal(t, filepath.Join(dir, "app.log_part02"), l.currFilename, "sequence suffix should be formatted")
+
+	// files of the same modification time are ordered by sequence.
+	mtime := time.Now().Add(-time.Hour)
+	for _, name := range []string{"app.log", "app.log_part01"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), mtime, mtime), "Chtimes should succeed")
+	}
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, filepath.Join(dir, "app.log"), "file of the lowest sequence should be removed")
+	require.FileExists(t, filepath.Join(dir, "app.log_part01"), "file within MaxBackups should be kept")
+
+	ts, seq, ok := MatchPattern(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.3"))
+	require.True(t, ok && seq == 3 && ts.IsZero(), "MatchPattern should match the default sequence suffix")
+}
+
+func Test_byModTime(t *testing.T) {
+	mtime := time.Now()
+	var files []FileInfo
+	for _, name := range []string{"app.log", "app.log_part10", "app.log_part02"} {
+		files = append(files, FileInfo{Path: name, FileInfo: testFileInfo{modTime: mtime}})
+	}
+	sort.Sort(byModTime{files, "_part%02d"})
+	require.Equal(t, []string{"app.log_part10", "app.log_part02", "app.log"}, paths(files), "files should be ordered by sequence")
+}
+
+func Test_PurgeDir_SequenceSuffix(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PurgeDir_SequenceSuffix")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Now()
+	var names []string
+	for i, name := range []string{"app.20240101.log", "app.20240101.log_part01", "app.20240101.log-2"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644), "WriteFile should succeed")
+		mtime := now.Add(time.Duration(i-3) * time.Hour)
+		require.NoError(t, os.Chtimes(path, mtime, mtime), "Chtimes should succeed")
+		names = append(names, path)
+	}
+
+	removed, err := PurgeDir(filepath.Join(dir, "app.%Y%m%d.log"), NewMaxBackupsPolicy(1), WithSequenceSuffix("_part%02d"))
+	require.NoError(t, err, "PurgeDir should succeed")
+	require.Equal(t, names[:1], removed, "oldest file should be removed")
+	require.FileExists(t, names[1], "newest file with the sequence suffix should be kept")
+	require.FileExists(t, names[2], "file not matching the pattern should be kept")
+}
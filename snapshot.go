@@ -0,0 +1,67 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+)
+
+// SnapshotTo copies the content of the current file written so far to w,
+// atomically with respect to rotation, so that support tooling can grab
+// "the log so far" without catching a half-rotated state. In buffered mode,
+// the snapshot is taken after the previously submitted writes, the same as
+// Rotate. It returns ErrClosed after Close called.
+//
+// The file is opened and its size is taken under the internal lock, and
+// then copied to w without holding it, so slow writers of w don't block
+// logging. The subsequent writes are not included, and a rotation after the
+// snapshot was taken doesn't affect the copy.
+func (l *Logger) SnapshotTo(w io.Writer) error {
+	var f *os.File
+	var size int64
+	var err error
+	done := make(chan struct{})
+	ok := l.submit(func() {
+		f, size, err = l.openSnapshot()
+		close(done)
+	})
+	if !ok {
+		return ErrClosed
+	}
+	<-done
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, io.NewSectionReader(f, 0, size))
+	return err
+}
+
+// openSnapshot opens the current file for reading, and returns it with
+// its size, under l.mu.
+func (l *Logger) openSnapshot() (f *os.File, size int64, err error) {
+	l.mu.Lock()
+	defer func() {
+		if cerr := l.unlock(); cerr != nil {
+			tracef(os.Stderr, "failed to close rotated file: %v", cerr)
+		}
+	}()
+	if l.file.Load() == nil {
+		if err := l.openExistingOrNew(0); err != nil {
+			return nil, 0, err
+		}
+	}
+	h := l.file.Load()
+	name := h.name
+	if err := h.flush(); err != nil {
+		return nil, 0, &RotationError{Op: "flush", Path: name, Err: err}
+	}
+	if f, err = os.Open(name); err != nil {
+		return nil, 0, &RotationError{Op: "open", Path: name, Err: err}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, &RotationError{Op: "stat", Path: name, Err: err}
+	}
+	return f, fi.Size(), nil
+}
@@ -0,0 +1,40 @@
+package logrotate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SnapshotTo(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SnapshotTo")
+	defer os.RemoveAll(dir)
+
+	l, err := New(filepath.Join(dir, "app.log"), WithWriteChan(10))
+	require.NoError(t, err, "New should succeed")
+
+	var buf bytes.Buffer
+	require.NoError(t, l.SnapshotTo(&buf), "SnapshotTo should succeed")
+	require.Empty(t, buf.String(), "snapshot should be empty before writes")
+
+	_, err = l.Write([]byte("1\n"))
+	require.NoError(t, err, "Write should succeed")
+	_, err = l.Write([]byte("2\n"))
+	require.NoError(t, err, "Write should succeed")
+	buf.Reset()
+	require.NoError(t, l.SnapshotTo(&buf), "SnapshotTo should succeed")
+	require.Equal(t, "1\n2\n", buf.String(), "snapshot should include the previously submitted writes")
+
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	_, err = l.Write([]byte("3\n"))
+	require.NoError(t, err, "Write should succeed")
+	buf.Reset()
+	require.NoError(t, l.SnapshotTo(&buf), "SnapshotTo should succeed")
+	require.Equal(t, "3\n", buf.String(), "snapshot should be of the current file")
+
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.ErrorIs(t, l.SnapshotTo(&buf), ErrClosed, "SnapshotTo should return ErrClosed")
+}
@@ -0,0 +1,143 @@
+package logrotate
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+)
+
+// spillHeaderSize is the size of the length header of a spilled write.
+const spillHeaderSize = 4
+
+// spill persists the writes overflowed from queue to a spill file, which
+// are replayed into the log by writeLoop when queue drains. While there
+// are spilled writes not yet replayed, the subsequent writes are spilled
+// too, so that the order of writes is kept.
+type spill struct {
+	dir string
+
+	mu       sync.Mutex // guards following
+	file     *os.File   // created on the first overflow
+	active   bool       // whether there are spilled writes not yet replayed
+	closed   bool       // set by close, so no spill file is created again
+	readOff  int64      // offset of the next write to replay
+	writeOff int64      // offset to append the next write
+}
+
+// write appends the segments with total length size as a single write to
+// the spill file. If onlyActive is true, it appends only when there are
+// spilled writes not yet replayed. It returns whether the write was spilled.
+func (s *spill) write(size int, onlyActive bool, segments ...[]byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || (onlyActive && !s.active) {
+		return false, nil
+	}
+	if s.file == nil {
+		if err := os.MkdirAll(s.dir, 0755); err != nil {
+			return false, err
+		}
+		f, err := os.CreateTemp(s.dir, "logrotate-*.spill")
+		if err != nil {
+			return false, err
+		}
+		s.file = f
+	}
+	buf := getBuffer(spillHeaderSize + size)
+	defer putBuffer(buf)
+	*buf = binary.BigEndian.AppendUint32(*buf, uint32(size))
+	for _, seg := range segments {
+		*buf = append(*buf, seg...)
+	}
+	if _, err := s.file.WriteAt(*buf, s.writeOff); err != nil {
+		return false, err
+	}
+	s.writeOff += int64(len(*buf))
+	s.active = true
+	return true, nil
+}
+
+// next returns the next spilled write to replay, or nil if all spilled
+// writes have been replayed. On error, the unreplayable spilled writes are
+// dropped.
+func (s *spill) next() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active || s.file == nil {
+		return nil, nil
+	}
+	if s.readOff >= s.writeOff {
+		return nil, s.reset()
+	}
+	var header [spillHeaderSize]byte
+	if _, err := s.file.ReadAt(header[:], s.readOff); err != nil {
+		return nil, errors.Join(err, s.reset())
+	}
+	b := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := s.file.ReadAt(b, s.readOff+spillHeaderSize); err != nil {
+		return nil, errors.Join(err, s.reset())
+	}
+	s.readOff += int64(spillHeaderSize + len(b))
+	return b, nil
+}
+
+// reset truncates the spill file after all spilled writes are replayed or
+// dropped. s.mu must be held by the caller.
+func (s *spill) reset() error {
+	s.active = false
+	s.readOff, s.writeOff = 0, 0
+	return s.file.Truncate(0)
+}
+
+// close closes and removes the spill file.
+func (s *spill) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	err := s.file.Close()
+	s.file = nil
+	return errors.Join(err, os.Remove(name))
+}
+
+// spillWrite spills the segments if writes are being spilled, or if force
+// is true, and notifies writeLoop to replay them, as it may be idle with
+// the queue drained in the meantime. It returns whether the write was
+// spilled, and reports the error to the ErrorHandler if it fails.
+func (l *Logger) spillWrite(size int, force bool, segments ...[]byte) bool {
+	if l.spill == nil {
+		return false
+	}
+	ok, err := l.spill.write(size, !force, segments...)
+	if err != nil {
+		l.handleError(err)
+		return false
+	}
+	if ok {
+		l.metrics.Spills.Add(1)
+		l.notifyWrite()
+	}
+	return ok
+}
+
+// replaySpill replays the spilled writes into the log. If all is false, it
+// stops once queue has pending ops, so they are not delayed.
+func (l *Logger) replaySpill(all bool) {
+	if l.spill == nil {
+		return
+	}
+	for all || l.queued() == 0 {
+		b, err := l.spill.next()
+		if err != nil {
+			l.handleError(err)
+		}
+		if b == nil {
+			return
+		}
+		l.writeBuffered(b)
+	}
+}
@@ -0,0 +1,111 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SpillDir(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SpillDir")
+	defer os.RemoveAll(dir)
+
+	spillDir := filepath.Join(dir, "spill")
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(1), WithSpillDir(spillDir))
+	require.NoError(t, err, "New should succeed")
+
+	// block writeLoop, so writeCh overflows.
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+
+	var want strings.Builder
+	for i := 0; i < 100; i++ {
+		line := fmt.Sprintf("line%d\n", i)
+		want.WriteString(line)
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should spill instead of discarding")
+	}
+	metrics := l.Metrics()
+	require.Equal(t, uint64(0), metrics.Discards, "nothing should be discarded")
+	require.Equal(t, uint64(99), metrics.Spills, "overflowed writes should be spilled")
+	files, _ := filepath.Glob(filepath.Join(spillDir, "*.spill"))
+	require.Len(t, files, 1, "spill file should be created")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filename)
+		return len(data) == want.Len()
+	}, time.Second, time.Millisecond, "spilled writes should be replayed")
+	require.NoError(t, l.Close(), "Close should succeed")
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, want.String(), string(data), "spilled writes should be replayed in order")
+	files, _ = filepath.Glob(filepath.Join(spillDir, "*"))
+	require.Empty(t, files, "spill file should be removed on Close")
+}
+
+func Test_SpillDir_Resume(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SpillDir_Resume")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(1), WithSpillDir(filepath.Join(dir, "spill")))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go l.submit(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+	for _, line := range []string{"1", "2", "3"} {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err, "Write should succeed")
+	}
+	close(release)
+
+	// replayed when writeCh drains, so the later writes go to writeCh again.
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filename)
+		return string(data) == "123"
+	}, time.Second, time.Millisecond, "spilled writes should be replayed")
+	_, err = l.Write([]byte("4"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, uint64(2), l.Metrics().Spills, "Write should not spill after replayed")
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filename)
+		return string(data) == "1234"
+	}, time.Second, time.Millisecond, "Write should be written")
+}
+
+func Test_SpillDir_IdleWriteLoop(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SpillDir_IdleWriteLoop")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithWriteChan(1), WithSpillDir(filepath.Join(dir, "spill")))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	// a writer found the queue full, but writeLoop drained it and went idle
+	// before the write was spilled.
+	require.True(t, l.spillWrite(1, true, []byte("1")), "write should be spilled")
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filename)
+		return string(data) == "12"
+	}, time.Second, time.Millisecond, "spilled writes should be replayed without Close")
+}
@@ -0,0 +1,148 @@
+package logrotate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TimestampFunc returns the timestamp of a log line, or false if the line
+// has none, e.g.: a continuation line of a stack trace.
+type TimestampFunc func(line []byte) (time.Time, bool)
+
+// NewLayoutTimestamp returns a TimestampFunc which parses the leading
+// fields of a log line, as many as the space-separated fields of the time
+// layout, e.g.: time.RFC3339 or time.DateTime, in loc if the layout has no
+// time zone.
+func NewLayoutTimestamp(layout string, loc *time.Location) TimestampFunc {
+	fields := len(strings.Fields(layout))
+	return func(line []byte) (time.Time, bool) {
+		prefix := strings.Fields(string(line))
+		if len(prefix) < fields {
+			return time.Time{}, false
+		}
+		t, err := time.ParseInLocation(layout, strings.Join(prefix[:fields], " "), loc)
+		return t, err == nil
+	}
+}
+
+// SplitFile splits the existing log file at path into per-interval files
+// named by the pattern, e.g.: to backfill the directory structure of logs
+// written before migrating to this package, so that retention applies to
+// historical data. Each line goes to the file of the interval its timestamp
+// falls in, in the location of the timestamp, and a line without a
+// timestamp goes with the previous line. If interval <= 0, it's 24 hours.
+//
+// The modification time of each file is set to the timestamp of its latest
+// line. An existing file is never overwritten, but an error is returned.
+// It returns the created files in the order of their first lines.
+func SplitFile(path, pattern string, interval time.Duration, timestamp TimestampFunc) ([]string, error) {
+	filenamePattern, err := newStrftime(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strftime pattern: %v", err)
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var (
+		created []string
+		latest  = make(map[string]time.Time)
+		dst     *os.File
+		pending [][]byte // lines before the first timestamp
+	)
+	closeDst := func() error {
+		if dst == nil {
+			return nil
+		}
+		err := dst.Close()
+		dst = nil
+		return err
+	}
+	switchDst := func(name string) error {
+		if dst != nil && dst.Name() == name {
+			return nil
+		}
+		if err := closeDst(); err != nil {
+			return err
+		}
+		flag := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+		if _, ok := latest[name]; ok {
+			// lines out of order, back to a file created before.
+			flag = os.O_WRONLY | os.O_APPEND
+		} else if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(name, flag, defaultFileMode)
+		if err != nil {
+			return err
+		}
+		if _, ok := latest[name]; !ok {
+			created = append(created, name)
+			latest[name] = time.Time{}
+		}
+		dst = f
+		return nil
+	}
+
+	r := bufio.NewReader(src)
+	for {
+		line, rerr := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if t, ok := timestamp(line); ok {
+				_, offset := t.Zone()
+				tzOffset := int64(offset) * int64(time.Second)
+				rotationTime := evalRotationTime(t.UnixNano(), tzOffset, int64(interval))
+				name := filenamePattern.FormatString(time.Unix(0, rotationTime-tzOffset).In(t.Location()))
+				if err := switchDst(name); err != nil {
+					closeDst()
+					return created, err
+				}
+				if t.After(latest[name]) {
+					latest[name] = t
+				}
+				for _, p := range pending {
+					if _, err := dst.Write(p); err != nil {
+						closeDst()
+						return created, err
+					}
+				}
+				pending = nil
+			}
+			if dst == nil {
+				pending = append(pending, line)
+			} else if _, err := dst.Write(line); err != nil {
+				closeDst()
+				return created, err
+			}
+		}
+		if errors.Is(rerr, io.EOF) {
+			break
+		} else if rerr != nil {
+			closeDst()
+			return created, rerr
+		}
+	}
+	if err := closeDst(); err != nil {
+		return created, err
+	}
+	if len(pending) > 0 {
+		return created, fmt.Errorf("no timestamp found in %s", path)
+	}
+	for _, name := range created {
+		if err := os.Chtimes(name, latest[name], latest[name]); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
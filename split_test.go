@@ -0,0 +1,53 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SplitFile(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SplitFile")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	src := filepath.Join(dir, "huge.log")
+	require.NoError(t, os.WriteFile(src, []byte(
+		"2024-01-01 10:00:00 first\n"+
+			"2024-01-01 23:59:59 panic\n"+
+			"\tgoroutine 1\n"+
+			"2024-01-02 00:00:01 second\n"+
+			"2024-01-01 23:59:58 late\n"+
+			"2024-01-03 08:00:00 third"), 0644), "WriteFile should succeed")
+
+	timestamp := NewLayoutTimestamp(time.DateTime, time.UTC)
+	pattern := filepath.Join(dir, "%Y", "app.%Y%m%d.log")
+	created, err := SplitFile(src, pattern, 0, timestamp)
+	require.NoError(t, err, "SplitFile should succeed")
+	day1 := filepath.Join(dir, "2024", "app.20240101.log")
+	day2 := filepath.Join(dir, "2024", "app.20240102.log")
+	day3 := filepath.Join(dir, "2024", "app.20240103.log")
+	require.Equal(t, []string{day1, day2, day3}, created, "created files should match")
+
+	for path, want := range map[string]struct {
+		content string
+		mtime   time.Time
+	}{
+		day1: {"2024-01-01 10:00:00 first\n2024-01-01 23:59:59 panic\n\tgoroutine 1\n2024-01-01 23:59:58 late\n", time.Date(2024, 1, 1, 23, 59, 59, 0, time.UTC)},
+		day2: {"2024-01-02 00:00:01 second\n", time.Date(2024, 1, 2, 0, 0, 1, 0, time.UTC)},
+		day3: {"2024-01-03 08:00:00 third", time.Date(2024, 1, 3, 8, 0, 0, 0, time.UTC)},
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		require.Equal(t, want.content, string(data), "content of %s should match", path)
+		fi, err := os.Stat(path)
+		require.NoError(t, err, "Stat should succeed")
+		require.True(t, want.mtime.Equal(fi.ModTime()), "mtime of %s should be the latest timestamp", path)
+	}
+
+	_, err = SplitFile(src, pattern, 0, timestamp)
+	require.Error(t, err, "SplitFile should not overwrite existing files")
+}
@@ -0,0 +1,98 @@
+package logrotate
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// standbyFile is the file of the next interval pre-opened ahead of the
+// boundary, see WithWarmStandby.
+type standbyFile struct {
+	name string
+	f    *os.File
+}
+
+// standbyLoop runs in a goroutine to pre-open the file of the next interval
+// WarmStandby before each MaxInterval boundary until Close is called.
+func (l *Logger) standbyLoop() {
+	for {
+		next := l.nextRotationTime()
+		select {
+		case <-l.life.quit:
+			return
+		case <-l.after(next.Sub(l.opts.clock.Now()) - l.opts.warmStandby):
+		}
+		l.prepareStandby(next)
+		// wait for the boundary, so the next standby is of the next interval.
+		select {
+		case <-l.life.quit:
+			return
+		case <-l.after(next.Sub(l.opts.clock.Now())):
+		}
+	}
+}
+
+// prepareStandby makes the directory of the file of the interval starting
+// at next, and pre-opens the file, unless it exists already, e.g.: written
+// by the predecessor. The previous standby is discarded if not taken.
+func (l *Logger) prepareStandby(next time.Time) {
+	base := l.genBaseFilename(next.UnixNano() + l.tzOffset)
+	name := sequenceFilename(base, l.opts.seqSuffix, 0)
+	flag := os.O_CREATE | os.O_EXCL | os.O_WRONLY | l.opts.durability.flag()
+	f, err := l.createFile(name, flag)
+	if err != nil {
+		if !errors.Is(err, fs.ErrExist) {
+			l.handleError(err)
+		}
+		return
+	}
+	l.mu.Lock()
+	defer func() {
+		if err := l.unlock(); err != nil {
+			l.handleError(err)
+		}
+	}()
+	if l.closed.Load() {
+		l.discardStandby(&standbyFile{name: name, f: f})
+		return
+	}
+	l.discardStandby(l.standby)
+	l.standby = &standbyFile{name: name, f: f}
+}
+
+// isStandby reports whether filename is the pre-opened standby file. l.mu
+// must be held by the caller.
+func (l *Logger) isStandby(filename string) bool {
+	return l.standby != nil && l.standby.name == filename
+}
+
+// takeStandby takes the pre-opened file if it's filename, or returns nil.
+// l.mu must be held by the caller.
+func (l *Logger) takeStandby(filename string) *os.File {
+	if !l.isStandby(filename) {
+		return nil
+	}
+	f := l.standby.f
+	l.standby = nil
+	return f
+}
+
+// discardStandby closes the standby file s not taken, if any, and removes
+// it unless it has been written by others in the meantime. l.mu must be
+// held by the caller.
+func (l *Logger) discardStandby(s *standbyFile) {
+	if s == nil {
+		return
+	}
+	if l.standby == s {
+		l.standby = nil
+	}
+	fi, err := s.f.Stat()
+	s.f.Close()
+	if err == nil && fi.Size() == 0 && s.name != l.currFilename {
+		os.Remove(s.name)
+		l.index.touch(s.name)
+	}
+}
@@ -0,0 +1,60 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WarmStandby(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WarmStandby")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithWarmStandby(0))
+	require.Error(t, err, "non-positive lead should be rejected")
+	_, err = New(filepath.Join(dir, "app.log"), WithWarmStandby(time.Minute), WithSavelog(3, false))
+	require.Error(t, err, "savelog should be rejected")
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 30, 0, 0, time.Local))
+	l, err := New(
+		filepath.Join(dir, "%Y%m%d%H", "app.log"),
+		WithClock(clock),
+		WithMaxInterval(time.Hour),
+		WithWarmStandby(time.Minute),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, time.Minute, l.Options().WarmStandby, "WarmStandby should match")
+
+	_, err = l.Write([]byte("10\n"))
+	require.NoError(t, err, "Write should succeed")
+
+	// the directory and the file of the next interval are ready ahead.
+	next := filepath.Join(dir, "2024010111", "app.log")
+	l.prepareStandby(l.nextRotationTime())
+	require.FileExists(t, next, "standby file should be pre-created")
+	l.mu.RLock()
+	standby := l.standby
+	l.mu.RUnlock()
+	require.NotNil(t, standby, "standby file should be pre-opened")
+	require.Equal(t, next, standby.name, "standby file should be of the next interval")
+
+	clock.Advance(time.Hour)
+	_, err = l.Write([]byte("11\n"))
+	require.NoError(t, err, "Write should succeed")
+	f, ok := l.file.Load().osFile()
+	require.True(t, ok, "current file should be an *os.File")
+	require.Same(t, standby.f, f, "standby file should be taken on rotation")
+	b, err := os.ReadFile(next)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "11\n", string(b), "write should go to the standby file")
+
+	// the standby file not taken is removed on Close.
+	l.prepareStandby(l.nextRotationTime())
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.NoFileExists(t, filepath.Join(dir, "2024010112", "app.log"), "standby file should be removed on Close")
+}
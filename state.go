@@ -0,0 +1,99 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stateFile persists the rotation time and the sequence of the current file
+// to a sidecar file, so that a restarted Logger continues the schedule of
+// its predecessor, instead of resetting the rotation time baseline to now,
+// e.g.: with a stable pattern like "app.log" and MaxInterval, the file
+// written before the restart is still rotated at its interval boundary.
+type stateFile struct {
+	path string
+
+	rotationTime int64 // Unix nanoseconds with location
+	sequence     uint
+	restored     bool // whether loaded and not yet taken
+}
+
+// loadStateFile loads the state persisted at path by a predecessor, if any.
+func loadStateFile(path string) (*stateFile, error) {
+	s := &stateFile{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	line := strings.TrimSpace(string(data))
+	if _, err := fmt.Sscanf(line, "%d %d", &s.rotationTime, &s.sequence); err != nil {
+		// a torn state can't be written, as the file is replaced
+		// atomically, so it's corrupted by others.
+		return nil, fmt.Errorf("invalid state %q: %v", line, err)
+	}
+	s.restored = true
+	return s, nil
+}
+
+// take returns the restored rotation time and sequence once, and false if
+// nothing is restored. It's a no-op if s is nil, as is save.
+func (s *stateFile) take() (int64, uint, bool) {
+	if s == nil || !s.restored {
+		return 0, 0, false
+	}
+	s.restored = false
+	return s.rotationTime, s.sequence, true
+}
+
+// save persists the rotation time and the sequence if changed.
+func (s *stateFile) save(rotationTime int64, sequence uint) error {
+	if s == nil || (s.rotationTime == rotationTime && s.sequence == sequence) {
+		return nil
+	}
+	data := fmt.Sprintf("%d %d\n", rotationTime, sequence)
+	if err := replaceFile(s.path, []byte(data)); err != nil {
+		return err
+	}
+	s.rotationTime, s.sequence = rotationTime, sequence
+	return nil
+}
+
+// saveState persists the rotation time and the sequence of the current file
+// if WithStateFile. l.mu must be held by the caller.
+func (l *Logger) saveState() {
+	if err := l.state.save(l.currRotationTime, l.currSequence); err != nil {
+		l.report(&RotationError{Op: "state", Path: l.opts.stateFile, Err: err})
+	}
+}
+
+// replaceFile replaces the file at path with data atomically, so that it's
+// never torn by a crash.
+func replaceFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		// persisted before replacing, so the file is never torn.
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,66 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StateFile(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_StateFile")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC))
+	filename := filepath.Join(dir, "app.log")
+	stateFile := filepath.Join(dir, ".app.state")
+	newLogger := func(options ...Option) *Logger {
+		l, err := New(
+			filename,
+			append([]Option{WithClock(clock), WithMaxInterval(time.Hour)}, options...)...,
+		)
+		require.NoError(t, err, "New should succeed")
+		return l
+	}
+	readFile := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+	hour := func(h int) int64 {
+		return time.Date(2024, 1, 1, h, 0, 0, 0, time.UTC).UnixNano()
+	}
+
+	l := newLogger(WithStateFile(stateFile))
+	require.Equal(t, stateFile, l.Options().StateFile, "StateFile should match")
+	_, err := l.Write([]byte("a"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.Equal(t, fmt.Sprintf("%d 0\n", hour(10)), readFile(stateFile), "state should be persisted")
+
+	// restarted in the next interval, the file written before is rotated.
+	clock.Advance(40 * time.Minute)
+	l = newLogger(WithStateFile(stateFile))
+	_, err = l.Write([]byte("b"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.Equal(t, "a", readFile(filename), "file content should match")
+	require.Equal(t, "b", readFile(filename+".1"), "file content should match")
+	require.Equal(t, fmt.Sprintf("%d 1\n", hour(11)), readFile(stateFile), "state should be updated on rotation")
+
+	// without the state file, the baseline is reset on restart.
+	clock.Advance(time.Hour)
+	l = newLogger()
+	_, err = l.Write([]byte("c"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.Equal(t, "ac", readFile(filename), "file content should match")
+
+	require.NoError(t, os.WriteFile(stateFile, []byte("corrupted"), 0644), "WriteFile should succeed")
+	_, err = New(filename, WithStateFile(stateFile))
+	require.Error(t, err, "New should fail on a corrupted state")
+}
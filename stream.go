@@ -0,0 +1,86 @@
+package logrotate
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// streamWriter is the io.WriteCloser returned by Writer.
+type streamWriter struct {
+	l      *Logger
+	closed atomic.Bool
+}
+
+// Writer returns a sub-writer pinned to the current file, for streaming a
+// large blob, e.g.: a panic dump, which is never split across files: no
+// rotation occurs until the sub-writer is closed, and a rotation requested
+// meanwhile, by Rotate or ScheduledRotation, is deferred until then, while
+// MaxSize, MaxLines, MaxInterval and RotatePredicate are evaluated again on
+// the next write.
+//
+// Writes of the sub-writer go to the file directly, even in buffered mode,
+// and writes of the Logger meanwhile go to the same file. The sub-writer
+// must be closed, and it returns os.ErrClosed after closed.
+func (l *Logger) Writer() io.WriteCloser {
+	l.mu.Lock()
+	l.pins++
+	l.mu.Unlock()
+	return &streamWriter{l: l}
+}
+
+// Write writes b to the current file of the Logger.
+func (w *streamWriter) Write(b []byte) (n int, err error) {
+	if w.closed.Load() {
+		return 0, os.ErrClosed
+	}
+	l := w.l
+	if len(l.opts.redactors) > 0 {
+		size := len(b)
+		b = l.redact(b)
+		defer func() { n = redactedLen(n, err, size) }()
+	}
+	if l.closed.Load() {
+		l.discard(&l.metrics.DiscardsClosed, len(b), b)
+		return 0, ErrClosed
+	}
+	if l.opts.mirror != nil {
+		defer l.mirror(&err, b)
+	}
+	return l.write(b)
+}
+
+// Close unpins the current file, and runs the rotation deferred by the
+// sub-writer, if any, once no other sub-writers are open.
+func (w *streamWriter) Close() error {
+	if !w.closed.CompareAndSwap(false, true) {
+		return os.ErrClosed
+	}
+	l := w.l
+	l.mu.Lock()
+	l.pins--
+	var err error
+	if reason := l.pendingReason; l.pins == 0 && reason != 0 {
+		l.pendingReason = 0
+		if !l.closed.Load() {
+			_, err = l.rotateFor(reason)
+		}
+	}
+	if cerr := l.unlock(); cerr != nil {
+		err = errors.Join(err, cerr)
+	}
+	return err
+}
+
+// rotateOrDefer rotates the current file for reason, or defers the
+// rotation until the open Writers are closed. l.mu must be held by the
+// caller.
+func (l *Logger) rotateOrDefer(reason RotateReason) error {
+	if l.pins > 0 {
+		l.pendingReason = reason
+		return nil
+	}
+	_, err := l.rotateFor(reason)
+	return err
+}
@@ -0,0 +1,45 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Writer(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Writer")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	l, err := New(filename, WithMaxSize(4))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	readFile := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "ReadFile should succeed")
+		return string(data)
+	}
+
+	w := l.Writer()
+	for _, chunk := range []string{"aaaa", "bbbb"} {
+		_, err = w.Write([]byte(chunk))
+		require.NoError(t, err, "Write should succeed")
+	}
+	_, err = l.Write([]byte("c"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Rotate(), "Rotate should be deferred")
+	require.Equal(t, filename, l.currentFilename(), "file should not be rotated while pinned")
+	require.Equal(t, "aaaabbbbc", readFile(filename), "writes should not be split")
+
+	require.NoError(t, w.Close(), "Close should succeed")
+	require.Equal(t, filename+".1", l.currentFilename(), "deferred rotation should run on Close")
+	_, err = w.Write([]byte("x"))
+	require.ErrorIs(t, err, os.ErrClosed, "Write should fail after Close")
+	require.ErrorIs(t, w.Close(), os.ErrClosed, "Close should fail after Close")
+
+	_, err = l.Write([]byte("d"))
+	require.NoError(t, err, "Write should succeed")
+	require.Equal(t, "d", readFile(filename+".1"), "file should be rotated after unpinned")
+}
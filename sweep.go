@@ -0,0 +1,95 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partialSuffix is the suffix of the files written before being renamed
+// into place, e.g.: by compression, bundling and replaceFile.
+const partialSuffix = ".tmp"
+
+// isPartial reports whether path is a partial artifact of an operation,
+// e.g.: "app.log.1.gz.tmp", or "app.log.symlink#123.4" of linkOnce.
+func isPartial(path string) bool {
+	return strings.HasSuffix(path, partialSuffix) || strings.Contains(filepath.Base(path), ".symlink#")
+}
+
+// sweepLoop runs in a goroutine to request a sweep of partial artifacts on
+// startup and every PartialSweep, until Close is called.
+func (l *Logger) sweepLoop() {
+	for {
+		l.sweepDue.Store(true)
+		l.mill()
+		select {
+		case <-l.life.quit:
+			return
+		case <-l.after(l.opts.partialSweep):
+		}
+	}
+}
+
+// sweepPartials removes the partial artifacts older than PartialSweep, left
+// by operations killed midway. A partial compression whose source still
+// exists is resumed by queueing the source again.
+func (l *Logger) sweepPartials() error {
+	l.sweepMu.Lock()
+	defer l.sweepMu.Unlock()
+	paths, err := filepath.Glob(l.globPattern)
+	if err != nil {
+		return err
+	}
+	for _, sidecar := range []string{l.opts.journal, l.opts.stateFile, l.opts.segmentIndex} {
+		if sidecar != "" {
+			paths = append(paths, sidecar+partialSuffix)
+		}
+	}
+
+	now := l.opts.clock.Now()
+	var errs []error
+	for _, path := range paths {
+		if !isPartial(path) {
+			continue
+		}
+		fi, err := os.Lstat(path)
+		if err != nil || now.Sub(fi.ModTime()) < l.opts.partialSweep {
+			continue // gone, or may still be in progress
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, &PurgeError{Path: path, Err: err})
+			continue
+		}
+		l.index.touch(path)
+		if src, ok := strings.CutSuffix(path, ".gz"+partialSuffix); ok {
+			l.resumeCompression(src)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resumeCompression queues the source of an interrupted compression at src
+// again, unless it's gone, current, or queued already.
+func (l *Logger) resumeCompression(src string) {
+	if _, err := os.Lstat(src); err != nil || src == l.currentFilename() {
+		return
+	}
+	if l.opts.savelogCycle > 0 {
+		if !l.opts.savelogCompress {
+			return
+		}
+		l.savelogMu.Lock()
+		defer l.savelogMu.Unlock()
+		if !containsString(l.savelogQueue, src) {
+			l.savelogQueue = append(l.savelogQueue, src)
+		}
+		return
+	}
+	l.rotatedMu.Lock()
+	queued := containsString(l.rotated, src)
+	l.rotatedMu.Unlock()
+	if !queued {
+		l.queueRotated(src)
+	}
+}
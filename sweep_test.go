@@ -0,0 +1,59 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PartialSweep(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_PartialSweep")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	_, err := New(filepath.Join(dir, "app.log"), WithPartialSweep(0))
+	require.Error(t, err, "non-positive sweep age should be rejected")
+
+	clock := clockwork.NewFakeClockAt(time.Now())
+	old := clock.Now().Add(-2 * time.Hour)
+	create := func(name string, mtime time.Time) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("partial"), 0644), "WriteFile should succeed")
+		require.NoError(t, os.Chtimes(path, mtime, mtime), "Chtimes should succeed")
+		return path
+	}
+	source := create("app.log.1", old)
+	partialGz := create("app.log.1.gz.tmp", old)
+	staleLink := create("app.log.symlink#123.4", old)
+	staleState := create("state.tmp", old)
+	youngBundle := create("app.log.20240101.zip.tmp", clock.Now())
+
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithClock(clock),
+		WithPartialSweep(time.Hour),
+		WithStateFile(filepath.Join(dir, "state")),
+		WithPostRotateProcessors(NewGzipProcessor()),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, time.Hour, l.Options().PartialSweep, "PartialSweep should match")
+
+	l.sweepDue.Store(true)
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+
+	// the interrupted compression is resumed, maybe by the startup sweep.
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(source)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond, "source should be removed after compression")
+	require.FileExists(t, source+".gz", "source should be compressed")
+	for _, path := range []string{partialGz, staleLink, staleState} {
+		require.NoFileExists(t, path, "stale partial artifact should be removed")
+	}
+	require.FileExists(t, youngBundle, "young partial artifact should be kept")
+}
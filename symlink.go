@@ -0,0 +1,123 @@
+package logrotate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SymlinkRule selects the target of a symlink on each mill run. current is
+// the current file, or "" if not open, and files are the log files sorted
+// newest first as for RetentionPolicy. It returns "" to leave the symlink
+// unchanged.
+type SymlinkRule func(current string, files []FileInfo, now time.Time) string
+
+// SymlinkCurrent links the current file, or the latest log file if the
+// current file is not open. It's the rule of WithSymlink.
+func SymlinkCurrent(current string, files []FileInfo, now time.Time) string {
+	if current != "" {
+		return current
+	}
+	if len(files) > 0 {
+		return files[0].Path
+	}
+	return ""
+}
+
+// SymlinkNewestOfDay links the newest log file of the current day in the
+// location of now, e.g.: for a "today" link, or leaves the symlink
+// unchanged if there is none.
+func SymlinkNewestOfDay(current string, files []FileInfo, now time.Time) string {
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	for _, f := range files {
+		if !f.Time().Before(midnight) {
+			return f.Path
+		}
+	}
+	return ""
+}
+
+// SymlinkNewest links the newest log file, regardless of the current file.
+// It's the rule of WithSymlink with SymlinkNewestByTime.
+func SymlinkNewest(current string, files []FileInfo, now time.Time) string {
+	if len(files) > 0 {
+		return files[0].Path
+	}
+	return ""
+}
+
+// SymlinkTarget is the file which Symlink is linked to, see
+// WithSymlinkTarget.
+type SymlinkTarget int
+
+const (
+	// SymlinkActiveFile links the current file of the Logger.
+	SymlinkActiveFile SymlinkTarget = iota
+	// SymlinkNewestByTime links the newest log file in the directory.
+	SymlinkNewestByTime
+)
+
+// String returns the name of t.
+func (t SymlinkTarget) String() string {
+	switch t {
+	case SymlinkActiveFile:
+		return "active-file"
+	case SymlinkNewestByTime:
+		return "newest-by-time"
+	default:
+		return fmt.Sprintf("SymlinkTarget(%d)", int(t))
+	}
+}
+
+// symlinkRule is a symlink name and its rule.
+type symlinkRule struct {
+	name string
+	rule SymlinkRule
+}
+
+// symlinkRules returns the rules of Symlink and Symlinks, sorted by name.
+func (opts *Options) symlinkRules() []symlinkRule {
+	var rules []symlinkRule
+	if opts.symlink != "" {
+		rule := SymlinkCurrent
+		if opts.symlinkTarget == SymlinkNewestByTime {
+			rule = SymlinkNewest
+		}
+		rules = append(rules, symlinkRule{name: opts.symlink, rule: rule})
+	}
+	for name, rule := range opts.symlinks {
+		if name != opts.symlink {
+			rules = append(rules, symlinkRule{name: name, rule: rule})
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].name < rules[j].name })
+	return rules
+}
+
+// evalSymlink evaluates the strftime tokens in the symlink name, if any,
+// e.g.: "logs/%Y/%m/current", at rotationTime, so the symlink can live
+// alongside the directory of the period.
+func (l *Logger) evalSymlink(name string, rotationTime int64) string {
+	if !strings.Contains(name, "%") {
+		return name
+	}
+	p, err := newStrftime(name)
+	if err != nil {
+		return name // validated by the options
+	}
+	return genBaseFilename(p, l.opts.clock, rotationTime)
+}
+
+// validateSymlink returns an error if the strftime tokens in the symlink
+// name are invalid.
+func validateSymlink(name string) error {
+	if !strings.Contains(name, "%") {
+		return nil
+	}
+	if _, err := newStrftime(name); err != nil {
+		return fmt.Errorf("invalid symlink %q: %v", name, err)
+	}
+	return nil
+}
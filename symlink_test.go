@@ -0,0 +1,148 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SymlinkRules(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 30, 0, 0, time.UTC)
+	files := genTestFiles(now, 20*time.Minute, 3, 10) // 00:30, 00:10, 23:50
+
+	require.Equal(t, "app.log", SymlinkCurrent("app.log", files, now), "current file should be linked")
+	require.Equal(t, "log.0", SymlinkCurrent("", files, now), "latest file should be linked if not open")
+	require.Empty(t, SymlinkCurrent("", nil, now), "symlink should be unchanged without files")
+
+	require.Equal(t, "log.0", SymlinkNewest("app.log", files, now), "newest file should be linked regardless of current")
+	require.Equal(t, "log.0", SymlinkNewestOfDay("", files, now), "newest file of today should be linked")
+	require.Empty(t, SymlinkNewestOfDay("", files[2:], now), "symlink should be unchanged without files of today")
+}
+
+func Test_WithSymlinks(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WithSymlinks")
+	defer os.RemoveAll(dir)
+
+	latest, oldest := filepath.Join(dir, "latest"), filepath.Join(dir, "oldest")
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithSymlink(filepath.Join(dir, "current")),
+		WithSymlinks(map[string]SymlinkRule{latest: SymlinkCurrent}),
+		WithSymlinks(map[string]SymlinkRule{
+			oldest: func(current string, files []FileInfo, now time.Time) string {
+				return files[len(files)-1].Path
+			},
+		}),
+		WithMaxBackups(1),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, 2, l.Options().Symlinks, "Symlinks should match")
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Rotate(), "Rotate should succeed")
+	_, err = l.Write([]byte("2"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+
+	readlink := func(name string) string {
+		target, err := os.Readlink(name)
+		require.NoError(t, err, "Readlink should succeed")
+		return target
+	}
+	require.Equal(t, "app.log.1", readlink(filepath.Join(dir, "current")), "Symlink should link the current file")
+	require.Equal(t, "app.log.1", readlink(latest), "latest should link the current file")
+	require.Equal(t, "app.log", readlink(oldest), "oldest should link the oldest file")
+	require.FileExists(t, filepath.Join(dir, "app.log"), "linked files should not be removed")
+}
+
+func Test_SymlinkTarget(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SymlinkTarget")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithSymlinkTarget(SymlinkNewestByTime+1))
+	require.Error(t, err, "invalid symlink target should be rejected")
+
+	for _, target := range []SymlinkTarget{SymlinkActiveFile, SymlinkNewestByTime} {
+		t.Run(target.String(), func(t *testing.T) {
+			dir := filepath.Join(dir, target.String())
+			defer os.RemoveAll(dir)
+
+			clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			symlink := filepath.Join(dir, "current")
+			l, err := New(
+				filepath.Join(dir, "app.%Y%m%d.log"),
+				WithClock(clock),
+				WithMaxSize(4),
+				WithSymlink(symlink),
+				WithSymlinkTarget(target),
+			)
+			require.NoError(t, err, "New should succeed")
+			defer l.Close()
+			require.Equal(t, target, l.Options().SymlinkTarget, "SymlinkTarget should match")
+
+			// sequence files created on MaxSize within the interval.
+			for _, line := range []string{"aaa\n", "bbb\n", "ccc\n"} {
+				_, err = l.Write([]byte(line))
+				require.NoError(t, err, "Write should succeed")
+			}
+			require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+			linked, err := os.Readlink(symlink)
+			require.NoError(t, err, "Readlink should succeed")
+			require.Equal(t, filepath.Base(l.currentFilename()), linked, "highest sequence file should be linked")
+			require.Equal(t, "app.20240101.log.2", linked, "highest sequence file should be linked")
+
+			// a newer sequence file written by another process.
+			other := filepath.Join(dir, "app.20240101.log.5")
+			require.NoError(t, os.WriteFile(other, []byte("ddd\n"), 0644), "WriteFile should succeed")
+			require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+			linked, err = os.Readlink(symlink)
+			require.NoError(t, err, "Readlink should succeed")
+			if target == SymlinkActiveFile {
+				require.Equal(t, "app.20240101.log.2", linked, "active file should be linked")
+			} else {
+				require.Equal(t, "app.20240101.log.5", linked, "newest file should be linked")
+			}
+		})
+	}
+}
+
+func Test_SymlinkTokens(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_SymlinkTokens")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithSymlink(filepath.Join(dir, "%Q")))
+	require.Error(t, err, "New should fail with invalid symlink tokens")
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "%Y%m%d", "app.log"),
+		WithClock(clock),
+		WithMaxInterval(24*time.Hour),
+		WithSymlink(filepath.Join(dir, "%Y%m%d", "current")),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	readlink := func(name string) string {
+		target, err := os.Readlink(name)
+		require.NoError(t, err, "Readlink should succeed")
+		return target
+	}
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.Equal(t, "app.log", readlink(filepath.Join(dir, "20240101", "current")), "symlink of day 1 should link the current file")
+
+	clock.Advance(24 * time.Hour)
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.Equal(t, "app.log", readlink(filepath.Join(dir, "20240102", "current")), "symlink of day 2 should live in the directory of day 2")
+	require.Equal(t, "app.log", readlink(filepath.Join(dir, "20240101", "current")), "symlink of day 1 should be kept")
+}
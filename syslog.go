@@ -0,0 +1,166 @@
+package logrotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Facility is the syslog facility of the entries forwarded by WithSyslog.
+type Facility int
+
+const (
+	FacilityKern   Facility = 0
+	FacilityUser   Facility = 1
+	FacilityDaemon Facility = 3
+	FacilityAuth   Facility = 4
+	FacilityLocal0 Facility = 16
+	FacilityLocal1 Facility = 17
+	FacilityLocal2 Facility = 18
+	FacilityLocal3 Facility = 19
+	FacilityLocal4 Facility = 20
+	FacilityLocal5 Facility = 21
+	FacilityLocal6 Facility = 22
+	FacilityLocal7 Facility = 23
+)
+
+// syslogSeverity is the syslog severity of forwarded entries: informational.
+const syslogSeverity = 6
+
+// syslogAddrs are the local datagram sockets of syslog daemons and
+// journald, tried in order. Mocked out for testing.
+var syslogAddrs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+const (
+	forwardQueueSize = 1024        // max entries queued for forwarding
+	forwardBackoff   = time.Second // backoff between dials after a failure
+)
+
+// forwarder forwards the entries written to files to the local syslog or
+// journald in its own goroutine, so that a slow daemon never stalls writes.
+type forwarder struct {
+	l        *Logger
+	facility Facility
+	tag      string
+	ch       chan QueueEntry // entries waiting to be forwarded
+	done     chan struct{}   // closed when run returns
+
+	mu     sync.RWMutex // guards following, and sending on ch
+	closed bool         // set by close, so send never sends on closed ch
+
+	conn     net.Conn  // only accessed by run
+	nextDial time.Time // only accessed by run
+}
+
+// newForwarder starts a forwarder of l's entries if WithSyslog, or returns
+// nil.
+func newForwarder(l *Logger) *forwarder {
+	if l.opts.syslogTag == "" {
+		return nil
+	}
+	fw := &forwarder{
+		l:        l,
+		facility: l.opts.syslogFacility,
+		tag:      l.opts.syslogTag,
+		ch:       make(chan QueueEntry, forwardQueueSize),
+		done:     make(chan struct{}),
+	}
+	go fw.run()
+	return fw
+}
+
+// send queues a copy of b written to the file for forwarding. The entry is
+// dropped if the queue is full. It's a no-op on a nil forwarder.
+func (fw *forwarder) send(b []byte) {
+	if fw == nil || len(b) == 0 {
+		return
+	}
+	e := QueueEntry{Data: append([]byte(nil), b...), Time: fw.l.opts.clock.Now()}
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+	if fw.closed {
+		fw.l.metrics.ForwardErrors.Add(1)
+		return
+	}
+	select {
+	case fw.ch <- e:
+	default:
+		fw.l.metrics.ForwardErrors.Add(1)
+	}
+}
+
+// close forwards the queued entries, and closes the connection. It's a
+// no-op on a nil forwarder.
+func (fw *forwarder) close() {
+	if fw == nil {
+		return
+	}
+	fw.mu.Lock()
+	if !fw.closed {
+		fw.closed = true
+		close(fw.ch)
+	}
+	fw.mu.Unlock()
+	<-fw.done
+}
+
+// run forwards each line of the queued entries as a message until close.
+func (fw *forwarder) run() {
+	defer close(fw.done)
+	for e := range fw.ch {
+		for _, line := range bytes.Split(bytes.TrimSuffix(e.Data, []byte("\n")), []byte("\n")) {
+			if err := fw.forward(e.Time, line); err != nil {
+				fw.l.metrics.ForwardErrors.Add(1)
+				continue
+			}
+			fw.l.metrics.Forwarded.Add(1)
+		}
+	}
+	if fw.conn != nil {
+		fw.conn.Close()
+	}
+}
+
+// forward sends line as a message in the local syslog format, dialing the
+// daemon on demand.
+func (fw *forwarder) forward(t time.Time, line []byte) error {
+	if fw.conn == nil {
+		if t.Before(fw.nextDial) {
+			return errors.New("syslog unavailable")
+		}
+		conn, err := dialSyslog()
+		if err != nil {
+			// reported once per backoff, as every line would fail alike.
+			fw.nextDial = t.Add(forwardBackoff)
+			fw.l.handleError(&RotationError{Op: "forward", Path: "syslog", Err: err})
+			return err
+		}
+		fw.conn = conn
+	}
+	pri := int(fw.facility)*8 + syslogSeverity
+	msg := fmt.Sprintf("<%d>%s %s[%d]: %s", pri, t.Format(time.Stamp), fw.tag, os.Getpid(), line)
+	if _, err := fw.conn.Write([]byte(msg)); err != nil {
+		// redialed for the next line, e.g.: the daemon restarted.
+		fw.conn.Close()
+		fw.conn = nil
+		return err
+	}
+	return nil
+}
+
+// dialSyslog connects to the first available local syslog socket.
+func dialSyslog() (net.Conn, error) {
+	var errs []error
+	for _, addr := range syslogAddrs {
+		conn, err := net.Dial("unixgram", addr)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
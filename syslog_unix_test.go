@@ -0,0 +1,58 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package logrotate
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Syslog(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Syslog")
+	defer os.RemoveAll(dir)
+
+	_, err := New(filepath.Join(dir, "app.log"), WithSyslog(Facility(24), "app"))
+	require.Error(t, err, "invalid facility should be rejected")
+
+	// a short path, as the length of socket paths is limited.
+	sockDir, err := os.MkdirTemp("", "syslog")
+	require.NoError(t, err, "MkdirTemp should succeed")
+	defer os.RemoveAll(sockDir)
+	sock := filepath.Join(sockDir, "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	require.NoError(t, err, "ListenUnixgram should succeed")
+	defer conn.Close()
+	defer func(addrs []string) { syslogAddrs = addrs }(syslogAddrs)
+	syslogAddrs = []string{filepath.Join(sockDir, "missing"), sock}
+
+	l, err := New(filepath.Join(dir, "app.log"), WithSyslog(FacilityLocal0, "app"))
+	require.NoError(t, err, "New should succeed")
+	require.Equal(t, FacilityLocal0, l.Options().SyslogFacility, "SyslogFacility should match")
+	require.Equal(t, "app", l.Options().SyslogTag, "SyslogTag should match")
+	_, err = l.Write([]byte("hello\nworld\n"))
+	require.NoError(t, err, "Write should succeed")
+	require.NoError(t, l.Close(), "Close should succeed")
+	require.Equal(t, uint64(2), l.Metrics().Forwarded, "lines should be forwarded")
+
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "hello\nworld\n", string(b), "lines should be written to the file too")
+
+	suffix := fmt.Sprintf(" app[%d]: ", os.Getpid())
+	buf := make([]byte, 1024)
+	for _, line := range []string{"hello", "world"} {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)), "SetReadDeadline should succeed")
+		n, err := conn.Read(buf)
+		require.NoError(t, err, "Read should succeed")
+		msg := string(buf[:n])
+		require.True(t, strings.HasPrefix(msg, "<134>"), "priority should be of local0.info: %q", msg)
+		require.True(t, strings.HasSuffix(msg, suffix+line), "message should be tagged line: %q", msg)
+	}
+}
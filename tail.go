@@ -0,0 +1,226 @@
+package logrotate
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTailPollInterval is the default interval to poll for new data and
+// rotation by a Tailer.
+const defaultTailPollInterval = 100 * time.Millisecond
+
+// Tailer follows the current log file of a Logger across rotations, like
+// "tail -F", so sidecar shippers written in Go can consume what the Logger
+// produces with seamless rotation handover.
+//
+// It follows either the Symlink of a Logger (or any fixed filename), or the
+// filename pattern of a Logger, in which case the newest matched file is
+// followed. On rotation, the old file is read to the end before switching
+// to the new file.
+//
+// NOTE: the current file is polled, so files rotated out faster than the
+// poll interval may be skipped.
+type Tailer struct {
+	// PollInterval is the interval to poll for new data and rotation. It
+	// defaults to 100ms if <= 0. It must be set before the first Read.
+	PollInterval time.Duration
+	// SequenceSuffix is the format of the sequence suffix of the Logger,
+	// see WithSequenceSuffix, to order the files matched by the pattern. It
+	// defaults to ".%d" if empty. It must be set before the first Read.
+	SequenceSuffix string
+
+	path      string
+	isPattern bool
+	fromStart bool
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex // guards following
+	file *os.File   // file being read
+	info os.FileInfo
+	off  int64 // offset in file
+}
+
+// NewTailer creates a Tailer following path, which is the Symlink or the
+// filename pattern of a Logger. If fromStart is false, the current file is
+// read from its end, otherwise from its start. The files switched to on
+// rotation are always read from the start.
+func NewTailer(path string, fromStart bool) *Tailer {
+	return &Tailer{
+		path:      path,
+		isPattern: strings.ContainsAny(path, "%*"),
+		fromStart: fromStart,
+		quit:      make(chan struct{}),
+	}
+}
+
+// Read implements io.Reader. It blocks until there is new data, or the
+// Tailer is closed, in which case io.EOF is returned.
+func (t *Tailer) Read(p []byte) (int, error) {
+	for {
+		n, err := t.read(p)
+		if n > 0 || err != nil {
+			return n, err
+		}
+		select {
+		case <-t.quit:
+			return 0, io.EOF
+		case <-time.After(t.pollInterval()):
+		}
+	}
+}
+
+// Lines returns a channel of lines (without the trailing newline) read from
+// the Tailer. The channel is closed when the Tailer is closed or fails.
+func (t *Tailer) Lines() <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(t)
+		for scanner.Scan() {
+			select {
+			case ch <- scanner.Text():
+			case <-t.quit:
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Close stops following, and closes the file being read.
+func (t *Tailer) Close() error {
+	t.closeOnce.Do(func() { close(t.quit) })
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+func (t *Tailer) pollInterval() time.Duration {
+	if t.PollInterval <= 0 {
+		return defaultTailPollInterval
+	}
+	return t.PollInterval
+}
+
+// read reads once from the current file, and switches to the new file on
+// rotation. It returns 0 and nil if there is no new data yet.
+func (t *Tailer) read(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.quit:
+		return 0, io.EOF
+	default:
+	}
+
+	if t.file == nil {
+		if err := t.open(!t.fromStart); err != nil {
+			return 0, nil // wait for the file to be created
+		}
+	}
+	n, err := t.file.Read(p)
+	t.off += int64(n)
+	if n > 0 || (err != nil && !errors.Is(err, io.EOF)) {
+		return n, err
+	}
+
+	// at EOF, check whether the file was rotated or truncated.
+	path, info, err := t.current()
+	if err != nil {
+		return 0, nil
+	}
+	if !os.SameFile(info, t.info) {
+		// rotated: the old file has been read to the end, so switch to
+		// the new one and read it from the start.
+		t.file.Close()
+		t.file = nil
+		if err := t.openPath(path, false); err != nil {
+			return 0, nil
+		}
+		return 0, nil
+	}
+	if info.Size() < t.off {
+		// truncated, e.g.: by copytruncate
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		t.off = 0
+	}
+	return 0, nil
+}
+
+// open opens the current file, and seeks to its end if toEnd is true.
+func (t *Tailer) open(toEnd bool) error {
+	path, _, err := t.current()
+	if err != nil {
+		return err
+	}
+	return t.openPath(path, toEnd)
+}
+
+func (t *Tailer) openPath(path string, toEnd bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	var off int64
+	if toEnd {
+		if off, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	t.file, t.info, t.off = f, info, off
+	return nil
+}
+
+// current returns the path and info of the current file: the target of the
+// symlink, or the newest file matched the pattern.
+func (t *Tailer) current() (string, os.FileInfo, error) {
+	if !t.isPattern {
+		info, err := os.Stat(t.path)
+		return t.path, info, err
+	}
+	paths, err := filepath.Glob(parseGlobPattern(t.path))
+	if err != nil {
+		return "", nil, err
+	}
+	seqSuffix := t.SequenceSuffix
+	if seqSuffix == "" {
+		seqSuffix = defaultSequenceSuffix
+	}
+	names := newTimeParser(t.path, seqSuffix)
+	var files []FileInfo
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		f := FileInfo{Path: path, FileInfo: info}
+		names.stamp(&f)
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return "", nil, os.ErrNotExist
+	}
+	sort.Sort(byModTime{files, seqSuffix})
+	return files[0].Path, files[0].FileInfo, nil
+}
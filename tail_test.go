@@ -0,0 +1,88 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Tailer(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Tailer")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"symlink", filepath.Join(dir, "symlink", "current.log")},
+		{"pattern", filepath.Join(dir, "pattern", "app.log.%Y%m%d%H%M%S")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(
+				filepath.Join(dir, tt.name, "app.log.%Y%m%d%H%M%S"),
+				WithSymlink(filepath.Join(dir, tt.name, "current.log")),
+				WithMaxSize(8),
+			)
+			require.NoError(t, err, "New should succeed")
+			defer l.Close()
+
+			tailer := NewTailer(tt.path, true)
+			tailer.PollInterval = time.Millisecond
+			defer tailer.Close()
+
+			for _, line := range []string{"logfile1", "logfile2", "logfile3"} {
+				_, err = l.Write([]byte(line))
+				require.NoError(t, err, "Write should succeed")
+				require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+
+				buf := make([]byte, len(line))
+				_, err = io.ReadFull(tailer, buf)
+				require.NoError(t, err, "Read should succeed")
+				require.Equal(t, line, string(buf), "should read across rotations")
+			}
+
+			require.NoError(t, tailer.Close(), "Close should succeed")
+			_, err = tailer.Read(make([]byte, 1))
+			require.ErrorIs(t, err, io.EOF, "Read should return EOF after Close")
+		})
+	}
+}
+
+func Test_Tailer_Lines(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_Tailer_Lines")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+	require.NoError(t, os.WriteFile(filename, []byte("old\n"), 0644), "WriteFile should succeed")
+
+	tailer := NewTailer(filename, false)
+	tailer.PollInterval = time.Millisecond
+	defer tailer.Close()
+	lines := tailer.Lines()
+
+	// wait for the tailer to open the file at its end
+	require.Eventually(t, func() bool {
+		tailer.mu.Lock()
+		defer tailer.mu.Unlock()
+		return tailer.file != nil
+	}, time.Second, time.Millisecond, "file should be opened")
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err, "OpenFile should succeed")
+	_, err = f.WriteString("new1\nnew2\n")
+	require.NoError(t, err, "WriteString should succeed")
+	require.NoError(t, f.Close(), "Close should succeed")
+
+	require.Equal(t, "new1", <-lines, "should skip the existing content")
+	require.Equal(t, "new2", <-lines, "line should match")
+
+	require.NoError(t, tailer.Close(), "Close should succeed")
+	_, ok := <-lines
+	require.False(t, ok, "Lines should be closed after Close")
+}
@@ -0,0 +1,73 @@
+package logrotate
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Degraded reports whether the logger is degraded by a write which timed
+// out (see WithWriteTimeout) and is still pending. While degraded, writes
+// fail fast with ErrWriteTimeout.
+func (l *Logger) Degraded() bool {
+	return l.hung.Load() > 0
+}
+
+// supervise wraps the file f with a timeoutWriter if WriteTimeout is set.
+func (l *Logger) supervise(f io.WriteCloser) io.WriteCloser {
+	if l.opts.writeTimeout <= 0 {
+		return f
+	}
+	return &timeoutWriter{WriteCloser: f, timeout: l.opts.writeTimeout, hung: &l.hung}
+}
+
+// Write states of a timeoutWriter.
+const (
+	writePending int32 = iota
+	writeDone
+	writeTimedOut
+)
+
+// timeoutWriter writes to the underlying file in a supervised goroutine, and
+// fails the write with ErrWriteTimeout if it doesn't complete in time. The
+// timed out write is not canceled, so its bytes may still land in the file
+// after Write returns 0.
+type timeoutWriter struct {
+	io.WriteCloser
+	timeout time.Duration
+	hung    *atomic.Int64 // count of timed out writes still pending
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	type result struct {
+		n   int
+		err error
+	}
+	// b may be reused by the caller after a timeout, so copy it.
+	buf := append([]byte(nil), b...)
+	done := make(chan result, 1)
+	var state atomic.Int32
+	go func() {
+		n, err := w.WriteCloser.Write(buf)
+		done <- result{n, err}
+		if !state.CompareAndSwap(writePending, writeDone) {
+			// the caller gave up, so the logger recovers from degraded.
+			w.hung.Add(-1)
+		}
+	}()
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-timer.C:
+		if state.CompareAndSwap(writePending, writeTimedOut) {
+			w.hung.Add(1)
+			return 0, ErrWriteTimeout
+		}
+		// completed right at the deadline
+		r := <-done
+		return r.n, r.err
+	}
+}
@@ -0,0 +1,66 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hungFile is a fake file whose writes hang until unblock is closed.
+type hungFile struct {
+	unblock chan struct{}
+}
+
+func (f hungFile) Write(b []byte) (int, error) {
+	<-f.unblock
+	return len(b), nil
+}
+
+func (f hungFile) Close() error {
+	return nil
+}
+
+func Test_WriteTimeout(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_WriteTimeout")
+	defer os.RemoveAll(dir)
+
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithWriteTimeout(50*time.Millisecond),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	_, err = l.Write([]byte("1"))
+	require.NoError(t, err, "Write should succeed")
+	require.False(t, l.Degraded(), "logger should not be degraded")
+
+	// hook l.file
+	oldFile := l.file.Load()
+	f := hungFile{unblock: make(chan struct{})}
+	l.file.Store(newFileHandle(l.supervise(f), l.currentFilename(), l.currRotationTime, 1))
+
+	start := time.Now()
+	_, err = l.Write([]byte("2"))
+	require.ErrorIs(t, err, ErrWriteTimeout, "hung write should time out")
+	require.Less(t, time.Since(start), time.Second, "hung write should not block")
+	require.True(t, l.Degraded(), "logger should be degraded")
+
+	// fail fast while degraded
+	start = time.Now()
+	_, err = l.Write([]byte("3"))
+	require.ErrorIs(t, err, ErrWriteTimeout, "write should fail fast while degraded")
+	require.Less(t, time.Since(start), 50*time.Millisecond, "write should fail fast while degraded")
+
+	// the hung write completes
+	close(f.unblock)
+	require.Eventually(t, func() bool { return !l.Degraded() }, time.Second, 10*time.Millisecond, "logger should recover")
+	_, err = l.Write([]byte("4"))
+	require.NoError(t, err, "Write should succeed after recovered")
+
+	// restored
+	l.file.Store(oldFile)
+}
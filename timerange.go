@@ -0,0 +1,46 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renameTimeRange renames the closed rotated file h at path to include the
+// time range of its writes if WithTimeRangeName, e.g.:
+// "app.20240101T00-20240101T06.log". It returns the new path, or path if
+// nothing was written to h or the rename failed, which is reported to the
+// ErrorHandler.
+func (l *Logger) renameTimeRange(h *fileHandle, path string) string {
+	if l.opts.timeRange == nil {
+		return path
+	}
+	first, last := h.firstWrite.Load(), h.lastWrite.Load()
+	if first == 0 {
+		return path
+	}
+	loc := l.opts.clock.Now().Location()
+	timeRange := l.opts.timeRange.FormatString(time.Unix(0, first).In(loc)) +
+		"-" + l.opts.timeRange.FormatString(time.Unix(0, last).In(loc))
+	filename := l.freeFilename(timeRangeFilename(path, timeRange))
+	if err := os.Rename(path, filename); err != nil {
+		l.handleError(&RotationError{Op: "rename", Path: path, Err: err})
+		return path
+	}
+	return filename
+}
+
+// timeRangeFilename inserts timeRange before the extension of path, with
+// the sequence suffix if any removed, e.g.: "app.log.1" to
+// "app.<timeRange>.log".
+func timeRangeFilename(path, timeRange string) string {
+	if ext := filepath.Ext(path); len(ext) > 1 {
+		if _, err := strconv.Atoi(ext[1:]); err == nil {
+			path = strings.TrimSuffix(path, ext)
+		}
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + timeRange + ext
+}
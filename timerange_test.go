@@ -0,0 +1,57 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TimeRangeName(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_TimeRangeName")
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l, err := New(
+		filepath.Join(dir, "app.log"),
+		WithClock(clock),
+		WithMaxInterval(0),
+		WithTimeRangeName("%Y%m%dT%H"),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.Equal(t, "%Y%m%dT%H", l.Options().TimeRangeName, "TimeRangeName should match")
+
+	rotate := func(lines ...string) string {
+		for _, line := range lines {
+			_, err := l.Write([]byte(line))
+			require.NoError(t, err, "Write should succeed")
+			clock.Advance(6 * time.Hour)
+		}
+		path, err := l.RotateAndGet()
+		require.NoError(t, err, "RotateAndGet should succeed")
+		return path
+	}
+
+	path := rotate("1", "2")
+	require.Equal(t, filepath.Join(dir, "app.20240101T00-20240101T06.log"), path, "rotated file should be renamed with time range")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "ReadFile should succeed")
+	require.Equal(t, "12", string(data), "content should match")
+
+	path = rotate("3")
+	require.Equal(t, filepath.Join(dir, "app.20240101T12-20240101T12.log"), path, "sequence suffix should be removed")
+
+	path = rotate()
+	require.Equal(t, filepath.Join(dir, "app.log.2"), path, "file with no writes should not be renamed")
+}
+
+func Test_timeRangeFilename(t *testing.T) {
+	require.Equal(t, "app.R.log", timeRangeFilename("app.log", "R"))
+	require.Equal(t, "app.R.log", timeRangeFilename("app.log.12", "R"))
+	require.Equal(t, "dir.d/app.R", timeRangeFilename("dir.d/app", "R"))
+	require.Equal(t, "app.20240101.R.log", timeRangeFilename("app.20240101.log", "R"))
+}
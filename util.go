@@ -1,15 +1,21 @@
 package logrotate
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/lestrrat-go/strftime"
@@ -32,6 +38,38 @@ func (systemClock) Now() time.Time {
 	return time.Now()
 }
 
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// utcClock is a Clock which returns the time of clock in UTC, for WithUTC.
+// Timers are waited on clock if it implements TimerClock.
+type utcClock struct {
+	clock Clock
+}
+
+func (c utcClock) Now() time.Time {
+	return c.clock.Now().UTC()
+}
+
+func (c utcClock) After(d time.Duration) <-chan time.Time {
+	if tc, ok := c.clock.(TimerClock); ok {
+		return tc.After(d)
+	}
+	return time.After(d)
+}
+
+// TimerClock is a Clock with timer support. If the Clock of a Logger
+// implements it, scheduled rotation waits on it, so the whole rotation
+// schedule can be driven by a fake clock (e.g.: clockwork.FakeClock) in
+// tests without sleeps.
+type TimerClock interface {
+	Clock
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
 // genBaseFilename2 creates a file name based on pattern, clock, and interval.
 //
 // The base time used to generate the filename is truncated based on interval.
@@ -59,22 +97,60 @@ func genBaseFilename2(pattern *strftime.Strftime, clock Clock, interval time.Dur
 	return pattern.FormatString(base)
 }
 
+// genBaseFilename creates a file name based on pattern and rotationTime in
+// nanoseconds since the Unix epoch in the Location of clock.
 func genBaseFilename(pattern *strftime.Strftime, clock Clock, rotationTime int64) string {
 	now := clock.Now()
 	_, offset := now.Zone()
-	t := time.Unix(rotationTime-int64(offset), 0)
+	t := time.Unix(0, rotationTime-int64(offset)*int64(time.Second))
 	base := t.In(now.Location())
 	return pattern.FormatString(base)
 }
 
-// evalCurrRotationTime evaluates the current rotation time in seconds
+// evalCurrRotationTime evaluates the current rotation time in nanoseconds
 // at interval scale since the Unix epoch in Location (timezone offset).
 func evalCurrRotationTime(clock Clock, tzOffset, interval int64) int64 {
-	now := clock.Now().Unix() + tzOffset
-	return now - (now % interval)
+	return evalRotationTime(clock.Now().UnixNano(), tzOffset, interval)
+}
+
+// evalRotationTime evaluates the rotation time in nanoseconds at interval
+// scale which the Unix nanoseconds ts falls in, since the Unix epoch in
+// Location (timezone offset). The time is floored, even if it's before the
+// epoch. If interval <= 0, the time is not truncated.
+func evalRotationTime(ts, tzOffset, interval int64) int64 {
+	ts += tzOffset
+	if interval <= 0 {
+		return ts
+	}
+	rem := ts % interval
+	if rem < 0 {
+		rem += interval
+	}
+	return ts - rem
+}
+
+// nanoseconds appends the zero-padded, 9-digit nanoseconds of the time.
+var nanoseconds = strftime.AppendFunc(func(b []byte, t time.Time) []byte {
+	ns := strconv.Itoa(t.Nanosecond())
+	for i := len(ns); i < 9; i++ {
+		b = append(b, '0')
+	}
+	return append(b, ns...)
+})
+
+// newStrftime compiles the strftime pattern, with the sub-second
+// directives supported in addition to the standard ones: %L (3-digit
+// milliseconds), %f (6-digit microseconds) and %N (9-digit nanoseconds).
+func newStrftime(pattern string) (*strftime.Strftime, error) {
+	return strftime.New(pattern,
+		strftime.WithMilliseconds('L'),
+		strftime.WithMicroseconds('f'),
+		strftime.WithSpecification('N', nanoseconds),
+	)
 }
 
 var patternConversionRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`%\{[^}]*\}`),  // template function
 	regexp.MustCompile(`%[%+A-Za-z]`), // strftime format pattern
 	regexp.MustCompile(`\*+`),         // one or multiple *
 }
@@ -90,6 +166,11 @@ func parseGlobPattern(pattern string) string {
 	return globPattern + suffixGlob
 }
 
+// isReadOnly reports whether err is caused by the read-only filesystem.
+func isReadOnly(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
+
 // tracef formats according to a format specifier and writes to w
 // with trace info and a newline appended.
 func tracef(w io.Writer, format string, args ...any) (int, error) {
@@ -107,44 +188,287 @@ func tracef(w io.Writer, format string, args ...any) (int, error) {
 	return fmt.Fprintf(w, "%s:%d %s "+format+"\n", args...)
 }
 
-type logfile struct {
-	path string
+// fileHandle is a file being written to, which is shared by concurrent
+// writers on the write fast path.
+type fileHandle struct {
+	io.WriteCloser
+	name         string // filename
+	rotationTime int64  // rotation time when the file was opened
+	rotated      bool   // set with l.mu held if rotated out by a new file
+	takenOver    bool   // set with l.mu held if taken over by RotateAndGet
+	nextName     string // set with l.mu held to the filename rotated to
+	archivedName string // set with l.mu held to the filename renamed to if WithActiveFile
+	finalName    string // filename after the RotateHook, set on close
+	finishing    bool   // set with l.mu held if done by rotation, so finalized on close
+
+	reason RotateReason // set with l.mu held to the reason of rotation
+
+	size     atomic.Int64 // write size of file
+	inflight atomic.Int64 // count of in-flight writes
+	retired  atomic.Bool  // set when the file is going to be closed
+
+	drainMu sync.Mutex // guards waiting for in-flight writes on retire
+	drained sync.Cond  // signaled when the last in-flight write of a retired h is done
+
+	lines        atomic.Int64 // line count of file if MaxLines is set
+	reconciledAt atomic.Int64 // time of the last size reconciliation in Unix nanoseconds
+	entries      atomic.Int64 // count of writes
+	firstWrite   atomic.Int64 // time of the first write in Unix nanoseconds
+	lastWrite    atomic.Int64 // time of the last write in Unix nanoseconds
+}
+
+func newFileHandle(f io.WriteCloser, name string, rotationTime, size int64) *fileHandle {
+	h := &fileHandle{
+		WriteCloser:  f,
+		name:         name,
+		rotationTime: rotationTime,
+	}
+	h.drained.L = &h.drainMu
+	h.size.Store(size)
+	return h
+}
+
+// stats returns the FileStats of h.
+func (h *fileHandle) stats() FileStats {
+	return FileStats{
+		Path:       h.name,
+		Size:       h.size.Load(),
+		Lines:      h.lines.Load(),
+		Entries:    h.entries.Load(),
+		FirstWrite: unixNanoTime(h.firstWrite.Load()),
+		LastWrite:  unixNanoTime(h.lastWrite.Load()),
+	}
+}
+
+// unixNanoTime returns the local Time of the Unix nanoseconds ns, or the
+// zero Time if ns is 0.
+func unixNanoTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// recordWrite records a write to h at now in Unix nanoseconds.
+func (h *fileHandle) recordWrite(now int64) {
+	h.entries.Add(1)
+	h.firstWrite.CompareAndSwap(0, now)
+	for {
+		last := h.lastWrite.Load()
+		if last >= now || h.lastWrite.CompareAndSwap(last, now) {
+			return
+		}
+	}
+}
+
+// acquire acquires h for writing, and returns false if h was retired.
+func (h *fileHandle) acquire() bool {
+	h.inflight.Add(1)
+	if h.retired.Load() {
+		h.release()
+		return false
+	}
+	return true
+}
+
+// release releases h acquired for writing, and wakes up retire if it's the
+// last in-flight write.
+func (h *fileHandle) release() {
+	if h.inflight.Add(-1) == 0 && h.retired.Load() {
+		h.drainMu.Lock()
+		h.drained.Broadcast()
+		h.drainMu.Unlock()
+	}
+}
+
+// retire retires h, and waits until all in-flight writes are done.
+func (h *fileHandle) retire() {
+	h.retired.Store(true)
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	for h.inflight.Load() > 0 {
+		h.drained.Wait()
+	}
+}
+
+// Close retires h, and then closes the file.
+func (h *fileHandle) Close() error {
+	h.retire()
+	return h.WriteCloser.Close()
+}
+
+// flush writes out the writes buffered by WithAlignedWrites, if any, so
+// the file can be accessed directly.
+func (h *fileHandle) flush() error {
+	if aw, ok := h.WriteCloser.(*alignedWriter); ok {
+		return aw.flush()
+	}
+	return nil
+}
+
+// osFile returns the underlying *os.File of h, if any.
+func (h *fileHandle) osFile() (*os.File, bool) {
+	w := h.WriteCloser
+	if aw, ok := w.(*alignedWriter); ok {
+		w = aw.WriteCloser
+	}
+	if tw, ok := w.(*timeoutWriter); ok {
+		w = tw.WriteCloser
+	}
+	f, ok := w.(*os.File)
+	return f, ok
+}
+
+// maxPooledBufferSize is the max capacity of buffers put back to bufferPool,
+// so that a few large entries won't pin a lot of memory.
+const maxPooledBufferSize = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+// getBuffer returns an empty buffer from bufferPool with at least size
+// capacity.
+func getBuffer(size int) *[]byte {
+	buf := bufferPool.Get().(*[]byte)
+	if cap(*buf) < size {
+		*buf = make([]byte, 0, size)
+	}
+	return buf
+}
+
+// putBuffer puts buf back to bufferPool.
+func putBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBufferSize {
+		return
+	}
+	*buf = (*buf)[:0]
+	bufferPool.Put(buf)
+}
+
+// FileStats describes a log file written by the Logger, passed to the
+// RotatePredicate, returned by CurrentStats, and sent in RotationEvents, so
+// that downstream systems can index files by time range and volume without
+// parsing them. Only the writes since the file was opened by the Logger are
+// taken into account, except Size.
+type FileStats struct {
+	Path       string    // path of the file
+	Size       int64     // write size of the file, including in-flight writes
+	Lines      int64     // line count of the file, only counted if MaxLines is set
+	Entries    int64     // count of writes to the file
+	FirstWrite time.Time // time of the first write, zero if no writes
+	LastWrite  time.Time // time of the last write, zero if no writes
+}
+
+// FileInfo describes a log file matched by the filename pattern.
+type FileInfo struct {
+	Path string // path of the log file
 	os.FileInfo
+
+	birthTime time.Time // zero if not used or not available
+	nameTime  time.Time // parsed from the filename, zero if not matched
+	nameSeq   int       // parsed from the filename along with nameTime
 }
 
-// byModTime sorts files by modification time in descending order.
-type byModTime []*logfile
+// Time returns the time used for retention of the log file, which is the
+// birth time if BirthTime is enabled and available, otherwise the
+// modification time.
+func (f FileInfo) Time() time.Time {
+	if !f.birthTime.IsZero() {
+		return f.birthTime
+	}
+	return f.ModTime()
+}
+
+// sortTime returns the time which the log file is ordered by: the time
+// parsed from the filename if matched, otherwise Time.
+func (f FileInfo) sortTime() time.Time {
+	if !f.nameTime.IsZero() {
+		return f.nameTime
+	}
+	return f.Time()
+}
+
+// sequence returns the sequence suffix in seqSuffix format of the log file,
+// parsed along with the time from the filename if matched, as the pattern
+// may end with digits which would be taken for a sequence alone.
+func (f FileInfo) sequence(seqSuffix string) int {
+	if !f.nameTime.IsZero() {
+		return f.nameSeq
+	}
+	_, seq := trimSequence(f.Path, seqSuffix)
+	return seq
+}
+
+// byModTime sorts files in descending order by the time parsed from their
+// filenames, falling back to Time (modification time by default) for the
+// files not matched, and then by the sequence suffix in seqSuffix format, and
+// then by Time. The filename order is not affected by clock changes or
+// touches, which would reorder the files by Time.
+type byModTime struct {
+	files     []FileInfo
+	seqSuffix string
+}
 
 func (b byModTime) Less(i, j int) bool {
-	parseSuffixSeq := func(path string) int {
-		suffixSeqStr := strings.TrimPrefix(filepath.Ext(path), ".")
-		seq, _ := strconv.Atoi(suffixSeqStr)
-		return seq
+	fi, fj := b.files[i], b.files[j]
+	if ti, tj := fi.sortTime(), fj.sortTime(); !ti.Equal(tj) {
+		return ti.After(tj)
 	}
-	if b[i].ModTime() == b[j].ModTime() {
-		// For most file systems, sub-second information is not available. So we
-		// need to compare the suffix sequence.
-		// e.g.: ext3 only supports second level precision.
-		return parseSuffixSeq(b[i].path) > parseSuffixSeq(b[j].path)
+	// For most file systems, sub-second information is not available. So we
+	// need to compare the suffix sequence.
+	// e.g.: ext3 only supports second level precision.
+	if seqi, seqj := fi.sequence(b.seqSuffix), fj.sequence(b.seqSuffix); seqi != seqj {
+		return seqi > seqj
 	}
-	return b[i].ModTime().After(b[j].ModTime())
+	return fi.Time().After(fj.Time())
 }
 
 func (b byModTime) Swap(i, j int) {
-	b[i], b[j] = b[j], b[i]
+	b.files[i], b.files[j] = b.files[j], b.files[i]
 }
 
 func (b byModTime) Len() int {
-	return len(b)
+	return len(b.files)
+}
+
+// createRetries is the max retries to create directories, files and
+// symlinks, which may race with concurrent creators, e.g.: many Loggers
+// starting at once in one directory (k8s init storms).
+const createRetries = 3
+
+// retryJitter sleeps a random duration up to 5ms << attempt before the retry
+// attempt (from 1), so that concurrent creators are spread out.
+func retryJitter(attempt int) {
+	time.Sleep(time.Duration(rand.Int63n(int64(5*time.Millisecond) << attempt)))
 }
 
-// link creates a symbolic link to the provided filename.
+// linkSeq makes the temporary symlink names unique in the process.
+var linkSeq atomic.Uint64
+
+// link creates a symbolic link to the provided filename, and retries with
+// jitter on failure, as concurrent Loggers may race on it.
+func link(filename string, symlink string) error {
+	err := linkOnce(filename, symlink)
+	for attempt := 1; err != nil && attempt <= createRetries; attempt++ {
+		retryJitter(attempt)
+		err = linkOnce(filename, symlink)
+	}
+	return err
+}
+
+// linkOnce creates a symbolic link to the provided filename.
 //
 // How the symlink name is generated based on where the target location is.
 // If the location is directly underneath the filename's parent directory,
 // then we create a symlink with a relative path.
-func link(filename string, symlink string) error {
-	tmpLinkName := filename + ".symlink#"
+func linkOnce(filename string, symlink string) error {
+	// unique per process and call, so concurrent creators never collide,
+	// and a stale one left by a crash never blocks.
+	tmpLinkName := fmt.Sprintf("%s.symlink#%d.%d", filename, os.Getpid(), linkSeq.Add(1))
 	linkDest := filename
 	linkDir := filepath.Dir(symlink)
 
@@ -161,30 +485,283 @@ func link(filename string, symlink string) error {
 		return fmt.Errorf("failed to create new symlink: %v", err)
 	}
 
-	// the directory where symlink should be created must exist
+	// the directory where symlink should be created must exist. MkdirAll
+	// tolerates the directory created by others in the meantime.
 	_, err := os.Stat(linkDir)
 	if err != nil { // Assume err != nil means the directory doesn't exist
 		if err := os.MkdirAll(linkDir, 0755); err != nil {
+			os.Remove(tmpLinkName)
 			return fmt.Errorf("failed to create directory %s: %v", linkDir, err)
 		}
 	}
 
 	if err := os.Rename(tmpLinkName, symlink); err != nil {
+		os.Remove(tmpLinkName)
 		return fmt.Errorf("failed to rename new symlink %s -> %s: %v", tmpLinkName, symlink, err)
 	}
 	return nil
 }
 
 type atomicMetrics struct {
-	Discards atomic.Uint64
+	Discards          atomic.Uint64
+	DiscardedBytes    atomic.Uint64
+	DiscardsQueueFull atomic.Uint64
+	DiscardsClosed    atomic.Uint64
+	DiscardsCanceled  atomic.Uint64
+
+	Processed      atomic.Uint64
+	ProcessErrors  atomic.Uint64
+	ProcessRetries atomic.Uint64
+	Spills         atomic.Uint64
+	Redactions     atomic.Uint64
+
+	ClockRegressions atomic.Uint64
+	DeferredDeletes  atomic.Uint64
+	MirrorErrors     atomic.Uint64
+	Forwarded        atomic.Uint64
+	ForwardErrors    atomic.Uint64
+
+	rotations [rotateReasons]atomic.Uint64 // rotations by reason
+
+	oldestQueued atomic.Int64 // enqueue time of the oldest queued write
+}
+
+// countRotation counts a rotation for reason.
+func (a *atomicMetrics) countRotation(reason RotateReason) {
+	if reason > 0 && reason < rotateReasons {
+		a.rotations[reason].Add(1)
+	}
+}
+
+// discard counts a discarded log line of n bytes, and its cause.
+func (a *atomicMetrics) discard(cause *atomic.Uint64, n int) {
+	a.Discards.Add(1)
+	a.DiscardedBytes.Add(uint64(n))
+	cause.Add(1)
+}
+
+// oldestQueuedAge returns the age of the oldest queued entry at now, or 0
+// if the queue is empty.
+func (a *atomicMetrics) oldestQueuedAge(now time.Time) time.Duration {
+	if t := a.oldestQueued.Load(); t > 0 && now.UnixNano() > t {
+		return now.Sub(time.Unix(0, t))
+	}
+	return 0
 }
 
-func (a *atomicMetrics) toMetrics() Metrics {
+func (a *atomicMetrics) toMetrics(now time.Time) Metrics {
+	discards := a.Discards.Load()
 	return Metrics{
-		Discards: a.Discards.Load(),
+		Time:           now,
+		Discards:       discards,
+		Processed:      a.Processed.Load(),
+		ProcessErrors:  a.ProcessErrors.Load(),
+		ProcessRetries: a.ProcessRetries.Load(),
+		Spills:         a.Spills.Load(),
+		Redactions:     a.Redactions.Load(),
+
+		ClockRegressions: a.ClockRegressions.Load(),
+		DeferredDeletes:  a.DeferredDeletes.Load(),
+		MirrorErrors:     a.MirrorErrors.Load(),
+		Forwarded:        a.Forwarded.Load(),
+		ForwardErrors:    a.ForwardErrors.Load(),
+
+		RotationsBySize:      a.rotations[RotateBySize].Load(),
+		RotationsByLines:     a.rotations[RotateByLines].Load(),
+		RotationsByInterval:  a.rotations[RotateByInterval].Load(),
+		RotationsByPredicate: a.rotations[RotateByPredicate].Load(),
+		RotationsByRequest:   a.rotations[RotateByRequest].Load(),
+		RotationsByRecovery:  a.rotations[RotateByRecovery].Load(),
+
+		DiscardedEntries:   discards,
+		DiscardedBytes:     a.DiscardedBytes.Load(),
+		DiscardedQueueFull: a.DiscardsQueueFull.Load(),
+		DiscardedClosed:    a.DiscardsClosed.Load(),
+		DiscardedCanceled:  a.DiscardsCanceled.Load(),
+
+		OldestQueuedAge: a.oldestQueuedAge(now),
 	}
 }
 
+// Metrics is a snapshot of the Logger's counters. It's a plain value, so
+// taking a snapshot performs no allocations.
 type Metrics struct {
-	Discards uint64 // discarded log lines
+	Time           time.Time // when the snapshot was taken
+	Discards       uint64    // discarded log lines, same as DiscardedEntries
+	Processed      uint64    // rotated files processed by post-rotation processors
+	ProcessErrors  uint64    // rotated files failed to be processed
+	ProcessRetries uint64    // retries of failed post-rotation processors
+	Spills         uint64    // log lines spilled to the spill file on overflow
+	Redactions     uint64    // matches redacted by redactors
+
+	ClockRegressions uint64 // clock jumps backwards before the current rotation time
+	DeferredDeletes  uint64 // deletions deferred by VerifyBeforeDelete, MinAgeBeforeDelete or OpenFileCheck
+	MirrorErrors     uint64 // writes failed to be duplicated to the Mirror
+	Forwarded        uint64 // lines forwarded to syslog if WithSyslog
+	ForwardErrors    uint64 // lines failed to be forwarded, or dropped, if WithSyslog
+
+	RotationsBySize      uint64 // rotations as MaxSize was reached
+	RotationsByLines     uint64 // rotations as MaxLines was reached
+	RotationsByInterval  uint64 // rotations at MaxInterval boundaries
+	RotationsByPredicate uint64 // rotations as RotatePredicate returned true
+	RotationsByRequest   uint64 // rotations requested by Rotate
+	RotationsByRecovery  uint64 // rotations of existing files unable to resume
+
+	DiscardedEntries   uint64 // discarded log lines of all causes
+	DiscardedBytes     uint64 // bytes of discarded log lines
+	DiscardedQueueFull uint64 // log lines discarded as the queue was full
+	DiscardedClosed    uint64 // log lines written after or dropped on Close
+	DiscardedCanceled  uint64 // log lines given up by WriteContext as ctx was done
+
+	// OldestQueuedAge is the age of the oldest entry queued in buffered
+	// mode but not yet written, or 0 if the queue is empty. It's a gauge,
+	// which grows when the writeLoop is falling behind, e.g.: slow disk.
+	OldestQueuedAge time.Duration
+	// QueuedBytes is the total length of entries in the queue in buffered
+	// mode. It's a gauge too.
+	QueuedBytes int64
+	// QueueBound is the effective bound of the default queue in buffered
+	// mode, tuned if WithAutoTune. It's a gauge too.
+	QueueBound int
+
+	// CommittedBytes is the durability watermark if WithGroupCommit: the
+	// count of bytes written and fsynced by group commits so far.
+	CommittedBytes uint64
+	GroupCommits   uint64 // group commits run if WithGroupCommit
+}
+
+// Delta returns the increments of counters since the prev snapshot, so
+// monitoring agents polling Metrics periodically can get per-interval
+// values and rates. Gauges, e.g.: OldestQueuedAge, are kept as is.
+func (m Metrics) Delta(prev Metrics) MetricsDelta {
+	return MetricsDelta{
+		Metrics: Metrics{
+			Time:           m.Time,
+			Discards:       m.Discards - prev.Discards,
+			Processed:      m.Processed - prev.Processed,
+			ProcessErrors:  m.ProcessErrors - prev.ProcessErrors,
+			ProcessRetries: m.ProcessRetries - prev.ProcessRetries,
+			Spills:         m.Spills - prev.Spills,
+			Redactions:     m.Redactions - prev.Redactions,
+
+			ClockRegressions: m.ClockRegressions - prev.ClockRegressions,
+			DeferredDeletes:  m.DeferredDeletes - prev.DeferredDeletes,
+			MirrorErrors:     m.MirrorErrors - prev.MirrorErrors,
+			Forwarded:        m.Forwarded - prev.Forwarded,
+			ForwardErrors:    m.ForwardErrors - prev.ForwardErrors,
+
+			RotationsBySize:      m.RotationsBySize - prev.RotationsBySize,
+			RotationsByLines:     m.RotationsByLines - prev.RotationsByLines,
+			RotationsByInterval:  m.RotationsByInterval - prev.RotationsByInterval,
+			RotationsByPredicate: m.RotationsByPredicate - prev.RotationsByPredicate,
+			RotationsByRequest:   m.RotationsByRequest - prev.RotationsByRequest,
+			RotationsByRecovery:  m.RotationsByRecovery - prev.RotationsByRecovery,
+
+			DiscardedEntries:   m.DiscardedEntries - prev.DiscardedEntries,
+			DiscardedBytes:     m.DiscardedBytes - prev.DiscardedBytes,
+			DiscardedQueueFull: m.DiscardedQueueFull - prev.DiscardedQueueFull,
+			DiscardedClosed:    m.DiscardedClosed - prev.DiscardedClosed,
+			DiscardedCanceled:  m.DiscardedCanceled - prev.DiscardedCanceled,
+
+			OldestQueuedAge: m.OldestQueuedAge,
+			QueuedBytes:     m.QueuedBytes,
+			QueueBound:      m.QueueBound,
+
+			CommittedBytes: m.CommittedBytes - prev.CommittedBytes,
+			GroupCommits:   m.GroupCommits - prev.GroupCommits,
+		},
+		Elapsed: m.Time.Sub(prev.Time),
+	}
+}
+
+// MetricsDelta is the increments of counters between two Metrics snapshots.
+type MetricsDelta struct {
+	Metrics               // increments of counters
+	Elapsed time.Duration // elapsed time between the two snapshots
+}
+
+// Rate returns the per-second rate of counter n (one of the increments)
+// during the elapsed time, e.g.: d.Rate(d.Discards).
+func (d MetricsDelta) Rate(n uint64) float64 {
+	if d.Elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / d.Elapsed.Seconds()
+}
+
+// countLines counts lines in b, by counting '\n'.
+func countLines(b []byte) int64 {
+	return int64(bytes.Count(b, []byte{'\n'}))
+}
+
+// countFileLines counts lines in the file at path.
+func countFileLines(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += countLines(buf[:n])
+		if err == io.EOF {
+			return lines, nil
+		} else if err != nil {
+			return lines, err
+		}
+	}
+}
+
+// sizeUnits are the supported size units, longest suffixes first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"GB", 1000 * 1000 * 1000},
+	{"MB", 1000 * 1000},
+	{"KB", 1000},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes with an optional unit, e.g.: "100MiB",
+// "10MB", "512K" or "1024". The K, M and G suffixes are binary units, the
+// same as KiB, MiB and GiB.
+func ParseSize(s string) (int64, error) {
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSuffix(s, u.suffix), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative size %d", n)
+	}
+	if n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("size %d * %d overflows int64", n, unit)
+	}
+	return n * unit, nil
+}
+
+// containsString reports whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
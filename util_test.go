@@ -3,11 +3,15 @@ package logrotate
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/jonboulle/clockwork"
 	"github.com/lestrrat-go/strftime"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_tracef(t *testing.T) {
@@ -18,8 +22,7 @@ func Test_tracef(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    int
-		wantW   string
+		wantW   string // formatted with the line of the tracef call
 		wantErr bool
 	}{
 		{
@@ -28,24 +31,25 @@ func Test_tracef(t *testing.T) {
 				format: "test %d %s",
 				args:   []any{1, "hello"},
 			},
-			want:    57,
-			wantW:   "util_test.go:39 logrotate.Test_tracef.func1 test 1 hello\n",
+			wantW:   "util_test.go:%d logrotate.Test_tracef.func1 test 1 hello\n",
 			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := &bytes.Buffer{}
+			_, _, line, _ := runtime.Caller(0)
 			got, err := tracef(w, tt.args.format, tt.args.args...)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("tracef() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("tracef() = %v, want %v", got, tt.want)
+			wantW := fmt.Sprintf(tt.wantW, line+1)
+			if got != len(wantW) {
+				t.Errorf("tracef() = %v, want %v", got, len(wantW))
 			}
-			if gotW := w.String(); gotW != tt.wantW {
-				t.Errorf("tracef() = %v, want %v", gotW, tt.wantW)
+			if gotW := w.String(); gotW != wantW {
+				t.Errorf("tracef() = %v, want %v", gotW, wantW)
 			}
 		})
 	}
@@ -61,7 +65,7 @@ func Test_genBaseFilename(t *testing.T) {
 	ts := []time.Time{
 		time.Now(),
 		time.Now().Add(time.Hour + time.Minute + time.Second),
-		(time.Time{}).Add(24 * time.Hour),
+		time.Unix(0, 0).UTC().Add(24 * time.Hour),
 	}
 	genExpectedName := func(t time.Time) string {
 		return fmt.Sprintf("/path/to/%04d/%02d/%02d/%02d/%02d/%02d",
@@ -75,10 +79,10 @@ func Test_genBaseFilename(t *testing.T) {
 	}
 	genIntervalTime := func(clock clockwork.FakeClock) int64 {
 		_, offset := clock.Now().Zone()
-		now := clock.Now().Unix() + int64(offset)
+		now := clock.Now().UnixNano() + int64(offset)*int64(time.Second)
 		// tracef(os.Stderr, "now: %v", now)
 		interval := time.Second
-		t := now - (now % int64(interval.Seconds()))
+		t := now - (now % int64(interval))
 		// tracef(os.Stderr, "genIntervalTime: %v", t)
 		return t
 	}
@@ -259,3 +263,113 @@ func Test_parseGlobPattern(t *testing.T) {
 		})
 	}
 }
+
+func Test_Metrics_Delta(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	l, err := New(filepath.Join(t.TempDir(), "app.log"), WithClock(clock))
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	prev := l.Metrics()
+	l.metrics.Discards.Add(10)
+	clock.Advance(2 * time.Second)
+	curr := l.Metrics()
+
+	delta := curr.Delta(prev)
+	require.Equal(t, uint64(10), delta.Discards, "discards delta should match")
+	require.Equal(t, 2*time.Second, delta.Elapsed, "elapsed should match")
+	require.Equal(t, 5.0, delta.Rate(delta.Discards), "discards rate should match")
+	require.Equal(t, 0.0, MetricsDelta{}.Rate(1), "rate should be 0 without elapsed time")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		curr = l.Metrics()
+		delta = curr.Delta(prev)
+	})
+	require.Equal(t, 0.0, allocs, "metrics snapshot should not allocate")
+}
+
+func Test_ParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"100MiB", 100 << 20},
+		{"10MB", 10 * 1000 * 1000},
+		{"512K", 512 << 10},
+		{"1G", 1 << 30},
+		{"0B", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) error: %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "MiB", "-1", "10MiBB", "9223372036854775807K"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) should fail", in)
+		}
+	}
+}
+
+func Test_evalRotationTime(t *testing.T) {
+	tests := []struct {
+		ts, tzOffset, interval int64
+		want                   int64
+	}{
+		{1500, 0, 1000, 1000},
+		{1500, 100, 1000, 1000},
+		{1500, 600, 1000, 2000},
+		{-1500, 0, 1000, -2000},
+		{1500, 0, 0, 1500},
+		{1500, 0, -1000, 1500},
+	}
+	for _, tt := range tests {
+		if got := evalRotationTime(tt.ts, tt.tzOffset, tt.interval); got != tt.want {
+			t.Errorf("evalRotationTime(%d, %d, %d) = %d, want %d", tt.ts, tt.tzOffset, tt.interval, got, tt.want)
+		}
+	}
+}
+
+func Test_newStrftime_SubSecond(t *testing.T) {
+	pattern, err := newStrftime("app.%H%M%S.%L.%f.%N.log")
+	if err != nil {
+		t.Fatalf("newStrftime failed: %v", err)
+	}
+	ts := time.Date(2024, 1, 1, 12, 30, 45, 1234567, time.UTC)
+	want := "app.123045.001.001234.001234567.log"
+	if got := pattern.FormatString(ts); got != want {
+		t.Errorf("FormatString() = %v, want %v", got, want)
+	}
+}
+
+func Test_fileHandle_retire(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "app.log"))
+	require.NoError(t, err, "Create should succeed")
+	defer f.Close()
+	h := newFileHandle(f, f.Name(), 0, 0)
+	require.True(t, h.acquire(), "acquire should succeed")
+
+	retired := make(chan struct{})
+	go func() {
+		h.retire()
+		close(retired)
+	}()
+	require.Eventually(t, h.retired.Load, time.Second, time.Millisecond, "h should be retired")
+	require.False(t, h.acquire(), "acquire should fail after retire")
+	select {
+	case <-retired:
+		t.Fatal("retire should wait for the in-flight write")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	h.release()
+	select {
+	case <-retired:
+	case <-time.After(time.Second):
+		t.Fatal("retire should return once the in-flight write is done")
+	}
+}
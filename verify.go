@@ -0,0 +1,69 @@
+package logrotate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// RemoteObject is the remote object archived for a local log file.
+type RemoteObject struct {
+	Size   int64  // size of the content in bytes
+	SHA256 string // hex-encoded SHA-256 of the content, or "" if unknown
+}
+
+// RemoteStore is the remote archive of the log files, e.g.: an S3-compatible
+// object store which an archiver uploads the rotated files to.
+type RemoteStore interface {
+	// StatObject returns the remote object archived for the local log file
+	// at path, or an error wrapping fs.ErrNotExist if it's not archived.
+	StatObject(ctx context.Context, path string) (RemoteObject, error)
+}
+
+// verifyArchived returns nil if the log file f is archived to store set by
+// VerifyBeforeDelete, or store is nil, i.e.: the remote object exists and
+// matches its size, and also its checksum if the remote one is known.
+// Otherwise, it returns an error wrapping ErrNotArchived, and the deletion
+// of f should be deferred.
+func verifyArchived(ctx context.Context, store RemoteStore, f FileInfo) error {
+	if store == nil {
+		return nil
+	}
+	return verifyRemoteObject(ctx, store, f)
+}
+
+// verifyRemoteObject compares the local log file f with its remote object
+// in store.
+func verifyRemoteObject(ctx context.Context, store RemoteStore, f FileInfo) error {
+	obj, err := store.StatObject(ctx, f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrNotArchived, err)
+	} else if err != nil {
+		return fmt.Errorf("%w: stat remote object: %w", ErrNotArchived, err)
+	}
+	if obj.Size != f.Size() {
+		return fmt.Errorf("%w: remote size %d, local size %d", ErrNotArchived, obj.Size, f.Size())
+	}
+	if obj.SHA256 == "" {
+		return nil
+	}
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("%w: open logfile: %w", ErrNotArchived, err)
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, ThrottleReader(ctx, file)); err != nil {
+		return fmt.Errorf("%w: hash logfile: %w", ErrNotArchived, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, obj.SHA256) {
+		return fmt.Errorf("%w: remote checksum %s, local checksum %s", ErrNotArchived, obj.SHA256, sum)
+	}
+	return nil
+}
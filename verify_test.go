@@ -0,0 +1,125 @@
+package logrotate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+type mapRemoteStore map[string]RemoteObject
+
+func (s mapRemoteStore) StatObject(ctx context.Context, path string) (RemoteObject, error) {
+	obj, ok := s[path]
+	if !ok {
+		return RemoteObject{}, fmt.Errorf("stat %s: %w", path, fs.ErrNotExist)
+	}
+	return obj, nil
+}
+
+func Test_VerifyBeforeDelete(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_VerifyBeforeDelete")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(dir, 0755), "MkdirAll should succeed")
+
+	now := time.Now()
+	var names []string
+	for i := 0; i < 5; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.2024010%d.log", i+1))
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+		mtime := now.Add(time.Duration(i-5) * time.Hour)
+		require.NoError(t, os.Chtimes(name, mtime, mtime), "Chtimes should succeed")
+		names = append(names, name)
+	}
+	sum := sha256.Sum256([]byte("data"))
+	store := mapRemoteStore{
+		names[0]: {Size: 4, SHA256: hex.EncodeToString(sum[:])},
+		names[1]: {Size: 4},
+		names[2]: {Size: 4, SHA256: "bad"},
+	}
+
+	l, err := New(
+		filepath.Join(dir, "app.%Y%m%d.log"),
+		WithMaxBackups(2),
+		WithVerifyBeforeDelete(store),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+	require.True(t, l.Options().VerifyBeforeDelete, "VerifyBeforeDelete should be set")
+	// no writes, so the mill goroutine runs no pass, and counts are exact.
+
+	err = l.millRunOnce()
+	require.ErrorIs(t, err, ErrNotArchived, "deferred deletion should be reported")
+	var perr *PurgeError
+	require.True(t, errors.As(err, &perr), "error should be a PurgeError")
+	require.Equal(t, names[2], perr.Path, "file with mismatched checksum should be deferred")
+	require.NoFileExists(t, names[0], "archived file should be removed")
+	require.NoFileExists(t, names[1], "archived file should be removed by size")
+	require.FileExists(t, names[2], "file with mismatched checksum should be kept")
+	require.FileExists(t, names[3], "file within MaxBackups should be kept")
+	require.FileExists(t, names[4], "file within MaxBackups should be kept")
+	require.Equal(t, uint64(1), l.Metrics().DeferredDeletes, "DeferredDeletes should match")
+
+	// removed on a later pass, once archived.
+	store[names[2]] = RemoteObject{Size: 4}
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoFileExists(t, names[2], "archived file should be removed")
+	require.FileExists(t, names[3], "file within MaxBackups should be kept")
+}
+
+func Test_VerifyBeforeDelete_RetentionDirectory(t *testing.T) {
+	dir := filepath.Join(baseLogDir, "Test_VerifyBeforeDelete_RetentionDirectory")
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+	var days []string
+	for i := 1; i <= 3; i++ {
+		day := filepath.Join(dir, fmt.Sprintf("2024010%d", i))
+		require.NoError(t, os.MkdirAll(day, 0755), "MkdirAll should succeed")
+		mtime := now.Add(time.Duration(i-4) * 24 * time.Hour)
+		require.NoError(t, os.Chtimes(day, mtime, mtime), "Chtimes should succeed")
+		days = append(days, day)
+	}
+	archived := filepath.Join(days[0], "app.log")
+	pending := filepath.Join(days[0], "app.log.1")
+	for _, name := range []string{archived, pending} {
+		require.NoError(t, os.WriteFile(name, []byte("data"), 0644), "WriteFile should succeed")
+	}
+	require.NoError(t, os.Chtimes(days[0], now.Add(-72*time.Hour), now.Add(-72*time.Hour)), "Chtimes should succeed")
+	store := mapRemoteStore{archived: {Size: 4}}
+
+	l, err := New(
+		filepath.Join(dir, "%Y%m%d", "app.log"),
+		WithClock(clockwork.NewFakeClockAt(now)),
+		WithMaxInterval(24*time.Hour),
+		WithMaxBackups(1),
+		WithRetentionGranularity(RetentionDirectory),
+		WithVerifyBeforeDelete(store),
+	)
+	require.NoError(t, err, "New should succeed")
+	defer l.Close()
+
+	err = l.millRunOnce()
+	require.ErrorIs(t, err, ErrNotArchived, "deferred deletion should be reported")
+	var perr *PurgeError
+	require.True(t, errors.As(err, &perr), "error should be a PurgeError")
+	require.Equal(t, days[0], perr.Path, "directory with a file not archived should be deferred")
+	require.FileExists(t, archived, "directory with a file not archived should be kept entirely")
+	require.FileExists(t, pending, "file not archived should be kept")
+	require.NoDirExists(t, days[1], "directory with no files should be removed")
+	require.DirExists(t, days[2], "directory within MaxBackups should be kept")
+
+	// removed on a later pass, once archived.
+	store[pending] = RemoteObject{Size: 4}
+	require.NoError(t, l.millRunOnce(), "millRunOnce should succeed")
+	require.NoDirExists(t, days[0], "archived directory should be removed")
+}
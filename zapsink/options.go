@@ -0,0 +1,82 @@
+package zapsink
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gounknown/logrotate"
+)
+
+// parseURL parses the filename pattern and options from u.
+func parseURL(u *url.URL) (pattern string, options []logrotate.Option, err error) {
+	if u.Host != "" {
+		return "", nil, fmt.Errorf("zapsink: host must be empty, got %q", u.Host)
+	}
+	pattern = u.Path
+	if u.Opaque != "" {
+		// relative path, e.g.: rotate:app.%25Y%25m%25d.log
+		if pattern, err = url.PathUnescape(u.Opaque); err != nil {
+			return "", nil, fmt.Errorf("zapsink: invalid path: %w", err)
+		}
+	}
+	if pattern == "" {
+		return "", nil, fmt.Errorf("zapsink: filename pattern is required")
+	}
+	options, err = parseOptions(u.Query())
+	if err != nil {
+		return "", nil, err
+	}
+	return pattern, options, nil
+}
+
+// parseOptions parses logrotate options from query params.
+func parseOptions(query url.Values) ([]logrotate.Option, error) {
+	var options []logrotate.Option
+	for key, values := range query {
+		value := values[len(values)-1]
+		var opt logrotate.Option
+		var err error
+		switch strings.ToLower(key) {
+		case "symlink":
+			opt = logrotate.WithSymlink(value)
+		case "maxinterval":
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			opt = logrotate.WithMaxInterval(d)
+		case "maxsequence":
+			var n int
+			n, err = strconv.Atoi(value)
+			opt = logrotate.WithMaxSequence(n)
+		case "maxsize":
+			var n int64
+			n, err = logrotate.ParseSize(value)
+			opt = logrotate.WithMaxSize(int(n))
+		case "maxage":
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			opt = logrotate.WithMaxAge(d)
+		case "maxbackups":
+			var n int
+			n, err = strconv.Atoi(value)
+			opt = logrotate.WithMaxBackups(n)
+		case "maxtotalsize":
+			var n int64
+			n, err = logrotate.ParseSize(value)
+			opt = logrotate.WithMaxTotalSize(n)
+		case "writechan":
+			var n int
+			n, err = strconv.Atoi(value)
+			opt = logrotate.WithWriteChan(n)
+		default:
+			return nil, fmt.Errorf("zapsink: unknown option %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zapsink: invalid option %s=%q: %w", key, value, err)
+		}
+		options = append(options, opt)
+	}
+	return options, nil
+}
@@ -0,0 +1,50 @@
+package zapsink
+
+import (
+	"net/url"
+	"testing"
+)
+
+func Test_parseURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		pattern string
+		options int
+	}{
+		{"rotate:///var/log/app.%25Y%25m%25d.log?maxsize=100MiB&maxage=720h", "/var/log/app.%Y%m%d.log", 2},
+		{"rotate:app.%25Y%25m%25d.log?MaxBackups=7", "app.%Y%m%d.log", 1},
+		{"rotate:///var/log/app.log", "/var/log/app.log", 0},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.raw)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) error: %v", tt.raw, err)
+		}
+		pattern, options, err := parseURL(u)
+		if err != nil {
+			t.Errorf("parseURL(%q) error: %v", tt.raw, err)
+			continue
+		}
+		if pattern != tt.pattern {
+			t.Errorf("parseURL(%q) pattern = %q, want %q", tt.raw, pattern, tt.pattern)
+		}
+		if len(options) != tt.options {
+			t.Errorf("parseURL(%q) got %d options, want %d", tt.raw, len(options), tt.options)
+		}
+	}
+
+	for _, raw := range []string{
+		"rotate://host/app.log",
+		"rotate:///app.log?maxsize=abc",
+		"rotate:///app.log?unknown=1",
+		"rotate://",
+	} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) error: %v", raw, err)
+		}
+		if _, _, err := parseURL(u); err == nil {
+			t.Errorf("parseURL(%q) should fail", raw)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package zapsink registers logrotate as a zap sink with the "rotate" URL
+// scheme, so rotation can be enabled purely through zap config files:
+//
+//	import _ "github.com/gounknown/logrotate/zapsink"
+//
+//	cfg := zap.NewProductionConfig()
+//	cfg.OutputPaths = []string{"rotate:///var/log/app.%25Y%25m%25d.log?maxsize=100MiB&maxage=720h"}
+//
+// The URL path is the filename pattern, with "%" escaped as "%25". Relative
+// paths are written as "rotate:app.%25Y%25m%25d.log". Supported query
+// params (case insensitive) are:
+//
+//   - symlink: see logrotate.WithSymlink
+//   - maxinterval: duration, see logrotate.WithMaxInterval
+//   - maxsequence: integer, see logrotate.WithMaxSequence
+//   - maxsize: size, e.g.: 100MiB, see logrotate.WithMaxSize
+//   - maxage: duration, see logrotate.WithMaxAge
+//   - maxbackups: integer, see logrotate.WithMaxBackups
+//   - maxtotalsize: size, see logrotate.WithMaxTotalSize
+//   - writechan: integer, see logrotate.WithWriteChan
+package zapsink
+
+import (
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"github.com/gounknown/logrotate"
+)
+
+// Scheme is the URL scheme registered with zap.
+const Scheme = "rotate"
+
+func init() {
+	if err := zap.RegisterSink(Scheme, NewSink); err != nil {
+		panic(err)
+	}
+}
+
+// Sink is a zap.Sink which writes to a rotated log file.
+type Sink struct {
+	*logrotate.Logger
+}
+
+// Sync implements zap.Sink. It flushes the buffered writes, if any, and
+// fsyncs the current file, see logrotate.Logger.Sync.
+func (s Sink) Sync() error {
+	return s.Logger.Sync()
+}
+
+// NewSink creates a Sink from u. It's registered with zap for the "rotate"
+// scheme, and can be registered for other schemes by zap.RegisterSink.
+func NewSink(u *url.URL) (zap.Sink, error) {
+	pattern, options, err := parseURL(u)
+	if err != nil {
+		return nil, err
+	}
+	l, err := logrotate.New(pattern, options...)
+	if err != nil {
+		return nil, err
+	}
+	return Sink{l}, nil
+}
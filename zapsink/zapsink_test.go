@@ -0,0 +1,33 @@
+package zapsink
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Sink_Sync(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	u := &url.URL{Scheme: "rotate", Path: filename, RawQuery: "writechan=10"}
+	s, err := NewSink(u)
+	if err != nil {
+		t.Fatalf("NewSink(%q) error: %v", u, err)
+	}
+	defer s.Close()
+
+	if _, err := s.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	// flushes the buffered writes.
+	if err := s.Sync(); err != nil {
+		t.Fatalf("Sync error: %v", err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if string(b) != "hello\n" {
+		t.Errorf("got %q, want %q", b, "hello\n")
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/gounknown/logrotate/zerologwriter
+
+go 1.20
+
+require (
+	github.com/gounknown/logrotate v0.0.0-20240405083505-0c6d6d14f42e
+	github.com/rs/zerolog v1.33.0
+)
+
+require (
+	github.com/lestrrat-go/strftime v1.0.6 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/sys v0.12.0 // indirect
+)
+
+replace github.com/gounknown/logrotate => ../
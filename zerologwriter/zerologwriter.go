@@ -0,0 +1,50 @@
+// Package zerologwriter wires a rotating logrotate.Logger as the output of
+// zerolog:
+//
+//	w, err := zerologwriter.New("/var/log/app.%Y%m%d.log", logrotate.WithMaxAge(720*time.Hour))
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer w.Close()
+//	logger := zerolog.New(w).With().Timestamp().Logger()
+package zerologwriter
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/gounknown/logrotate"
+)
+
+// ensure we always implement zerolog.LevelWriter
+var _ zerolog.LevelWriter = (*Writer)(nil)
+
+// Writer is a zerolog.LevelWriter which writes to rotated log files.
+type Writer struct {
+	*logrotate.Logger
+}
+
+// New creates a Writer with the provided filename pattern and options.
+func New(pattern string, options ...logrotate.Option) (*Writer, error) {
+	l, err := logrotate.New(pattern, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{l}, nil
+}
+
+// WriteLevel implements zerolog.LevelWriter. As zerolog exits right after
+// writing a fatal event, the Logger is closed on FatalLevel to flush the
+// buffered log lines (see logrotate.WithWriteChan) to files.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	n, err := w.Write(p)
+	if level == zerolog.FatalLevel {
+		_ = w.Close()
+	}
+	return n, err
+}
+
+// Sync flushes the buffered writes, if any, and fsyncs the current file,
+// see logrotate.Logger.Sync.
+func (w *Writer) Sync() error {
+	return w.Logger.Sync()
+}
@@ -0,0 +1,52 @@
+package zerologwriter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gounknown/logrotate"
+)
+
+func Test_WriteLevel(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	w, err := New(filename, logrotate.WithWriteChan(10))
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer w.Close()
+
+	logger := zerolog.New(w)
+	logger.Info().Msg("hello")
+	// flushes the buffered writes.
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync error: %v", err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if want := `{"level":"info","message":"hello"}` + "\n"; string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	// zerolog calls os.Exit after a fatal event, so call WriteLevel directly.
+	line := []byte(`{"level":"fatal","message":"bye"}` + "\n")
+	if n, err := w.WriteLevel(zerolog.FatalLevel, line); err != nil || n != len(line) {
+		t.Errorf("WriteLevel(FatalLevel) = %d, %v, want %d, nil", n, err, len(line))
+	}
+	if _, err := w.Write([]byte("after fatal\n")); !errors.Is(err, logrotate.ErrClosed) {
+		t.Errorf("Write after fatal error = %v, want %v", err, logrotate.ErrClosed)
+	}
+	b, err = os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if want := `{"level":"info","message":"hello"}` + "\n" + string(line); string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+}